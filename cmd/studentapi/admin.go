@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	app "github.com/pratik6266/go-full/internal"
+)
+
+const createAdminUsage = "usage: studentapi create-admin <email> [name], with the password on standard input"
+
+// runCreateAdmin implements the "studentapi create-admin" subcommand. The
+// password is read from the first line of stdin so it does not show up in
+// the process list or shell history.
+func runCreateAdmin(db *app.Db, args []string, stdin io.Reader) error {
+	if len(args) == 0 || len(args) > 2 {
+		return errors.New(createAdminUsage)
+	}
+	name := "Admin"
+	if len(args) == 2 {
+		name = args[1]
+	}
+	password, err := bufio.NewReader(stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("error reading password: %w", err)
+	}
+
+	user, err := app.CreateAdmin(context.Background(), app.NewPostgresRepositories(db), name, args[0], strings.TrimRight(password, "\r\n"))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Created admin %s with ID %d\n", user.Email, user.ID)
+	return nil
+}
@@ -1,51 +1,36 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/grafana/loki-client-go/loki"
 	docs "github.com/pratik6266/go-full/docs" // generated swagger docs
 	app "github.com/pratik6266/go-full/internal"
+	"github.com/pratik6266/go-full/internal/lokihook"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
-	"github.com/prometheus/common/model"
 	"github.com/sirupsen/logrus"
 	swaggerFiles "github.com/swaggo/files"     // swagger embed files
 	ginSwagger "github.com/swaggo/gin-swagger" // gin-swagger middleware
+	"go.opentelemetry.io/otel"
 )
 
-// LokiHook implements logrus.Hook to forward logs to Loki.
-type LokiHook struct {
-	client *loki.Client
-	labels model.LabelSet
-}
-
-func NewLokiHook(client *loki.Client) *LokiHook {
-	return &LokiHook{
-		client: client,
-		labels: model.LabelSet{model.LabelName("job"): model.LabelValue("student-api")},
-	}
-}
-
-func (h *LokiHook) Levels() []logrus.Level {
-	return logrus.AllLevels
-}
-
-func (h *LokiHook) Fire(entry *logrus.Entry) error {
-	// Format log line
-	line, err := entry.String()
-	if err != nil {
-		return err
-	}
-	// Send to Loki; ignore error for now to avoid loop
-	_ = h.client.Handle(h.labels, time.Now(), line)
-	return nil
-}
+// version identifies the build in the logs and traces it sends. Release builds
+// set it with -ldflags "-X main.version=...".
+var version = "dev"
 
 // @title           Student API Documentation
 // @version         1.0
 // @description     This is a sample server for managing student records.
+// @description     Authenticated POST requests accept an Idempotency-Key header. A retry with the same key and body gets the first response back, marked with Idempotent-Replayed; a retry that arrives while the first request is still running gets 409, and reusing a key for a different request gets 422.
+// @description     Every client is rate limited per user, registered X-API-Key or IP address. Responses carry RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers; a client out of requests gets 429 with Retry-After.
+// @description     Every response carries an X-Request-ID header, which is also logged with each line the request writes. Send your own ID in X-Request-ID to correlate requests across services.
+// @description     Requests are traced with OpenTelemetry. Send a W3C traceparent header to make the request part of your own trace.
 
 // @scheme http
 // @baeshPath /api/v1
@@ -58,61 +43,184 @@ func (h *LokiHook) Fire(entry *logrus.Entry) error {
 // @name Authorization
 
 func main() {
-	fmt.Println("Server is running on port 8080")
+	// Flags come before the optional subcommand: studentapi -env prod migrate up
+	cfg, args, err := app.LoadConfig(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	// Trace requests and queries; spans are exported as tracing.exporter says
+	tp, err := app.NewTracerProvider(context.Background(), cfg.Tracing, "student-api", version, cfg.Env)
+	if err != nil {
+		panic(err)
+	}
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(app.TracePropagator)
 
 	// Initialize database
-	db, err := app.InitDB()
+	db, err := app.InitDB(cfg.DB, tp)
 	if err != nil {
 		panic("Failed to connect to database")
 	}
 
-	// Initialize Gin router
+	// "studentapi migrate ..." manages the schema and exits
+	if len(args) > 0 && args[0] == "migrate" {
+		err := runMigrate(db, args[1:])
+		db.Close()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// "studentapi create-admin ..." creates the first admin and exits
+	if len(args) > 0 && args[0] == "create-admin" {
+		err := runCreateAdmin(db, args[1:], os.Stdin)
+		db.Close()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Optionally bring the schema up to date before serving traffic
+	if cfg.DB.AutoMigrate {
+		if err := runMigrate(db, []string{"up"}); err != nil {
+			panic("Failed to apply migrations: " + err.Error())
+		}
+	}
+
+	tokens := app.NewTokenManager(cfg.Auth.JWTSecret, cfg.Auth.AccessTokenTTL, cfg.Auth.RefreshTokenTTL)
+
+	logger := logrus.New()
+	logger.SetFormatter(cfg.LogFormatter())
+	logger.SetLevel(cfg.LogLevel())
+
+	// Initialize Gin router. Forwarded client addresses are only believed
+	// from the configured proxies, so callers cannot pick their own IP for
+	// rate limits and the audit trail.
 	r := gin.New()
-	r.Use(gin.Logger())
+	if err := r.SetTrustedProxies(cfg.HTTP.TrustedProxies); err != nil {
+		panic("Invalid trusted proxies: " + err.Error())
+	}
+	r.Use(app.Tracing("student-api", tp))
+	r.Use(app.RequestLogger(logger))
 	r.Use(gin.Recovery())
 	r.Use(app.PrometheusMiddleware())
 
-	// Loki Setup
-	lokiCfg, err := loki.NewDefaultConfig("http://localhost:3100/loki/api/v1/push")
-	if err != nil {
-		panic(err)
-	}
-	client, err := loki.New(lokiCfg)
-	if err != nil {
-		panic(err)
+	// Ship logs to Loki in the background; lines that cannot be shipped are
+	// dropped and counted rather than slowing down requests.
+	var lokiHook *lokihook.Hook
+	if cfg.Loki.URL != "" {
+		lokiHook, err = lokihook.New(lokihook.Config{
+			URL:            cfg.Loki.URL,
+			Labels:         lokiLabels(cfg),
+			PromotedFields: cfg.Loki.PromotedFields,
+			MaxLabelValues: cfg.Loki.MaxLabelValues,
+			BufferSize:     cfg.Loki.BufferSize,
+			BatchSize:      cfg.Loki.BatchSize,
+			BatchWait:      cfg.Loki.BatchWait,
+		})
+		if err != nil {
+			panic(err)
+		}
+		logger.AddHook(lokiHook)
 	}
-	logger := logrus.New()
-	logger.SetFormatter(&logrus.TextFormatter{})
-	logger.AddHook(NewLokiHook(client))
 
 	// Swagger UI endpoint
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 	docs.SwaggerInfo.BasePath = "/api/v1"
 
 	// API routes
-	h := app.NewHandler(db, logger)
-	rw := r.Group("/api/v1")
-	{
-		//healthcheck endpoint
-		rw.GET("/health", h.Healthcheck)
+	repos := app.NewPostgresRepositories(db)
+	h := app.NewHandler(repos, logger, tokens)
+	h.SetGradingScale(cfg.Grading.Scale)
+	h.SetRequireIfMatch(cfg.HTTP.RequireIfMatch)
+	migrator, err := app.NewMigrator(db)
+	if err != nil {
+		panic(err)
+	}
+	h.AddReadinessCheck(app.HealthCheck{Name: "database", Critical: true, Timeout: cfg.Health.CheckTimeout, Check: db.Ping})
+	h.AddReadinessCheck(app.HealthCheck{Name: "migrations", Critical: true, Timeout: cfg.Health.CheckTimeout, Check: migrator.CheckApplied})
+	if lokiHook != nil {
+		// Logs are buffered by the hook, so Loki being down does not stop us
+		// from serving traffic.
+		h.AddReadinessCheck(app.HealthCheck{
+			Name:    "loki",
+			Timeout: cfg.Health.CheckTimeout,
+			Check:   app.HTTPReadyCheck(http.DefaultClient, cfg.Loki.ReadyURL()),
+		})
+	}
+	// Rate limits are kept in memory unless replicas should share them
+	var limiter *app.RateLimiter
+	if cfg.RateLimit.Enabled {
+		store := app.NewMemoryRateLimitStore()
+		if cfg.RateLimit.Store == "postgres" {
+			store = app.NewPostgresRateLimitStore(db)
+		}
+		limiter = app.NewRateLimiter(store, cfg.RateLimit, logger)
+	}
 
-		// student endpoints
-		rw.GET("/students", h.GetStudents)
-		rw.POST("/students", h.CreateStudent)
-		rw.GET("/students/:id", h.GetStudentByID)
-		rw.PUT("/students/:id", h.UpdateStudent)
-		rw.DELETE("/students/:id", h.DeleteStudent)
+	rw := r.Group("/api/v1")
+	app.RegisterRoutes(rw, app.RouteMiddleware{
+		Auth:        app.AuthMiddleware(tokens, h),
+		Idempotency: app.Idempotency(repos.Idempotency, cfg.Idempotency.TTL, logger),
+		RateLimiter: limiter,
+	}, h.Routes())
 
-		// user endpoints
-		rw.GET("/users", h.GetUsers)
-		rw.GET("/users/by-id", h.GetUserById)
-		rw.POST("/users", h.CreateUser)
-		rw.DELETE("/users/:id", h.DeleteUserById)
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
-		// auth endpoints
+	// Serve until SIGINT/SIGTERM, then drain in-flight requests
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Remove deleted records once they are past the retention period
+	purgeDone := make(chan struct{})
+	go func() {
+		defer close(purgeDone)
+		app.NewPurger(repos, cfg.Purge, logger).Run(ctx)
+	}()
+
+	srv := &http.Server{
+		Addr:              cfg.HTTP.Addr,
+		Handler:           r,
+		ReadHeaderTimeout: 10 * time.Second,
 	}
+	if err := serve(ctx, srv, cfg.HTTP, h.StartDraining, logger.WithField("profile", cfg.Env)); err != nil {
+		logger.Error("HTTP server error:", err)
+	}
+	stop()
+	<-purgeDone
+
+	// Everything the handlers used is released only after the server stopped
+	flushCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := tp.Shutdown(flushCtx); err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to flush traces:", err)
+	}
+	if lokiHook != nil {
+		if err := lokiHook.Close(flushCtx); err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to flush logs to Loki:", err)
+		}
+	}
+	if err := db.Close(); err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to close database:", err)
+	}
+	fmt.Println("Server stopped")
+}
 
-	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
-
-	r.Run(":8080")
+// lokiLabels are the configured Loki labels plus env, instance and version,
+// unless the config sets those itself.
+func lokiLabels(cfg *app.Config) map[string]string {
+	labels := map[string]string{"env": cfg.Env, "version": version}
+	if host, err := os.Hostname(); err == nil {
+		labels["instance"] = host
+	}
+	for name, value := range cfg.Loki.Labels {
+		labels[name] = value
+	}
+	return labels
 }
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	app "github.com/pratik6266/go-full/internal"
+	"github.com/sirupsen/logrus"
+)
+
+// serve binds srv.Addr and logs that the server is running once the
+// listener is open. It then serves until ctx is cancelled (SIGINT/SIGTERM)
+// or the listener fails, and shuts down gracefully:
+//
+//  1. drain() flips readiness so load balancers stop sending traffic;
+//  2. after cfg.ShutdownDelay the listener closes and in-flight requests get
+//     up to cfg.DrainTimeout to finish.
+//
+// Releasing the Loki client and DB pool is left to the caller, after serve
+// returns, so nothing they back is torn down while requests are running.
+func serve(ctx context.Context, srv *http.Server, cfg app.HTTPConfig, drain func(), logger logrus.FieldLogger) error {
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		return err
+	}
+	logger.WithFields(logrus.Fields{"addr": ln.Addr().String(), "tls": cfg.TLS.Enabled()}).Info("Server is running")
+
+	errCh := make(chan error, 1)
+	go func() {
+		if cfg.TLS.Enabled() {
+			errCh <- srv.ServeTLS(ln, cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		} else {
+			errCh <- srv.Serve(ln)
+		}
+	}()
+
+	select {
+	case err := <-errCh:
+		// The listener failed before any shutdown was requested.
+		return err
+	case <-ctx.Done():
+	}
+
+	logger.Info("Shutdown signal received, draining connections")
+	drain()
+	time.Sleep(cfg.ShutdownDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.DrainTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		// Drain timed out; cut the remaining connections.
+		_ = srv.Close()
+		return err
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	logger.Info("HTTP server stopped")
+	return nil
+}
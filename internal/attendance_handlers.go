@@ -0,0 +1,156 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const dateLayout = "2006-01-02"
+
+// parseDateRange reads the inclusive from and to query parameters.
+func parseDateRange(c *gin.Context) (time.Time, time.Time, error) {
+	var bounds [2]time.Time
+	for i, name := range []string{"from", "to"} {
+		raw := c.Query(name)
+		if raw == "" {
+			return bounds[0], bounds[1], fmt.Errorf("%s is required", name)
+		}
+		t, err := time.Parse(dateLayout, raw)
+		if err != nil {
+			return bounds[0], bounds[1], fmt.Errorf("%s must be a date formatted as %s", name, dateLayout)
+		}
+		bounds[i] = t
+	}
+	if bounds[0].After(bounds[1]) {
+		return bounds[0], bounds[1], errors.New("from must not be after to")
+	}
+	return bounds[0], bounds[1], nil
+}
+
+// RecordAttendance godoc
+// @Summary      Record attendance for a day
+// @Description  Records the attendance of a whole class for one day in a single request. Either every record is stored or none is; a record replaces any status already stored for that student and day.
+// @Tags         Attendance
+// @Accept       json
+// @Produce      json
+// @Param        attendance       body      AttendanceRequest  true   "Day and per-student statuses"
+// @Param        Idempotency-Key  header    string             false  "Unique key that makes retrying this request safe"
+// @Success      200              {object}  AttendanceBatchResponse
+// @Failure      400              {object}  ErrorResponse
+// @Failure      401              {object}  ErrorResponse
+// @Failure      403              {object}  ErrorResponse
+// @Failure      422              {object}  ErrorResponse
+// @Failure      500              {object}  ErrorResponse
+// @Security     BearerAuth
+// @Router       /attendance [post]
+func (h *Handler) RecordAttendance(c *gin.Context) {
+	var req AttendanceRequest
+	if !h.bindJSON(c, &req) {
+		return
+	}
+	// The binding rules already checked the format.
+	day, _ := time.Parse(dateLayout, req.Date)
+
+	err := h.attendance.Record(c.Request.Context(), day, req.Records)
+	if h.respondConstraintError(c, err) {
+		return
+	} else if err != nil {
+		h.log(c).Error("Failed to record attendance:", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to record attendance"})
+		return
+	}
+
+	h.log(c).Info("Recorded attendance for ", len(req.Records), " students on ", req.Date)
+	c.JSON(http.StatusOK, AttendanceBatchResponse{Date: req.Date, Recorded: len(req.Records)})
+}
+
+// GetAttendanceReport godoc
+// @Summary      Attendance report
+// @Description  Returns the attendance counts and rate of every student with records between from and to, inclusive. The rate is the share of non-excused days a student was present or late.
+// @Tags         Attendance
+// @Produce      json
+// @Param        from  query     string  true  "First day (YYYY-MM-DD)"  example(2025-09-01)
+// @Param        to    query     string  true  "Last day (YYYY-MM-DD)"  example(2025-09-30)
+// @Success      200   {object}  AttendanceReport
+// @Failure      400   {object}  ErrorResponse
+// @Failure      401   {object}  ErrorResponse
+// @Failure      403   {object}  ErrorResponse
+// @Failure      500   {object}  ErrorResponse
+// @Security     BearerAuth
+// @Router       /attendance/report [get]
+func (h *Handler) GetAttendanceReport(c *gin.Context) {
+	from, to, err := parseDateRange(c)
+	if err != nil {
+		h.log(c).Warn("Invalid attendance report range:", err)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	report, err := h.attendance.Report(c.Request.Context(), from, to)
+	if err != nil {
+		h.log(c).Error("Failed to build attendance report:", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch attendance report"})
+		return
+	}
+
+	h.log(c).Info("Fetched attendance report successfully")
+	c.JSON(http.StatusOK, AttendanceReport{From: from.Format(dateLayout), To: to.Format(dateLayout), Data: report})
+}
+
+// GetStudentAttendance godoc
+// @Summary      Get a student's attendance
+// @Description  Returns the student's attendance counts and rate between from and to, inclusive. Student accounts may only read their own attendance.
+// @Tags         Attendance
+// @Produce      json
+// @Param        id    path      int     true  "Student ID"
+// @Param        from  query     string  true  "First day (YYYY-MM-DD)"  example(2025-09-01)
+// @Param        to    query     string  true  "Last day (YYYY-MM-DD)"  example(2025-09-30)
+// @Success      200   {object}  StudentAttendance
+// @Failure      400   {object}  ErrorResponse
+// @Failure      401   {object}  ErrorResponse
+// @Failure      403   {object}  ErrorResponse
+// @Failure      404   {object}  ErrorResponse
+// @Failure      500   {object}  ErrorResponse
+// @Security     BearerAuth
+// @Router       /students/{id}/attendance [get]
+func (h *Handler) GetStudentAttendance(c *gin.Context) {
+	idStr := c.Param("id")
+	studentID, err := strconv.Atoi(idStr)
+	if err != nil || studentID <= 0 {
+		h.log(c).Error("Invalid student ID for attendance:", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid ID"})
+		return
+	}
+	from, to, err := parseDateRange(c)
+	if err != nil {
+		h.log(c).Warn("Invalid attendance range:", err)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := h.students.Get(ctx, studentID); errors.Is(err, ErrNotFound) {
+		h.log(c).Warn("Student not found for attendance with ID:", studentID)
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Student not found"})
+		return
+	} else if err != nil {
+		h.log(c).Error("Failed to fetch student for attendance:", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch attendance"})
+		return
+	}
+
+	summary, err := h.attendance.Summary(ctx, studentID, from, to)
+	if err != nil {
+		h.log(c).Error("Failed to summarise attendance:", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch attendance"})
+		return
+	}
+
+	h.log(c).Info("Fetched attendance successfully for student ID:", studentID)
+	c.JSON(http.StatusOK, StudentAttendance{From: from.Format(dateLayout), To: to.Format(dateLayout), AttendanceSummary: summary})
+}
@@ -0,0 +1,119 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordAttendanceAndReport(t *testing.T) {
+	h, repos := newMemoryHandler(t)
+	ctx := context.Background()
+	_, _ = repos.Students.Create(ctx, Student{Name: "Alice", Age: 20, Email: "alice@example.com"})
+	_, _ = repos.Students.Create(ctx, Student{Name: "Bob", Age: 21, Email: "bob@example.com"})
+	r := testRouter(t, h, testAdmin, RouteMiddleware{})
+
+	for _, body := range []string{
+		`{"date": "2025-09-01", "records": [{"student_id": 1, "status": "present"}, {"student_id": 2, "status": "absent"}]}`,
+		`{"date": "2025-09-02", "records": [{"student_id": 1, "status": "late"}, {"student_id": 2, "status": "excused"}]}`,
+		`{"date": "2025-09-03", "records": [{"student_id": 1, "status": "absent"}]}`,
+		// Resubmitting a day replaces the earlier status.
+		`{"date": "2025-09-03", "records": [{"student_id": 1, "status": "present"}]}`,
+		`{"date": "2025-10-01", "records": [{"student_id": 1, "status": "absent"}]}`,
+	} {
+		var resp AttendanceBatchResponse
+		assert.Equal(t, http.StatusOK, doJSON(r, http.MethodPost, "/attendance", body, &resp))
+	}
+
+	var report AttendanceReport
+	code := doJSON(r, http.MethodGet, "/attendance/report?from=2025-09-01&to=2025-09-30", "", &report)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, report.Data, 2)
+	assert.Equal(t, AttendanceSummary{StudentID: 1, Days: 3, Present: 2, Late: 1}, withoutRate(report.Data[0]))
+	assert.Equal(t, 1.0, *report.Data[0].Rate)
+	assert.Equal(t, 0.0, *report.Data[1].Rate)
+
+	var one StudentAttendance
+	code = doJSON(r, http.MethodGet, "/students/1/attendance?from=2025-09-01&to=2025-10-31", "", &one)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 4, one.Days)
+	assert.Equal(t, 0.75, *one.Rate)
+
+	code = doJSON(r, http.MethodGet, "/students/2/attendance?from=2025-12-01&to=2025-12-31", "", &one)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Zero(t, one.Days)
+	assert.Nil(t, one.Rate)
+
+	assert.Equal(t, http.StatusNotFound, doJSON(r, http.MethodGet, "/students/9/attendance?from=2025-09-01&to=2025-09-30", "", nil))
+	assert.Equal(t, http.StatusBadRequest, doJSON(r, http.MethodGet, "/attendance/report?from=2025-09-30&to=2025-09-01", "", nil))
+	assert.Equal(t, http.StatusBadRequest, doJSON(r, http.MethodGet, "/attendance/report?from=2025-09-01", "", nil))
+}
+
+func withoutRate(s AttendanceSummary) AttendanceSummary {
+	s.Rate = nil
+	return s
+}
+
+func TestRecordAttendanceValidatesWholeBatch(t *testing.T) {
+	h, repos := newMemoryHandler(t)
+	_, _ = repos.Students.Create(context.Background(), Student{Name: "Alice", Age: 20, Email: "alice@example.com"})
+	r := testRouter(t, h, testAdmin, RouteMiddleware{})
+
+	var resp ErrorResponse
+	code := doJSON(r, http.MethodPost, "/attendance", `{"date": "09/01/2025", "records": [{"student_id": 1, "status": "present"}, {"student_id": 1, "status": "late"}]}`, &resp)
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	assert.Equal(t, []FieldError{
+		{Field: "date", Rule: "datetime", Message: "date must be a date formatted as 2006-01-02"},
+		{Field: "records", Rule: "unique", Message: "records must not repeat a student_id"},
+	}, resp.Details)
+
+	code = doJSON(r, http.MethodPost, "/attendance", `{"date": "2025-09-01", "records": [{"student_id": 1, "status": "sick"}]}`, &resp)
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	assert.Equal(t, []FieldError{
+		{Field: "records[0].status", Rule: "oneof", Message: "records[0].status must be one of present, absent, late, excused"},
+	}, resp.Details)
+
+	// An unknown student rejects the whole batch.
+	code = doJSON(r, http.MethodPost, "/attendance", `{"date": "2025-09-01", "records": [{"student_id": 1, "status": "present"}, {"student_id": 7, "status": "present"}]}`, &resp)
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	assert.Equal(t, CodeInvalidReference, resp.Code)
+
+	summary, _ := repos.Attendance.Summary(context.Background(), 1, time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC))
+	assert.Zero(t, summary.Days)
+}
+
+func TestPostgresAttendanceUsesSingleStatementAndSQLAggregates(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+	repo := NewPostgresRepositories(&Db{db: mockDB}).Attendance
+	day := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectExec("INSERT INTO attendance").
+		WithArgs(day, pq.Array([]int64{1, 2}), pq.Array([]string{"present", "late"})).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	err = repo.Record(context.Background(), day, []AttendanceEntry{{StudentID: 1, Status: AttendancePresent}, {StudentID: 2, Status: AttendanceLate}})
+	assert.NoError(t, err)
+
+	cols := []string{"student_id", "days", "present", "late", "absent", "excused", "rate"}
+	mock.ExpectQuery(`FILTER \(WHERE status IN \('present', 'late'\)\).*GROUP BY student_id ORDER BY student_id`).
+		WithArgs(day, day).
+		WillReturnRows(sqlmock.NewRows(cols).AddRow(1, 2, 1, 0, 0, 1, 1.0).AddRow(2, 1, 0, 0, 0, 1, nil))
+	report, err := repo.Report(context.Background(), day, day)
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, *report[0].Rate)
+	assert.Nil(t, report[1].Rate)
+
+	mock.ExpectQuery("AND student_id = \\$3").
+		WithArgs(day, day, 5).
+		WillReturnRows(sqlmock.NewRows(cols))
+	summary, err := repo.Summary(context.Background(), 5, day, day)
+	assert.NoError(t, err)
+	assert.Equal(t, AttendanceSummary{StudentID: 5}, summary)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
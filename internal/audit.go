@@ -0,0 +1,100 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+)
+
+// auditedChange describes what an audited write did to one record. Before is
+// nil for a create; After is the record as stored.
+type auditedChange struct {
+	action     AuditAction
+	entityType string
+	entityID   int
+	before     any
+	after      any
+}
+
+// audited runs write in a transaction and records the change it reports in
+// the same transaction, so the audit trail holds exactly the changes that
+// were committed. Errors from write are returned unchanged. A write that
+// turns out to change nothing, such as restoring a student that was not
+// deleted, leaves no event.
+func (h *Handler) audited(c *gin.Context, write func(tx Repositories) (auditedChange, error)) error {
+	return h.inTx(c.Request.Context(), func(tx Repositories) error {
+		change, err := write(tx)
+		if err != nil {
+			return err
+		}
+		return recordChange(c, tx, change)
+	})
+}
+
+// recordChange appends the change to the audit trail of tx, attributed to
+// the request's user, unless it changes nothing.
+func recordChange(c *gin.Context, tx Repositories, change auditedChange) error {
+	changes, err := diffFields(change.before, change.after)
+	if err != nil || len(changes) == 0 {
+		return err
+	}
+
+	e := AuditEvent{
+		Action:     change.action,
+		EntityType: change.entityType,
+		EntityID:   change.entityID,
+		Changes:    changes,
+		RequestID:  RequestID(c),
+		ClientIP:   c.ClientIP(),
+	}
+	if claims, ok := CurrentClaims(c); ok {
+		actorID := claims.UserID
+		e.ActorID = &actorID
+		e.ActorEmail = claims.Email
+	}
+	return tx.Audit.Record(c.Request.Context(), e)
+}
+
+// diffFields compares the JSON forms of two records field by field. A nil
+// record has no fields, so every field of the other one is reported.
+func diffFields(before, after any) (map[string]FieldChange, error) {
+	b, err := jsonFields(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := jsonFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]FieldChange{}
+	for name, value := range b {
+		if other, ok := a[name]; !ok || !reflect.DeepEqual(value, other) {
+			changes[name] = FieldChange{Before: value, After: a[name]}
+		}
+	}
+	for name, value := range a {
+		if _, ok := b[name]; !ok {
+			changes[name] = FieldChange{After: value}
+		}
+	}
+	return changes, nil
+}
+
+func jsonFields(v any) (map[string]any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]any
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	// Keep numbers as json.Number so large IDs are compared and stored
+	// exactly.
+	dec.UseNumber()
+	return fields, dec.Decode(&fields)
+}
@@ -0,0 +1,55 @@
+package internal
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAuditEvents godoc
+// @Summary      List audit events
+// @Description  Returns a page of the audit trail: who created, changed, deleted or restored which student or user, with the fields that changed. Pages are keyset based like the other listings; sort by -id for the newest events first.
+// @Tags         Audit
+// @Produce      json
+// @Param        limit        query     int     false  "Page size (1-200)"  default(50)
+// @Param        cursor       query     string  false  "Cursor from a previous page's next_cursor"
+// @Param        sort         query     string  false  "Sort key (id); prefix with - for descending"  example(-id)
+// @Param        entity_type  query     string  false  "Only events about this kind of record"  Enums(student, user)
+// @Param        entity_id    query     int     false  "Only events about this record; requires entity_type"
+// @Param        actor_id     query     int     false  "Only changes made by this user"
+// @Success      200  {object}  AuditListResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Security     BearerAuth
+// @Router       /audit [get]
+func (h *Handler) GetAuditEvents(c *gin.Context) {
+	opts, err := parseListOptions(c, auditSortColumns)
+	if err != nil {
+		h.log(c).Warn("Invalid audit list options:", err)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		h.log(c).Warn("Invalid audit filter:", err)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	page, err := h.audit.List(c.Request.Context(), filter, opts)
+	if err != nil {
+		h.log(c).Error("Error listing audit events:", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch audit events"})
+		return
+	}
+
+	resp := AuditListResponse{Data: page.Items, Total: page.Total}
+	if page.HasMore {
+		resp.NextCursor = opts.NextCursor(page.Items[len(page.Items)-1].sortValues(opts.Sort))
+	}
+
+	h.log(c).Info("Fetched audit events successfully")
+	c.JSON(http.StatusOK, resp)
+}
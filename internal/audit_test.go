@@ -0,0 +1,155 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMutationsAreAudited(t *testing.T) {
+	h, _ := newMemoryHandler(t)
+	r := testRouter(t, h, testAdmin, RouteMiddleware{})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/students", strings.NewReader(`{"name":"John Doe","age":20,"email":"john@example.com"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Request-ID", "req-1")
+	req.RemoteAddr = "203.0.113.7:4321"
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	assert.Equal(t, http.StatusOK, doJSON(r, http.MethodPut, "/students/1", `{"name":"John Doe","age":21,"email":"john@example.com"}`, nil))
+	// Neither a rejected write nor a restore of a live student changes anything.
+	assert.Equal(t, http.StatusNotFound, doJSON(r, http.MethodPut, "/students/9", `{"name":"Nobody","age":21,"email":"nobody@example.com"}`, nil))
+	assert.Equal(t, http.StatusOK, doJSON(r, http.MethodPost, "/students/1/restore", "", nil))
+	assert.Equal(t, http.StatusNoContent, doJSON(r, http.MethodDelete, "/students/1", "", nil))
+	assert.Equal(t, http.StatusOK, doJSON(r, http.MethodPost, "/students/1/restore", "", nil))
+	assert.Equal(t, http.StatusCreated, doJSON(r, http.MethodPost, "/users", `{"name":"Jane","email":"jane@example.com","role":"teacher"}`, nil))
+
+	var list AuditListResponse
+	assert.Equal(t, http.StatusOK, doJSON(r, http.MethodGet, "/audit?entity_type=student&entity_id=1", "", &list))
+	assert.Equal(t, 4, list.Total)
+	actions := make([]AuditAction, len(list.Data))
+	for i, e := range list.Data {
+		actions[i] = e.Action
+	}
+	assert.Equal(t, []AuditAction{AuditCreate, AuditUpdate, AuditDelete, AuditRestore}, actions)
+
+	created := list.Data[0]
+	assert.Equal(t, 1, *created.ActorID)
+	assert.Equal(t, "admin@example.com", created.ActorEmail)
+	assert.Equal(t, "req-1", created.RequestID)
+	assert.Equal(t, "203.0.113.7", created.ClientIP)
+	assert.Equal(t, FieldChange{Before: nil, After: "john@example.com"}, created.Changes["email"])
+
+	updated := list.Data[1]
+	assert.Equal(t, map[string]FieldChange{
+		"age":     {Before: float64(20), After: float64(21)},
+		"version": {Before: float64(1), After: float64(2)},
+	}, updated.Changes)
+	assert.Nil(t, list.Data[2].Changes["deleted_at"].Before)
+	assert.NotNil(t, list.Data[2].Changes["deleted_at"].After)
+	assert.Equal(t, []string{"deleted_at", "version"}, slices.Sorted(maps.Keys(list.Data[3].Changes)))
+
+	assert.Equal(t, http.StatusOK, doJSON(r, http.MethodGet, "/audit?actor_id=1&sort=-id&limit=1", "", &list))
+	assert.Equal(t, 5, list.Total)
+	assert.Equal(t, AuditEntityUser, list.Data[0].EntityType)
+	assert.NotEmpty(t, list.NextCursor)
+
+	assert.Equal(t, http.StatusOK, doJSON(r, http.MethodGet, "/audit?actor_id=2", "", &list))
+	assert.Equal(t, 0, list.Total)
+}
+
+func TestImportedStudentsAreAudited(t *testing.T) {
+	h, _ := newMemoryHandler(t)
+	r := testRouter(t, h, testAdmin, RouteMiddleware{})
+
+	importCSV := func(body string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/students/import", strings.NewReader(body))
+		req.Header.Set("Content-Type", "text/csv")
+		req.Header.Set("X-Request-ID", "req-import")
+		req.RemoteAddr = "203.0.113.7:4321"
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	// A rejected import stores nothing to audit.
+	assert.Equal(t, http.StatusUnprocessableEntity, importCSV("name,age,email\nJohn,x,john@example.com\n"))
+	assert.Equal(t, http.StatusCreated, importCSV("name,age,email\nJohn,20,john@example.com\nJane,22,jane@example.com\n"))
+
+	var list AuditListResponse
+	assert.Equal(t, http.StatusOK, doJSON(r, http.MethodGet, "/audit?entity_type=student", "", &list))
+	assert.Equal(t, 2, list.Total)
+	for i, e := range list.Data {
+		assert.Equal(t, AuditCreate, e.Action)
+		assert.Equal(t, i+1, e.EntityID)
+		assert.Equal(t, 1, *e.ActorID)
+		assert.Equal(t, "req-import", e.RequestID)
+		assert.Equal(t, "203.0.113.7", e.ClientIP)
+	}
+	assert.Equal(t, FieldChange{After: "jane@example.com"}, list.Data[1].Changes["email"])
+}
+
+func TestAuditFilterValidation(t *testing.T) {
+	h, _ := newMemoryHandler(t)
+	r := testRouter(t, h, testAdmin, RouteMiddleware{})
+
+	assert.Equal(t, http.StatusBadRequest, doJSON(r, http.MethodGet, "/audit?entity_type=course", "", nil))
+	assert.Equal(t, http.StatusBadRequest, doJSON(r, http.MethodGet, "/audit?entity_id=1", "", nil))
+	assert.Equal(t, http.StatusBadRequest, doJSON(r, http.MethodGet, "/audit?actor_id=me", "", nil))
+	assert.Equal(t, http.StatusBadRequest, doJSON(r, http.MethodGet, "/audit?sort=name", "", nil))
+}
+
+func TestAuditFailureRollsBackTheChange(t *testing.T) {
+	h, mock := newTestHandler(t)
+	r := testRouter(t, h, testAdmin, RouteMiddleware{})
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO students").
+		WithArgs("John Doe", 20, "john@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "version"}).AddRow(5, 1))
+	mock.ExpectExec("INSERT INTO audit_events").
+		WithArgs(1, "admin@example.com", AuditCreate, AuditEntityStudent, 5, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnError(errors.New("disk full"))
+	mock.ExpectRollback()
+
+	code := doJSON(r, http.MethodPost, "/students", `{"name":"John Doe","age":20,"email":"john@example.com"}`, nil)
+	assert.Equal(t, http.StatusInternalServerError, code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresAuditList(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+	repo := NewPostgresRepositories(&Db{db: mockDB}).Audit
+	entityID, actorID := 4, 1
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM audit_events WHERE entity_type = \$1 AND entity_id = \$2 AND actor_id = \$3`).
+		WithArgs(AuditEntityStudent, 4, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery(`SELECT id, occurred_at, actor_id, actor_email, action, entity_type, entity_id, changes, request_id, client_ip FROM audit_events WHERE entity_type = \$1 AND entity_id = \$2 AND actor_id = \$3 ORDER BY id DESC LIMIT 51`).
+		WithArgs(AuditEntityStudent, 4, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "occurred_at", "actor_id", "actor_email", "action", "entity_type", "entity_id", "changes", "request_id", "client_ip"}).
+			AddRow(12, time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC), 1, "admin@example.com", "update", "student", 4, []byte(`{"age":{"before":20,"after":21}}`), nil, "203.0.113.7"))
+
+	page, err := repo.List(context.Background(),
+		AuditFilter{EntityType: AuditEntityStudent, EntityID: &entityID, ActorID: &actorID},
+		ListOptions{Limit: 50, Sort: []SortField{{Column: "id", Desc: true}}},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, page.Total)
+	assert.Equal(t, int64(12), page.Items[0].ID)
+	assert.Equal(t, FieldChange{Before: float64(20), After: float64(21)}, page.Items[0].Changes["age"])
+	assert.Empty(t, page.Items[0].RequestID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
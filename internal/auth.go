@@ -0,0 +1,140 @@
+package internal
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const tokenIssuer = "student-api"
+
+// Claims are the JWT claims carried by an access token.
+type Claims struct {
+	UserID    int    `json:"uid"`
+	Email     string `json:"email"`
+	Role      Role   `json:"role"`
+	StudentID int    `json:"student_id,omitempty"`
+	// SessionID ties the access token to the refresh token family it was
+	// issued with, so revoking the session also invalidates the token.
+	SessionID string `json:"sid"`
+	jwt.RegisteredClaims
+}
+
+// TokenManager issues and validates signed access tokens and mints opaque
+// refresh tokens.
+type TokenManager struct {
+	secret     []byte
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+func NewTokenManager(secret string, accessTTL, refreshTTL time.Duration) *TokenManager {
+	return &TokenManager{
+		secret:     []byte(secret),
+		accessTTL:  accessTTL,
+		refreshTTL: refreshTTL,
+	}
+}
+
+// AccessTTL returns how long issued access tokens stay valid.
+func (m *TokenManager) AccessTTL() time.Duration {
+	return m.accessTTL
+}
+
+// RefreshTTL returns how long issued refresh tokens stay valid.
+func (m *TokenManager) RefreshTTL() time.Duration {
+	return m.refreshTTL
+}
+
+// IssueAccessToken signs a new HS256 access token for the given user and
+// session.
+func (m *TokenManager) IssueAccessToken(u User, sessionID string) (string, error) {
+	now := time.Now()
+	claims := Claims{
+		UserID:    u.ID,
+		Email:     u.Email,
+		Role:      u.Role,
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    tokenIssuer,
+			Subject:   strconv.Itoa(u.ID),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(m.accessTTL)),
+		},
+	}
+	if u.StudentID != nil {
+		claims.StudentID = *u.StudentID
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.secret)
+}
+
+// ParseAccessToken verifies the signature, issuer and expiry of a token and
+// returns its claims.
+func (m *TokenManager) ParseAccessToken(tokenStr string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (any, error) {
+		return m.secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(tokenIssuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid access token: %w", err)
+	}
+	if claims.UserID <= 0 || claims.SessionID == "" {
+		return nil, errors.New("invalid access token: missing user or session id")
+	}
+	return claims, nil
+}
+
+// NewRefreshToken returns a random opaque refresh token together with the
+// hash that is stored server-side. The plaintext token is never persisted.
+func NewRefreshToken() (token, hash string, err error) {
+	token, err = randomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	return token, HashRefreshToken(token), nil
+}
+
+// HashRefreshToken returns the hex encoded SHA-256 digest of a refresh token.
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewSessionID returns a random identifier for a refresh token family.
+func NewSessionID() (string, error) {
+	return randomToken(16)
+}
+
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error generating random token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashPassword returns the bcrypt hash of a plaintext password.
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("error hashing password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// CheckPassword reports whether password matches the stored bcrypt hash.
+func CheckPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
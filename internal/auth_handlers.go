@@ -0,0 +1,212 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Signup godoc
+// @Summary      Sign up
+// @Description  Registers a new user account and returns an access and refresh token
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        credentials  body      SignupRequest  true  "Signup payload"
+// @Success      201          {object}  TokenResponse
+// @Failure      400          {object}  ErrorResponse
+// @Failure      409          {object}  ErrorResponse
+// @Failure      422          {object}  ErrorResponse
+// @Failure      500          {object}  ErrorResponse
+// @Router       /auth/signup [post]
+func (h *Handler) Signup(c *gin.Context) {
+	var req SignupRequest
+	if !h.bindJSON(c, &req) {
+		return
+	}
+
+	hash, err := HashPassword(req.Password)
+	if err != nil {
+		h.log(c).Error("Failed to hash password:", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to create account"})
+		return
+	}
+
+	// Self-service accounts always start as students; elevated roles are
+	// granted by an admin through POST /users.
+	user, err := h.users.Create(c.Request.Context(), User{
+		Name:  req.Name,
+		Email: strings.ToLower(strings.TrimSpace(req.Email)),
+		Role:  RoleStudent,
+	}, hash)
+	if errors.Is(err, ErrDuplicate) {
+		h.log(c).Warn("Signup with an already registered email")
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Email is already registered"})
+		return
+	} else if err != nil {
+		h.log(c).Error("Failed to create account:", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to create account"})
+		return
+	}
+
+	h.log(c).Info("Signed up user successfully with ID:", user.ID)
+	h.startSession(c, http.StatusCreated, user)
+}
+
+// Login godoc
+// @Summary      Log in
+// @Description  Exchanges email and password for an access and refresh token
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        credentials  body      LoginRequest  true  "Login payload"
+// @Success      200          {object}  TokenResponse
+// @Failure      400          {object}  ErrorResponse
+// @Failure      401          {object}  ErrorResponse
+// @Failure      422          {object}  ErrorResponse
+// @Failure      500          {object}  ErrorResponse
+// @Router       /auth/login [post]
+func (h *Handler) Login(c *gin.Context) {
+	var req LoginRequest
+	if !h.bindJSON(c, &req) {
+		return
+	}
+
+	u, hash, err := h.users.GetCredentials(c.Request.Context(), strings.ToLower(strings.TrimSpace(req.Email)))
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		h.log(c).Error("Failed to fetch user for login:", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to log in"})
+		return
+	}
+	// Unknown emails and wrong passwords get the same answer so the endpoint
+	// cannot be used to enumerate accounts.
+	if err != nil || hash == "" || !CheckPassword(hash, req.Password) {
+		h.log(c).Warn("Failed login attempt")
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Invalid email or password"})
+		return
+	}
+
+	h.log(c).Info("Logged in user successfully with ID:", u.ID)
+	h.startSession(c, http.StatusOK, u)
+}
+
+// Refresh godoc
+// @Summary      Refresh tokens
+// @Description  Rotates a refresh token and returns a new access and refresh token. Presenting a refresh token that was already rotated revokes the whole session.
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        token  body      RefreshRequest  true  "Refresh token"
+// @Success      200    {object}  TokenResponse
+// @Failure      400    {object}  ErrorResponse
+// @Failure      401    {object}  ErrorResponse
+// @Failure      422    {object}  ErrorResponse
+// @Failure      500    {object}  ErrorResponse
+// @Router       /auth/refresh [post]
+func (h *Handler) Refresh(c *gin.Context) {
+	var req RefreshRequest
+	if !h.bindJSON(c, &req) {
+		return
+	}
+
+	refreshToken, newHash, err := NewRefreshToken()
+	if err != nil {
+		h.log(c).Error("Failed to generate refresh token:", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to refresh token"})
+		return
+	}
+
+	u, familyID, err := h.sessions.Rotate(c.Request.Context(),
+		HashRefreshToken(req.RefreshToken), newHash, time.Now().Add(h.tokens.RefreshTTL()))
+	switch {
+	case errors.Is(err, ErrRefreshTokenReused):
+		h.log(c).Warn("Refresh token reuse detected, revoked session for user ID:", u.ID)
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Refresh token reuse detected; session revoked"})
+		return
+	case errors.Is(err, ErrRefreshTokenInvalid):
+		h.log(c).Warn("Refresh attempted with unknown, revoked or expired token")
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Invalid refresh token"})
+		return
+	case err != nil:
+		h.log(c).Error("Failed to rotate refresh token:", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to refresh token"})
+		return
+	}
+
+	h.log(c).Info("Rotated refresh token for user ID:", u.ID)
+	h.respondWithTokens(c, http.StatusOK, u, familyID, refreshToken)
+}
+
+// Logout godoc
+// @Summary      Log out
+// @Description  Revokes the caller's session; its access and refresh tokens stop working immediately
+// @Tags         Auth
+// @Success      204
+// @Failure      401  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Security     BearerAuth
+// @Router       /auth/logout [post]
+func (h *Handler) Logout(c *gin.Context) {
+	claims, ok := CurrentClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Missing bearer token"})
+		return
+	}
+
+	if err := h.sessions.Revoke(c.Request.Context(), claims.SessionID); err != nil {
+		h.log(c).Error("Failed to revoke session:", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to log out"})
+		return
+	}
+
+	h.log(c).Info("Logged out user successfully with ID:", claims.UserID)
+	c.Status(http.StatusNoContent)
+}
+
+// SessionActive reports whether the refresh token family still has a usable
+// token. It implements SessionChecker for AuthMiddleware.
+func (h *Handler) SessionActive(ctx context.Context, sessionID string) (bool, error) {
+	return h.sessions.Active(ctx, sessionID)
+}
+
+// startSession opens a new refresh token family for the user and responds
+// with the first token pair.
+func (h *Handler) startSession(c *gin.Context, status int, u User) {
+	familyID, err := NewSessionID()
+	if err != nil {
+		h.log(c).Error("Failed to generate session ID:", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to start session"})
+		return
+	}
+	refreshToken, hash, err := NewRefreshToken()
+	if err != nil {
+		h.log(c).Error("Failed to generate refresh token:", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to start session"})
+		return
+	}
+	if err := h.sessions.Create(c.Request.Context(), u.ID, familyID, hash, time.Now().Add(h.tokens.RefreshTTL())); err != nil {
+		h.log(c).Error("Failed to store refresh token:", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to start session"})
+		return
+	}
+	h.respondWithTokens(c, status, u, familyID, refreshToken)
+}
+
+func (h *Handler) respondWithTokens(c *gin.Context, status int, u User, sessionID, refreshToken string) {
+	token, err := h.tokens.IssueAccessToken(u, sessionID)
+	if err != nil {
+		h.log(c).Error("Failed to sign access token:", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to issue access token"})
+		return
+	}
+	c.JSON(status, TokenResponse{
+		AccessToken:  token,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(h.tokens.AccessTTL().Seconds()),
+		RefreshToken: refreshToken,
+	})
+}
@@ -0,0 +1,63 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const claimsContextKey = "auth.claims"
+
+// SessionChecker reports whether the session an access token belongs to is
+// still active, allowing tokens to be revoked before they expire.
+type SessionChecker interface {
+	SessionActive(ctx context.Context, sessionID string) (bool, error)
+}
+
+// AuthMiddleware rejects requests that do not carry a valid
+// "Authorization: Bearer <token>" header or whose session has been revoked,
+// and stores the token claims in the Gin context for downstream handlers.
+func AuthMiddleware(tokens *TokenManager, sessions SessionChecker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		scheme, token, ok := strings.Cut(header, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+			c.Header("WWW-Authenticate", `Bearer realm="student-api"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, ErrorResponse{Error: "Missing bearer token"})
+			return
+		}
+
+		claims, err := tokens.ParseAccessToken(strings.TrimSpace(token))
+		if err != nil {
+			c.Header("WWW-Authenticate", `Bearer realm="student-api", error="invalid_token"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, ErrorResponse{Error: "Invalid or expired token"})
+			return
+		}
+
+		active, err := sessions.SessionActive(c.Request.Context(), claims.SessionID)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to verify session"})
+			return
+		}
+		if !active {
+			c.Header("WWW-Authenticate", `Bearer realm="student-api", error="invalid_token"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, ErrorResponse{Error: "Session has been revoked"})
+			return
+		}
+
+		c.Set(claimsContextKey, claims)
+		c.Next()
+	}
+}
+
+// CurrentClaims returns the claims stored by AuthMiddleware, if any.
+func CurrentClaims(c *gin.Context) (*Claims, bool) {
+	v, ok := c.Get(claimsContextKey)
+	if !ok {
+		return nil, false
+	}
+	claims, ok := v.(*Claims)
+	return claims, ok
+}
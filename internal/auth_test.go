@@ -0,0 +1,210 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenManagerRoundTrip(t *testing.T) {
+	tm := NewTokenManager("test-secret", time.Minute, time.Hour)
+	token, err := tm.IssueAccessToken(User{ID: 42, Email: "alice@example.com"}, "sess-1")
+	assert.NoError(t, err)
+
+	claims, err := tm.ParseAccessToken(token)
+	assert.NoError(t, err)
+	assert.Equal(t, 42, claims.UserID)
+	assert.Equal(t, "alice@example.com", claims.Email)
+	assert.Equal(t, "sess-1", claims.SessionID)
+
+	_, err = NewTokenManager("other-secret", time.Minute, time.Hour).ParseAccessToken(token)
+	assert.Error(t, err)
+}
+
+func TestTokenManagerRejectsExpiredToken(t *testing.T) {
+	tm := NewTokenManager("test-secret", -time.Minute, time.Hour)
+	token, err := tm.IssueAccessToken(User{ID: 1}, "sess-1")
+	assert.NoError(t, err)
+
+	_, err = tm.ParseAccessToken(token)
+	assert.Error(t, err)
+}
+
+// staticSessions is a SessionChecker that treats a fixed set of sessions as active.
+type staticSessions map[string]bool
+
+func (s staticSessions) SessionActive(_ context.Context, sessionID string) (bool, error) {
+	return s[sessionID], nil
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tm := NewTokenManager("test-secret", time.Minute, time.Hour)
+	r := gin.New()
+	r.GET("/protected", AuthMiddleware(tm, staticSessions{"live": true}), func(c *gin.Context) {
+		claims, _ := CurrentClaims(c)
+		c.JSON(http.StatusOK, gin.H{"uid": claims.UserID})
+	})
+
+	token, _ := tm.IssueAccessToken(User{ID: 5}, "live")
+	revoked, _ := tm.IssueAccessToken(User{ID: 5}, "revoked")
+	cases := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{"missing header", "", http.StatusUnauthorized},
+		{"wrong scheme", "Basic " + token, http.StatusUnauthorized},
+		{"garbage token", "Bearer not-a-jwt", http.StatusUnauthorized},
+		{"revoked session", "Bearer " + revoked, http.StatusUnauthorized},
+		{"valid token", "Bearer " + token, http.StatusOK},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/protected", nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			r.ServeHTTP(w, req)
+			assert.Equal(t, tc.want, w.Code)
+		})
+	}
+}
+
+func TestLoginSuccess(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h, mock := newTestHandler(t)
+	r := gin.New()
+	r.POST("/auth/login", h.Login)
+
+	hash, _ := HashPassword("correct-horse")
+	rows := sqlmock.NewRows([]string{"id", "name", "email", "role", "student_id", "password_hash"}).
+		AddRow(3, "Alice", "alice@example.com", "teacher", nil, hash)
+	mock.ExpectQuery("SELECT id, name, email, role, student_id, password_hash FROM users WHERE lower\\(email\\) = lower\\(\\$1\\)").
+		WithArgs("alice@example.com").WillReturnRows(rows)
+	mock.ExpectExec("INSERT INTO refresh_tokens").
+		WithArgs(3, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	w := httptest.NewRecorder()
+	body := `{"email":"Alice@example.com","password":"correct-horse"}`
+	req, _ := http.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(body))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp TokenResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	claims, err := h.tokens.ParseAccessToken(resp.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, claims.UserID)
+	assert.Equal(t, RoleTeacher, claims.Role)
+	assert.NotEmpty(t, resp.RefreshToken)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLoginWrongPassword(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h, mock := newTestHandler(t)
+	r := gin.New()
+	r.POST("/auth/login", h.Login)
+
+	hash, _ := HashPassword("correct-horse")
+	rows := sqlmock.NewRows([]string{"id", "name", "email", "role", "student_id", "password_hash"}).
+		AddRow(3, "Alice", "alice@example.com", "teacher", nil, hash)
+	mock.ExpectQuery("SELECT id, name, email, role, student_id, password_hash FROM users WHERE lower\\(email\\) = lower\\(\\$1\\)").
+		WithArgs("alice@example.com").WillReturnRows(rows)
+
+	w := httptest.NewRecorder()
+	body := `{"email":"alice@example.com","password":"battery-staple"}`
+	req, _ := http.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(body))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAdminCreatedUserLogsInWhateverTheEmailCase(t *testing.T) {
+	h, repos := newMemoryHandler(t)
+	r := testRouter(t, h, testAdmin, RouteMiddleware{})
+
+	var user User
+	code := doJSON(r, http.MethodPost, "/users", `{"name":"Jane","email":"Jane@Example.com","role":"teacher","password":"correct-horse"}`, &user)
+	assert.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, "jane@example.com", user.Email)
+	for _, email := range []string{"jane@example.com", "JANE@example.COM"} {
+		assert.Equal(t, http.StatusOK, doJSON(r, http.MethodPost, "/auth/login", `{"email":"`+email+`","password":"correct-horse"}`, nil), email)
+	}
+
+	// Accounts stored with capitals before emails were normalized still log in.
+	hash, _ := HashPassword("correct-horse")
+	_, err := repos.Users.Create(context.Background(), User{Name: "Old", Email: "Old.Account@example.com", Role: RoleTeacher}, hash)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, doJSON(r, http.MethodPost, "/auth/login", `{"email":"old.account@example.com","password":"correct-horse"}`, nil))
+}
+
+var refreshTokenColumns = []string{"id", "family_id", "expires_at", "revoked_at", "replaced_by", "id", "name", "email", "role", "student_id"}
+
+func TestRefreshRotatesToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h, mock := newTestHandler(t)
+	r := gin.New()
+	r.POST("/auth/refresh", h.Refresh)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT rt.id, rt.family_id").
+		WithArgs(HashRefreshToken("old-token")).
+		WillReturnRows(sqlmock.NewRows(refreshTokenColumns).
+			AddRow(10, "fam-1", time.Now().Add(time.Hour), nil, nil, 3, "Alice", "alice@example.com", "student", 7))
+	mock.ExpectQuery("INSERT INTO refresh_tokens").
+		WithArgs(3, "fam-1", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(11))
+	mock.ExpectExec("UPDATE refresh_tokens SET revoked_at = now\\(\\), replaced_by = \\$1 WHERE id = \\$2").
+		WithArgs(11, 10).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/auth/refresh", strings.NewReader(`{"refresh_token":"old-token"}`))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp TokenResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.NotEqual(t, "old-token", resp.RefreshToken)
+	claims, err := h.tokens.ParseAccessToken(resp.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "fam-1", claims.SessionID)
+	assert.Equal(t, 7, claims.StudentID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRefreshReuseRevokesFamily(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h, mock := newTestHandler(t)
+	r := gin.New()
+	r.POST("/auth/refresh", h.Refresh)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT rt.id, rt.family_id").
+		WithArgs(HashRefreshToken("rotated-token")).
+		WillReturnRows(sqlmock.NewRows(refreshTokenColumns).
+			AddRow(10, "fam-1", time.Now().Add(time.Hour), time.Now(), 11, 3, "Alice", "alice@example.com", "student", 7))
+	mock.ExpectExec("UPDATE refresh_tokens SET revoked_at = now\\(\\) WHERE family_id = \\$1").
+		WithArgs("fam-1").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/auth/refresh", strings.NewReader(`{"refresh_token":"rotated-token"}`))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
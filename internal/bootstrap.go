@@ -0,0 +1,48 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// CreateAdmin stores a new admin account. Signups are always students and
+// only admins can grant other roles, so this is how a deployment gets its
+// first admin. The account is audited like one made through POST /users,
+// without an actor.
+func CreateAdmin(ctx context.Context, repos Repositories, name, email, password string) (User, error) {
+	req := UserCreateRequest{Name: name, Email: strings.ToLower(strings.TrimSpace(email)), Role: RoleAdmin, Password: password}
+	if err := validateStruct(&req); err != nil {
+		_, resp := bindError(err)
+		msgs := make([]string, len(resp.Details))
+		for i, d := range resp.Details {
+			msgs[i] = d.Message
+		}
+		return User{}, errors.New(strings.Join(msgs, "; "))
+	}
+	if req.Password == "" {
+		return User{}, errors.New("password is required")
+	}
+	hash, err := HashPassword(req.Password)
+	if err != nil {
+		return User{}, err
+	}
+
+	var user User
+	err = repos.InTx(ctx, func(tx Repositories) error {
+		var err error
+		if user, err = tx.Users.Create(ctx, User{Name: req.Name, Email: req.Email, Role: RoleAdmin}, hash); err != nil {
+			return err
+		}
+		changes, err := diffFields(nil, user)
+		if err != nil {
+			return err
+		}
+		return tx.Audit.Record(ctx, AuditEvent{Action: AuditCreate, EntityType: AuditEntityUser, EntityID: user.ID, Changes: changes})
+	})
+	if errors.Is(err, ErrDuplicate) {
+		return User{}, fmt.Errorf("a user with email %s already exists", req.Email)
+	}
+	return user, err
+}
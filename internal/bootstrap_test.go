@@ -0,0 +1,47 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateAdminBootstrapsAdminAccess(t *testing.T) {
+	h, repos := newMemoryHandler(t)
+	ctx := context.Background()
+
+	admin, err := CreateAdmin(ctx, repos, "Root", " Root@Example.com", "correct-horse")
+	assert.NoError(t, err)
+	assert.Equal(t, RoleAdmin, admin.Role)
+	assert.Equal(t, "root@example.com", admin.Email)
+
+	// The new admin can log in and reach admin-only routes, with sessions
+	// checked as main checks them.
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	RegisterRoutes(r.Group("/"), RouteMiddleware{Auth: AuthMiddleware(h.tokens, h)}, h.Routes())
+	var tokens TokenResponse
+	assert.Equal(t, http.StatusOK, doJSON(r, http.MethodPost, "/auth/login", `{"email":"root@example.com","password":"correct-horse"}`, &tokens))
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/audit", nil)
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	events, err := repos.Audit.List(ctx, AuditFilter{}, ListOptions{Limit: 10, Sort: []SortField{{Column: "id"}}})
+	assert.NoError(t, err)
+	assert.Len(t, events.Items, 1)
+	assert.Equal(t, AuditCreate, events.Items[0].Action)
+	assert.Nil(t, events.Items[0].ActorID)
+
+	_, err = CreateAdmin(ctx, repos, "Root", "root@example.com", "correct-horse")
+	assert.EqualError(t, err, "a user with email root@example.com already exists")
+	_, err = CreateAdmin(ctx, repos, "Root", "other@example.com", "short")
+	assert.Error(t, err)
+	_, err = CreateAdmin(ctx, repos, "Root", "not-an-email", "correct-horse")
+	assert.Error(t, err)
+}
@@ -0,0 +1,584 @@
+package internal
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the complete runtime configuration of the API.
+//
+// Values are resolved in order of precedence: command line flags, then
+// environment variables, then the YAML profile, then built-in defaults.
+type Config struct {
+	// Env is the profile name; it selects config/<env>.yaml unless a file is
+	// given explicitly.
+	Env  string `yaml:"-"`
+	File string `yaml:"-"`
+
+	HTTP    HTTPConfig    `yaml:"http"`
+	DB      DBConfig      `yaml:"db"`
+	Auth    AuthConfig    `yaml:"auth"`
+	Log     LogConfig     `yaml:"log"`
+	Loki    LokiConfig    `yaml:"loki"`
+	Health  HealthConfig  `yaml:"health"`
+	Grading GradingConfig `yaml:"grading"`
+	Purge   PurgeConfig   `yaml:"purge"`
+
+	Idempotency IdempotencyConfig `yaml:"idempotency"`
+	RateLimit   RateLimitConfig   `yaml:"rate_limit"`
+	Tracing     TracingConfig     `yaml:"tracing"`
+}
+
+type HTTPConfig struct {
+	Addr string    `yaml:"addr"`
+	TLS  TLSConfig `yaml:"tls"`
+	// ShutdownDelay is how long readiness reports failure before the
+	// listener closes, giving load balancers time to stop routing to us.
+	ShutdownDelay time.Duration `yaml:"shutdown_delay"`
+	// DrainTimeout bounds how long in-flight requests may take to finish
+	// once shutdown starts.
+	DrainTimeout time.Duration `yaml:"drain_timeout"`
+	// RequireIfMatch rejects student writes that do not carry If-Match with
+	// 428 instead of applying them unconditionally.
+	RequireIfMatch bool `yaml:"require_if_match"`
+	// TrustedProxies lists the addresses or CIDR ranges of the proxies
+	// whose X-Forwarded-For and X-Real-IP headers name the client. Empty
+	// trusts none, so the client is always the peer of the connection.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+}
+
+// TLSConfig enables HTTPS when both files are set.
+type TLSConfig struct {
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+}
+
+func (t TLSConfig) Enabled() bool {
+	return t.CertFile != "" && t.KeyFile != ""
+}
+
+type DBConfig struct {
+	Host            string        `yaml:"host"`
+	Port            int           `yaml:"port"`
+	User            string        `yaml:"user"`
+	Password        string        `yaml:"password"`
+	Name            string        `yaml:"name"`
+	SSLMode         string        `yaml:"sslmode"`
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	// AutoMigrate applies pending migrations at startup.
+	AutoMigrate bool `yaml:"auto_migrate"`
+}
+
+// DSN renders the lib/pq connection string. Every value is quoted so that
+// passwords containing spaces, quotes or backslashes survive parsing.
+func (d DBConfig) DSN() string {
+	return fmt.Sprintf(
+		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		dsnQuote(d.Host), d.Port, dsnQuote(d.User), dsnQuote(d.Password), dsnQuote(d.Name), dsnQuote(d.SSLMode),
+	)
+}
+
+var dsnEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+// dsnQuote renders s as a single-quoted lib/pq connection string value.
+func dsnQuote(s string) string {
+	return "'" + dsnEscaper.Replace(s) + "'"
+}
+
+type AuthConfig struct {
+	JWTSecret       string        `yaml:"jwt_secret"`
+	AccessTokenTTL  time.Duration `yaml:"access_token_ttl"`
+	RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl"`
+}
+
+type LogConfig struct {
+	Level string `yaml:"level"`
+	// Format is "json", which Loki can query by field, or "text" for
+	// reading in a terminal.
+	Format string `yaml:"format"`
+}
+
+// LokiConfig configures log shipping. An empty URL disables it.
+type LokiConfig struct {
+	URL string `yaml:"url"`
+	// Labels are sent with every line, besides env, instance and version,
+	// which default to the profile, host name and build.
+	Labels map[string]string `yaml:"labels"`
+	// PromotedFields are log fields also sent as labels. Each keeps at most
+	// MaxLabelValues distinct values; the rest are sent as "other".
+	PromotedFields []string `yaml:"promoted_fields"`
+	MaxLabelValues int      `yaml:"max_label_values"`
+	// BufferSize lines may wait to be shipped; more are dropped rather than
+	// slowing down requests.
+	BufferSize int           `yaml:"buffer_size"`
+	BatchSize  int           `yaml:"batch_size"`
+	BatchWait  time.Duration `yaml:"batch_wait"`
+}
+
+// ReadyURL is Loki's readiness endpoint on the same host as the push URL.
+func (l LokiConfig) ReadyURL() string {
+	u, err := url.Parse(l.URL)
+	if err != nil {
+		return ""
+	}
+	u.Path, u.RawQuery = "/ready", ""
+	return u.String()
+}
+
+type HealthConfig struct {
+	// CheckTimeout bounds each readiness check.
+	CheckTimeout time.Duration `yaml:"check_timeout"`
+}
+
+// GradingConfig sets how course scores convert to letter grades and GPA
+// points on transcripts.
+type GradingConfig struct {
+	Scale GradingScale `yaml:"scale"`
+}
+
+// PurgeConfig sets how long deleted students and users are kept before
+// they are removed for good.
+type PurgeConfig struct {
+	// Retention is how long a deleted record can still be restored; zero
+	// keeps deleted records forever.
+	Retention time.Duration `yaml:"retention"`
+	// Interval is how often the purge runs.
+	Interval time.Duration `yaml:"interval"`
+}
+
+// IdempotencyConfig sets how long the response to a request sent with an
+// Idempotency-Key is kept for retries.
+type IdempotencyConfig struct {
+	TTL time.Duration `yaml:"ttl"`
+}
+
+// RateLimitConfig sets how many requests each client may send.
+type RateLimitConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Store keeps the token buckets: "memory" limits each replica on its
+	// own, "postgres" shares the limits between replicas.
+	Store string `yaml:"store"`
+	// Default applies to the routes without a limit of their own.
+	Default RateLimit `yaml:"default"`
+	// Routes overrides the limits of single routes, keyed by method and
+	// path as registered, e.g. "POST /auth/login".
+	Routes map[string]RateLimit `yaml:"routes"`
+	// APIKeys registers clients by name. A client sending its key in
+	// X-API-Key without logging in is limited by name rather than by IP.
+	APIKeys map[string]string `yaml:"api_keys"`
+}
+
+// rateLimitStores are the supported rate_limit.store values.
+var rateLimitStores = map[string]bool{"memory": true, "postgres": true}
+
+// TracingConfig configures OpenTelemetry tracing of requests and queries.
+type TracingConfig struct {
+	// Exporter is "otlp" to send spans to Endpoint, "stdout" to print them,
+	// or "none". Spans are recorded either way, so trace IDs still reach the
+	// logs and are propagated.
+	Exporter string `yaml:"exporter"`
+	// Endpoint is the OTLP/HTTP traces URL, e.g. http://jaeger:4318/v1/traces.
+	Endpoint string `yaml:"endpoint"`
+	// SampleRatio of new traces are exported. Requests continuing a trace
+	// follow the caller's decision.
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+// traceExporters are the supported tracing.exporter values.
+var traceExporters = map[string]bool{"otlp": true, "stdout": true, "none": true}
+
+// sslModes are the sslmode values lib/pq understands.
+var sslModes = map[string]bool{
+	"disable":     true,
+	"require":     true,
+	"verify-ca":   true,
+	"verify-full": true,
+}
+
+func defaultConfig() Config {
+	return Config{
+		Env:  "dev",
+		HTTP: HTTPConfig{Addr: ":8080", DrainTimeout: 15 * time.Second},
+		DB: DBConfig{
+			Host:            "localhost",
+			Port:            5432,
+			SSLMode:         "disable",
+			MaxOpenConns:    25,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 30 * time.Minute,
+		},
+		Auth: AuthConfig{
+			AccessTokenTTL:  15 * time.Minute,
+			RefreshTokenTTL: 30 * 24 * time.Hour,
+		},
+		Log: LogConfig{Level: "info", Format: "json"},
+		Loki: LokiConfig{
+			Labels:         map[string]string{"job": "student-api"},
+			PromotedFields: []string{"level", "route"},
+			MaxLabelValues: 100,
+			BufferSize:     10000,
+			BatchSize:      500,
+			BatchWait:      time.Second,
+		},
+		Health:  HealthConfig{CheckTimeout: 2 * time.Second},
+		Grading: GradingConfig{Scale: DefaultGradingScale()},
+		Purge:   PurgeConfig{Retention: 30 * 24 * time.Hour, Interval: time.Hour},
+
+		Idempotency: IdempotencyConfig{TTL: 24 * time.Hour},
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+			Store:   "memory",
+			Default: RateLimit{Requests: 120, Per: time.Minute, Burst: 60},
+		},
+		Tracing: TracingConfig{Exporter: "none", SampleRatio: 1},
+	}
+}
+
+// LoadConfig builds the configuration from the command line arguments (without
+// the program name), the environment and the selected YAML profile. It
+// returns the arguments left over after the flags, e.g. a subcommand.
+func LoadConfig(args []string) (*Config, []string, error) {
+	cfg := defaultConfig()
+
+	fs := flag.NewFlagSet("studentapi", flag.ContinueOnError)
+	envName := fs.String("env", "", "config profile to load from config/<env>.yaml (env APP_ENV)")
+	file := fs.String("config", "", "path to the config file; overrides -env (env CONFIG_FILE)")
+	addr := fs.String("addr", "", "HTTP listen address (env HTTP_ADDR)")
+	logLevel := fs.String("log-level", "", "log level (env LOG_LEVEL)")
+	dbHost := fs.String("db-host", "", "database host (env DB_HOST)")
+	dbPort := fs.Int("db-port", 0, "database port (env DB_PORT)")
+	dbSSLMode := fs.String("db-sslmode", "", "database sslmode (env DB_SSLMODE)")
+	lokiURL := fs.String("loki-url", "", "Loki push URL; empty disables shipping (env LOKI_URL)")
+	autoMigrate := fs.Bool("auto-migrate", false, "apply pending migrations at startup (env DB_AUTO_MIGRATE)")
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	// The profile and file are resolved first since they decide what the
+	// lower precedence layer looks like.
+	explicitFile := true
+	switch {
+	case set["config"]:
+		cfg.File = *file
+	case os.Getenv("CONFIG_FILE") != "":
+		cfg.File = os.Getenv("CONFIG_FILE")
+	default:
+		explicitFile = false
+		if set["env"] {
+			cfg.Env = *envName
+		} else if v := os.Getenv("APP_ENV"); v != "" {
+			cfg.Env = v
+		}
+		cfg.File = fmt.Sprintf("config/%s.yaml", cfg.Env)
+	}
+
+	if err := cfg.loadFile(explicitFile); err != nil {
+		return nil, nil, err
+	}
+	if err := cfg.loadEnv(); err != nil {
+		return nil, nil, err
+	}
+
+	if set["addr"] {
+		cfg.HTTP.Addr = *addr
+	}
+	if set["log-level"] {
+		cfg.Log.Level = *logLevel
+	}
+	if set["db-host"] {
+		cfg.DB.Host = *dbHost
+	}
+	if set["db-port"] {
+		cfg.DB.Port = *dbPort
+	}
+	if set["db-sslmode"] {
+		cfg.DB.SSLMode = *dbSSLMode
+	}
+	if set["loki-url"] {
+		cfg.Loki.URL = *lokiURL
+	}
+	if set["auto-migrate"] {
+		cfg.DB.AutoMigrate = *autoMigrate
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return &cfg, fs.Args(), nil
+}
+
+// loadFile merges the YAML file over the defaults. A missing profile file is
+// tolerated so the API can run from environment variables alone, but a file
+// named explicitly must exist.
+func (c *Config) loadFile(required bool) error {
+	raw, err := os.ReadFile(c.File)
+	if errors.Is(err, os.ErrNotExist) && !required {
+		c.File = ""
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("error reading config file: %w", err)
+	}
+	if err := yaml.Unmarshal(raw, c); err != nil {
+		return fmt.Errorf("error parsing config file %s: %w", c.File, err)
+	}
+	return nil
+}
+
+func (c *Config) loadEnv() error {
+	str := map[string]*string{
+		"HTTP_ADDR":        &c.HTTP.Addr,
+		"TLS_CERT_FILE":    &c.HTTP.TLS.CertFile,
+		"TLS_KEY_FILE":     &c.HTTP.TLS.KeyFile,
+		"DB_HOST":          &c.DB.Host,
+		"DB_USER":          &c.DB.User,
+		"DB_PASSWORD":      &c.DB.Password,
+		"DB_NAME":          &c.DB.Name,
+		"DB_SSLMODE":       &c.DB.SSLMode,
+		"JWT_SECRET":       &c.Auth.JWTSecret,
+		"LOG_LEVEL":        &c.Log.Level,
+		"LOG_FORMAT":       &c.Log.Format,
+		"RATE_LIMIT_STORE": &c.RateLimit.Store,
+		"TRACING_EXPORTER": &c.Tracing.Exporter,
+		"TRACING_ENDPOINT": &c.Tracing.Endpoint,
+	}
+	for key, dst := range str {
+		if v, ok := os.LookupEnv(key); ok {
+			*dst = v
+		}
+	}
+	// HTTP_TRUSTED_PROXIES is a comma separated list.
+	if v, ok := os.LookupEnv("HTTP_TRUSTED_PROXIES"); ok {
+		c.HTTP.TrustedProxies = nil
+		for _, p := range strings.Split(v, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				c.HTTP.TrustedProxies = append(c.HTTP.TrustedProxies, p)
+			}
+		}
+	}
+	// LOKI_URL may be set to an empty string to turn shipping off.
+	if v, ok := os.LookupEnv("LOKI_URL"); ok {
+		c.Loki.URL = v
+	}
+
+	ints := map[string]*int{
+		"DB_PORT":           &c.DB.Port,
+		"DB_MAX_OPEN_CONNS": &c.DB.MaxOpenConns,
+		"DB_MAX_IDLE_CONNS": &c.DB.MaxIdleConns,
+	}
+	for key, dst := range ints {
+		if v := os.Getenv(key); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("%s must be an integer: %w", key, err)
+			}
+			*dst = n
+		}
+	}
+
+	durations := map[string]*time.Duration{
+		"HTTP_SHUTDOWN_DELAY":  &c.HTTP.ShutdownDelay,
+		"HTTP_DRAIN_TIMEOUT":   &c.HTTP.DrainTimeout,
+		"DB_CONN_MAX_LIFETIME": &c.DB.ConnMaxLifetime,
+		"HEALTH_CHECK_TIMEOUT": &c.Health.CheckTimeout,
+		"ACCESS_TOKEN_TTL":     &c.Auth.AccessTokenTTL,
+		"REFRESH_TOKEN_TTL":    &c.Auth.RefreshTokenTTL,
+		"PURGE_RETENTION":      &c.Purge.Retention,
+		"PURGE_INTERVAL":       &c.Purge.Interval,
+		"IDEMPOTENCY_TTL":      &c.Idempotency.TTL,
+	}
+	for key, dst := range durations {
+		if v := os.Getenv(key); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("%s must be a duration: %w", key, err)
+			}
+			*dst = d
+		}
+	}
+
+	bools := map[string]*bool{
+		"DB_AUTO_MIGRATE":       &c.DB.AutoMigrate,
+		"HTTP_REQUIRE_IF_MATCH": &c.HTTP.RequireIfMatch,
+		"RATE_LIMIT_ENABLED":    &c.RateLimit.Enabled,
+	}
+	for key, dst := range bools {
+		if v := os.Getenv(key); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("%s must be a boolean: %w", key, err)
+			}
+			*dst = b
+		}
+	}
+	return nil
+}
+
+// Validate reports every invalid setting at once.
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if _, _, err := net.SplitHostPort(c.HTTP.Addr); err != nil {
+		fail("http.addr %q is not a host:port address", c.HTTP.Addr)
+	}
+	if (c.HTTP.TLS.CertFile == "") != (c.HTTP.TLS.KeyFile == "") {
+		fail("http.tls needs both cert_file and key_file")
+	}
+	if c.HTTP.ShutdownDelay < 0 {
+		fail("http.shutdown_delay must not be negative")
+	}
+	if c.HTTP.DrainTimeout <= 0 {
+		fail("http.drain_timeout must be positive")
+	}
+	for _, p := range c.HTTP.TrustedProxies {
+		if _, _, err := net.ParseCIDR(p); err != nil && net.ParseIP(p) == nil {
+			fail("http.trusted_proxies entry %q is not an IP address or CIDR range", p)
+		}
+	}
+
+	if c.DB.Host == "" {
+		fail("db.host is required")
+	}
+	if c.DB.Port < 1 || c.DB.Port > 65535 {
+		fail("db.port must be between 1 and 65535")
+	}
+	if c.DB.User == "" {
+		fail("db.user is required")
+	}
+	if c.DB.Name == "" {
+		fail("db.name is required")
+	}
+	if !sslModes[c.DB.SSLMode] {
+		fail("db.sslmode %q is not one of disable, require, verify-ca, verify-full", c.DB.SSLMode)
+	}
+	if c.DB.MaxOpenConns < 0 || c.DB.MaxIdleConns < 0 {
+		fail("db pool sizes must not be negative")
+	}
+	if c.DB.MaxOpenConns > 0 && c.DB.MaxIdleConns > c.DB.MaxOpenConns {
+		fail("db.max_idle_conns must not exceed db.max_open_conns")
+	}
+	if c.DB.ConnMaxLifetime < 0 {
+		fail("db.conn_max_lifetime must not be negative")
+	}
+
+	// Access tokens are signed with a shared secret; refuse to start without one.
+	if c.Auth.JWTSecret == "" {
+		fail("auth.jwt_secret is required (set JWT_SECRET)")
+	}
+	if c.Auth.AccessTokenTTL <= 0 || c.Auth.RefreshTokenTTL <= 0 {
+		fail("auth token TTLs must be positive")
+	}
+
+	if _, err := logrus.ParseLevel(c.Log.Level); err != nil {
+		fail("log.level: %v", err)
+	}
+	if c.Log.Format != "json" && c.Log.Format != "text" {
+		fail("log.format %q must be json or text", c.Log.Format)
+	}
+
+	if c.Loki.URL != "" {
+		if u, err := url.Parse(c.Loki.URL); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			fail("loki.url %q is not an http(s) URL", c.Loki.URL)
+		}
+	}
+	for name, value := range c.Loki.Labels {
+		if !model.LabelName(name).IsValidLegacy() || !model.LabelValue(value).IsValid() {
+			fail("loki.labels has an invalid label %q", name)
+		}
+	}
+	for _, name := range c.Loki.PromotedFields {
+		if _, ok := c.Loki.Labels[name]; ok || !model.LabelName(name).IsValidLegacy() {
+			fail("loki.promoted_fields: %q is not a valid label name or is already a label", name)
+		}
+	}
+	if c.Loki.MaxLabelValues <= 0 || c.Loki.BufferSize <= 0 || c.Loki.BatchSize <= 0 || c.Loki.BatchWait <= 0 {
+		fail("loki max_label_values, buffer_size, batch_size and batch_wait must be positive")
+	}
+
+	if c.Health.CheckTimeout <= 0 {
+		fail("health.check_timeout must be positive")
+	}
+
+	if err := c.Grading.Scale.Validate(); err != nil {
+		fail("grading.scale: %v", err)
+	}
+
+	if c.Purge.Retention < 0 {
+		fail("purge.retention must not be negative")
+	}
+	if c.Purge.Interval <= 0 {
+		fail("purge.interval must be positive")
+	}
+
+	if c.Idempotency.TTL <= 0 {
+		fail("idempotency.ttl must be positive")
+	}
+
+	if !rateLimitStores[c.RateLimit.Store] {
+		fail("rate_limit.store %q must be memory or postgres", c.RateLimit.Store)
+	}
+	if err := c.RateLimit.Default.Validate(); err != nil {
+		fail("rate_limit.default: %v", err)
+	}
+	for route, limit := range c.RateLimit.Routes {
+		if method, path, ok := strings.Cut(route, " "); !ok || method == "" || !strings.HasPrefix(path, "/") {
+			fail("rate_limit.routes key %q must be a method and path, e.g. \"POST /auth/login\"", route)
+		}
+		if err := limit.Validate(); err != nil {
+			fail("rate_limit.routes[%q]: %v", route, err)
+		}
+	}
+	for name, key := range c.RateLimit.APIKeys {
+		if key == "" {
+			fail("rate_limit.api_keys[%q] must not be empty", name)
+		}
+	}
+
+	if !traceExporters[c.Tracing.Exporter] {
+		fail("tracing.exporter %q must be otlp, stdout or none", c.Tracing.Exporter)
+	}
+	if c.Tracing.Exporter == "otlp" {
+		if u, err := url.Parse(c.Tracing.Endpoint); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			fail("tracing.endpoint %q is not an http(s) URL", c.Tracing.Endpoint)
+		}
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		fail("tracing.sample_ratio must be between 0 and 1")
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// LogFormatter returns the formatter log.format selects.
+func (c *Config) LogFormatter() logrus.Formatter {
+	if c.Log.Format == "text" {
+		return &logrus.TextFormatter{}
+	}
+	return &logrus.JSONFormatter{}
+}
+
+// LogLevel returns the parsed log level; Validate guarantees it parses.
+func (c *Config) LogLevel() logrus.Level {
+	level, _ := logrus.ParseLevel(c.Log.Level)
+	return level
+}
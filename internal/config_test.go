@@ -0,0 +1,107 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeConfigFile(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.yaml")
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	path := writeConfigFile(t, `
+http:
+  addr: ":9000"
+db:
+  host: file-host
+  port: 5433
+  user: app
+  name: studentdb
+  sslmode: require
+auth:
+  jwt_secret: from-file
+  access_token_ttl: 5m
+log:
+  level: warn
+loki:
+  labels:
+    env: test
+  promoted_fields: [level]
+`)
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("DB_HOST", "env-host")
+	t.Setenv("LOG_LEVEL", "debug")
+	t.Setenv("REFRESH_TOKEN_TTL", "1h")
+	t.Setenv("HTTP_TRUSTED_PROXIES", "10.0.0.1, 10.1.0.0/16")
+
+	cfg, rest, err := LoadConfig([]string{"-log-level", "error", "migrate", "up"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"migrate", "up"}, rest)
+
+	assert.Equal(t, ":9000", cfg.HTTP.Addr)  // file
+	assert.Equal(t, "env-host", cfg.DB.Host) // env over file
+	assert.Equal(t, "error", cfg.Log.Level)  // flag over env
+	assert.Equal(t, 5*time.Minute, cfg.Auth.AccessTokenTTL)
+	assert.Equal(t, time.Hour, cfg.Auth.RefreshTokenTTL)
+	assert.Equal(t, []string{"10.0.0.1", "10.1.0.0/16"}, cfg.HTTP.TrustedProxies)
+	assert.Equal(t, 25, cfg.DB.MaxOpenConns) // default
+	assert.Equal(t, map[string]string{"job": "student-api", "env": "test"}, cfg.Loki.Labels)
+	assert.Equal(t, []string{"level"}, cfg.Loki.PromotedFields)
+	assert.Equal(t, 500, cfg.Loki.BatchSize) // default
+	assert.Equal(t, "host='env-host' port=5433 user='app' password='' dbname='studentdb' sslmode='require'", cfg.DB.DSN())
+}
+
+func TestDSNQuotesValues(t *testing.T) {
+	db := DBConfig{Host: "db", Port: 5432, User: "app", Password: `it's a \secret`, Name: "studentdb", SSLMode: "disable"}
+	dsn := db.DSN()
+	assert.Equal(t, `host='db' port=5432 user='app' password='it\'s a \\secret' dbname='studentdb' sslmode='disable'`, dsn)
+
+	// Unquoted, lib/pq would read "a" and "\secret" as stray keys.
+	_, err := pq.NewConnector(dsn)
+	assert.NoError(t, err)
+}
+
+func TestLoadConfigMissingExplicitFile(t *testing.T) {
+	_, _, err := LoadConfig([]string{"-config", filepath.Join(t.TempDir(), "missing.yaml")})
+	assert.Error(t, err)
+}
+
+func TestConfigValidateReportsAllErrors(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.HTTP.Addr = "8080"
+	cfg.HTTP.TLS.CertFile = "cert.pem"
+	cfg.HTTP.TrustedProxies = []string{"10.0.0.0/8", "load-balancer"}
+	cfg.DB.SSLMode = "sometimes"
+	cfg.DB.MaxOpenConns, cfg.DB.MaxIdleConns = 2, 5
+	cfg.Log.Level = "loud"
+	cfg.Log.Format = "xml"
+	cfg.Loki.URL = "localhost:3100"
+	cfg.Loki.Labels["bad-label"] = "x"
+	cfg.Loki.PromotedFields = append(cfg.Loki.PromotedFields, "job")
+	cfg.RateLimit.Store = "redis"
+	cfg.Tracing.Exporter = "otlp"
+	cfg.RateLimit.Routes = map[string]RateLimit{"login": {Requests: 0, Per: time.Minute}}
+
+	err := cfg.Validate()
+	assert.Error(t, err)
+	for _, want := range []string{
+		"http.addr", "http.tls", `http.trusted_proxies entry "load-balancer"`, "db.user", "db.name", "db.sslmode",
+		"db.max_idle_conns", "auth.jwt_secret", "log.level", "log.format", "loki.url", "loki.labels",
+		"loki.promoted_fields",
+		"rate_limit.store", `rate_limit.routes key "login"`, `rate_limit.routes["login"]`,
+		"tracing.endpoint",
+	} {
+		assert.Contains(t, err.Error(), want)
+	}
+}
@@ -0,0 +1,139 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// ConstraintKind classifies integrity constraint violations.
+type ConstraintKind string
+
+const (
+	ConstraintUnique     ConstraintKind = "unique"
+	ConstraintForeignKey ConstraintKind = "foreign_key"
+	ConstraintCheck      ConstraintKind = "check"
+	ConstraintNotNull    ConstraintKind = "not_null"
+)
+
+// Machine-readable codes for constraint violations.
+const (
+	CodeAlreadyExists       = "already_exists"
+	CodeInvalidReference    = "invalid_reference"
+	CodeConstraintViolation = "constraint_violation"
+)
+
+// constraintFields maps database constraint names to the API field they
+// guard, so errors can point clients at the offending input.
+var constraintFields = map[string]string{
+	"students_email_key":    "email",
+	"students_age_check":    "age",
+	"users_email_key":       "email",
+	"users_email_lower_key": "email",
+	"users_role_check":      "role",
+	"users_student_id_fkey": "student_id",
+
+	"courses_code_term_key":      "code",
+	"courses_credits_check":      "credits",
+	"courses_capacity_check":     "capacity",
+	"enrollments_pkey":           "course_id",
+	"enrollments_course_id_fkey": "course_id",
+	"grades_score_check":         "score",
+	"grades_weight_check":        "weight",
+	"grades_enrollment_fkey":     "course_id",
+	"attendance_student_id_fkey": "student_id",
+	"attendance_status_check":    "status",
+}
+
+// ConstraintError reports a write rejected by an integrity constraint.
+// Unique violations also match ErrDuplicate with errors.Is.
+type ConstraintError struct {
+	Kind       ConstraintKind
+	Constraint string
+	// Field is the API field the constraint guards; empty when unknown.
+	Field string
+	Err   error
+}
+
+func (e *ConstraintError) Error() string {
+	return fmt.Sprintf("%s constraint %q violated", e.Kind, e.Constraint)
+}
+
+func (e *ConstraintError) Unwrap() error { return e.Err }
+
+func (e *ConstraintError) Is(target error) bool {
+	return target == ErrDuplicate && e.Kind == ConstraintUnique
+}
+
+// translatePgError converts Postgres integrity constraint violations into a
+// *ConstraintError and returns every other error unchanged.
+func translatePgError(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+	var kind ConstraintKind
+	switch pqErr.Code {
+	case "23505":
+		kind = ConstraintUnique
+	case "23503":
+		kind = ConstraintForeignKey
+	case "23514":
+		kind = ConstraintCheck
+	case "23502":
+		kind = ConstraintNotNull
+	default:
+		return err
+	}
+	field, ok := constraintFields[pqErr.Constraint]
+	if !ok {
+		// NOT NULL violations name the column rather than a constraint.
+		field = pqErr.Column
+	}
+	return &ConstraintError{Kind: kind, Constraint: pqErr.Constraint, Field: field, Err: err}
+}
+
+// response renders the violation: 409 for a taken unique value, 422 when the
+// input itself is unacceptable.
+func (e *ConstraintError) response() (int, ErrorResponse) {
+	field := e.Field
+	if field == "" {
+		field = "value"
+	}
+	var (
+		status  = http.StatusUnprocessableEntity
+		code    = CodeConstraintViolation
+		message string
+	)
+	switch e.Kind {
+	case ConstraintUnique:
+		status, code, message = http.StatusConflict, CodeAlreadyExists, field+" is already in use"
+	case ConstraintForeignKey:
+		code, message = CodeInvalidReference, field+" refers to a record that does not exist"
+	case ConstraintNotNull:
+		message = field + " is required"
+	default:
+		message = field + " is not an allowed value"
+	}
+
+	resp := ErrorResponse{Error: message, Code: code}
+	if e.Field != "" {
+		resp.Details = []FieldError{{Field: e.Field, Rule: string(e.Kind), Message: message}}
+	}
+	return status, resp
+}
+
+// respondConstraintError writes the response for a constraint violation and
+// reports whether err was one.
+func (h *Handler) respondConstraintError(c *gin.Context, err error) bool {
+	var ce *ConstraintError
+	if !errors.As(err, &ce) {
+		return false
+	}
+	h.log(c).Warn("Constraint violation:", ce)
+	c.JSON(ce.response())
+	return true
+}
@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTranslatePgError(t *testing.T) {
+	err := translatePgError(&pq.Error{Code: "23505", Constraint: "students_email_key"})
+	var ce *ConstraintError
+	assert.True(t, errors.As(err, &ce))
+	assert.Equal(t, ConstraintUnique, ce.Kind)
+	assert.Equal(t, "email", ce.Field)
+	assert.ErrorIs(t, err, ErrDuplicate)
+
+	err = translatePgError(&pq.Error{Code: "23502", Column: "name"})
+	assert.True(t, errors.As(err, &ce))
+	assert.Equal(t, "name", ce.Field)
+	assert.NotErrorIs(t, err, ErrDuplicate)
+
+	other := &pq.Error{Code: "40001"}
+	assert.Same(t, other, translatePgError(other))
+}
+
+func TestCreateStudentDuplicateEmailConflict(t *testing.T) {
+	h, _ := newMemoryHandler(t)
+
+	code, _ := postJSON(h.CreateStudent, `{"name": "John", "age": 20, "email": "john@example.com"}`)
+	assert.Equal(t, http.StatusCreated, code)
+
+	code, resp := postJSON(h.CreateStudent, `{"name": "Johnny", "age": 21, "email": "John@Example.com"}`)
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Equal(t, CodeAlreadyExists, resp.Code)
+	assert.Equal(t, []FieldError{{Field: "email", Rule: "unique", Message: "email is already in use"}}, resp.Details)
+}
+
+func TestPatchStudentDuplicateEmailConflict(t *testing.T) {
+	h, repos := newMemoryHandler(t)
+	ctx := context.Background()
+	_, _ = repos.Students.Create(ctx, Student{Name: "John Doe", Age: 20, Email: "john@example.com"})
+	_, _ = repos.Students.Create(ctx, Student{Name: "Jane Doe", Age: 22, Email: "jane@example.com"})
+
+	code, _ := patchStudent(t, h, mergePatchContentType, `{"email": "jane@example.com"}`)
+	assert.Equal(t, http.StatusConflict, code)
+}
+
+func TestCreateUserUnknownStudentIsUnprocessable(t *testing.T) {
+	h, mock := newTestHandler(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO users").
+		WillReturnError(&pq.Error{Code: "23503", Constraint: "users_student_id_fkey"})
+	mock.ExpectRollback()
+
+	code, resp := postJSON(h.CreateUser, `{"name": "Stu", "email": "stu@example.com", "student_id": 404}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	assert.Equal(t, CodeInvalidReference, resp.Code)
+	assert.Equal(t, "student_id", resp.Details[0].Field)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
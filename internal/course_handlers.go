@@ -0,0 +1,363 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Machine-readable codes for rejected enrollments and capacity changes.
+const (
+	CodeCourseFull              = "course_full"
+	CodeAlreadyEnrolled         = "already_enrolled"
+	CodeCapacityBelowEnrollment = "capacity_below_enrollment"
+)
+
+// GetCourses godoc
+// @Summary      List courses
+// @Description  Returns a page of courses with their current enrollment. Pages are keyset based: pass the returned next_cursor to fetch the following page with the same sort and filters.
+// @Tags         Courses
+// @Produce      json
+// @Param        limit           query     int     false  "Page size (1-200)"  default(50)
+// @Param        cursor          query     string  false  "Cursor from a previous page's next_cursor"
+// @Param        sort            query     string  false  "Comma separated sort keys (id, code, title, credits, term); prefix with - for descending"  example(term,code)
+// @Param        term            query     string  false  "Only courses in this term"  example(2025-fall)
+// @Param        title_contains  query     string  false  "Case-insensitive substring of the title"
+// @Success      200             {object}  CourseListResponse
+// @Failure      400             {object}  ErrorResponse
+// @Failure      401             {object}  ErrorResponse
+// @Failure      403             {object}  ErrorResponse
+// @Failure      500             {object}  ErrorResponse
+// @Security     BearerAuth
+// @Router       /courses [get]
+func (h *Handler) GetCourses(c *gin.Context) {
+	opts, err := parseListOptions(c, courseSortColumns)
+	if err != nil {
+		h.log(c).Warn("Invalid course list options:", err)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	page, err := h.courses.List(c.Request.Context(), parseCourseFilter(c), opts)
+	if err != nil {
+		h.log(c).Error("Error listing courses:", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch courses"})
+		return
+	}
+
+	resp := CourseListResponse{Data: page.Items, Total: page.Total}
+	if page.HasMore {
+		resp.NextCursor = opts.NextCursor(page.Items[len(page.Items)-1].sortValues(opts.Sort))
+	}
+
+	h.log(c).Info("Fetched courses successfully")
+	c.JSON(http.StatusOK, resp)
+}
+
+// CreateCourse godoc
+// @Summary      Create a course
+// @Description  Creates a new course. A course code may be offered once per term.
+// @Tags         Courses
+// @Accept       json
+// @Produce      json
+// @Param        course           body      CourseRequest  true   "Course payload"
+// @Param        Idempotency-Key  header    string         false  "Unique key that makes retrying this request safe"
+// @Success      201              {object}  Course
+// @Failure      400              {object}  ErrorResponse
+// @Failure      401              {object}  ErrorResponse
+// @Failure      403              {object}  ErrorResponse
+// @Failure      409              {object}  ErrorResponse
+// @Failure      422              {object}  ErrorResponse
+// @Failure      500              {object}  ErrorResponse
+// @Security     BearerAuth
+// @Router       /courses [post]
+func (h *Handler) CreateCourse(c *gin.Context) {
+	var req CourseRequest
+	if !h.bindJSON(c, &req) {
+		return
+	}
+
+	course, err := h.courses.Create(c.Request.Context(), req.course(0))
+	if h.respondConstraintError(c, err) {
+		return
+	} else if err != nil {
+		h.log(c).Error("Failed to create course:", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to create course"})
+		return
+	}
+
+	h.log(c).Info("Created course successfully with ID:", course.ID)
+	c.JSON(http.StatusCreated, course)
+}
+
+// GetCourseByID godoc
+// @Summary      Get a course by ID
+// @Description  Retrieves a course and its current enrollment by ID
+// @Tags         Courses
+// @Produce      json
+// @Param        id   path      int  true  "Course ID"
+// @Success      200  {object}  Course
+// @Failure      400  {object}  ErrorResponse
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Security     BearerAuth
+// @Router       /courses/{id} [get]
+func (h *Handler) GetCourseByID(c *gin.Context) {
+	id, ok := h.courseID(c)
+	if !ok {
+		return
+	}
+
+	course, err := h.courses.Get(c.Request.Context(), id)
+	if errors.Is(err, ErrNotFound) {
+		h.log(c).Warn("Course not found with ID:", id)
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Course not found"})
+		return
+	} else if err != nil {
+		h.log(c).Error("Failed to fetch course:", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch course"})
+		return
+	}
+
+	h.log(c).Info("Fetched course successfully with ID:", id)
+	c.JSON(http.StatusOK, course)
+}
+
+// UpdateCourse godoc
+// @Summary      Update a course
+// @Description  Replaces an existing course by ID
+// @Tags         Courses
+// @Accept       json
+// @Produce      json
+// @Param        id      path      int            true  "Course ID"
+// @Param        course  body      CourseRequest  true  "Course payload"
+// @Success      200     {object}  Course
+// @Failure      400     {object}  ErrorResponse
+// @Failure      401     {object}  ErrorResponse
+// @Failure      403     {object}  ErrorResponse
+// @Failure      404     {object}  ErrorResponse
+// @Failure      409     {object}  ErrorResponse
+// @Failure      422     {object}  ErrorResponse
+// @Failure      500     {object}  ErrorResponse
+// @Security     BearerAuth
+// @Router       /courses/{id} [put]
+func (h *Handler) UpdateCourse(c *gin.Context) {
+	id, ok := h.courseID(c)
+	if !ok {
+		return
+	}
+
+	var req CourseRequest
+	if !h.bindJSON(c, &req) {
+		return
+	}
+
+	updated, err := h.courses.Update(c.Request.Context(), req.course(id))
+	if errors.Is(err, ErrNotFound) {
+		h.log(c).Warn("Course not found for update with ID:", id)
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Course not found"})
+		return
+	} else if errors.Is(err, ErrCapacityBelowEnrollment) {
+		h.log(c).Warn("Capacity below enrollment, course ID:", id)
+		c.JSON(http.StatusConflict, ErrorResponse{
+			Error:   fmt.Sprintf("Capacity cannot be below the %d students already enrolled", updated.Enrolled),
+			Code:    CodeCapacityBelowEnrollment,
+			Details: []FieldError{{Field: "capacity", Rule: "min", Message: fmt.Sprintf("capacity must be at least %d", updated.Enrolled)}},
+		})
+		return
+	} else if h.respondConstraintError(c, err) {
+		return
+	} else if err != nil {
+		h.log(c).Error("Failed to update course:", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update course"})
+		return
+	}
+
+	h.log(c).Info("Updated course successfully with ID:", id)
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteCourse godoc
+// @Summary      Delete a course
+// @Description  Deletes a course and its enrollments by ID
+// @Tags         Courses
+// @Param        id   path      int  true  "Course ID"
+// @Success      204
+// @Failure      400  {object}  ErrorResponse
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Security     BearerAuth
+// @Router       /courses/{id} [delete]
+func (h *Handler) DeleteCourse(c *gin.Context) {
+	id, ok := h.courseID(c)
+	if !ok {
+		return
+	}
+
+	err := h.courses.Delete(c.Request.Context(), id)
+	if errors.Is(err, ErrNotFound) {
+		h.log(c).Warn("Course not found for deletion with ID:", id)
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Course not found"})
+		return
+	} else if err != nil {
+		h.log(c).Error("Failed to delete course:", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to delete course"})
+		return
+	}
+
+	h.log(c).Info("Deleted course successfully with ID:", id)
+	c.Status(http.StatusNoContent)
+}
+
+// GetCourseStudents godoc
+// @Summary      List enrolled students
+// @Description  Returns a page of the students enrolled in a course, paginated like GET /students.
+// @Tags         Courses
+// @Produce      json
+// @Param        id      path      int     true   "Course ID"
+// @Param        limit   query     int     false  "Page size (1-200)"  default(50)
+// @Param        cursor  query     string  false  "Cursor from a previous page's next_cursor"
+// @Param        sort    query     string  false  "Comma separated sort keys (id, name, age, email); prefix with - for descending"  example(name)
+// @Success      200     {object}  StudentListResponse
+// @Failure      400     {object}  ErrorResponse
+// @Failure      401     {object}  ErrorResponse
+// @Failure      403     {object}  ErrorResponse
+// @Failure      404     {object}  ErrorResponse
+// @Failure      500     {object}  ErrorResponse
+// @Security     BearerAuth
+// @Router       /courses/{id}/students [get]
+func (h *Handler) GetCourseStudents(c *gin.Context) {
+	id, ok := h.courseID(c)
+	if !ok {
+		return
+	}
+	opts, err := parseListOptions(c, studentSortColumns)
+	if err != nil {
+		h.log(c).Warn("Invalid enrolled student list options:", err)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := h.courses.Get(ctx, id); errors.Is(err, ErrNotFound) {
+		h.log(c).Warn("Course not found with ID:", id)
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Course not found"})
+		return
+	} else if err != nil {
+		h.log(c).Error("Failed to fetch course:", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch enrolled students"})
+		return
+	}
+
+	page, err := h.courses.ListStudents(ctx, id, opts)
+	if err != nil {
+		h.log(c).Error("Error listing enrolled students:", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch enrolled students"})
+		return
+	}
+
+	resp := StudentListResponse{Data: page.Items, Total: page.Total}
+	if page.HasMore {
+		resp.NextCursor = opts.NextCursor(page.Items[len(page.Items)-1].sortValues(opts.Sort))
+	}
+
+	h.log(c).Info("Fetched enrolled students successfully for course ID:", id)
+	c.JSON(http.StatusOK, resp)
+}
+
+// EnrollStudent godoc
+// @Summary      Enroll a student in a course
+// @Description  Enrolls the student in a course if a seat is left. Student accounts may only enroll themselves.
+// @Tags         Courses
+// @Accept       json
+// @Produce      json
+// @Param        id               path      int                true   "Student ID"
+// @Param        enrollment       body      EnrollmentRequest  true   "Course to enroll in"
+// @Param        Idempotency-Key  header    string             false  "Unique key that makes retrying this request safe"
+// @Success      201              {object}  Enrollment
+// @Failure      400              {object}  ErrorResponse
+// @Failure      401              {object}  ErrorResponse
+// @Failure      403              {object}  ErrorResponse
+// @Failure      404              {object}  ErrorResponse
+// @Failure      409              {object}  ErrorResponse
+// @Failure      422              {object}  ErrorResponse
+// @Failure      500              {object}  ErrorResponse
+// @Security     BearerAuth
+// @Router       /students/{id}/enrollments [post]
+func (h *Handler) EnrollStudent(c *gin.Context) {
+	idStr := c.Param("id")
+	studentID, err := strconv.Atoi(idStr)
+	if err != nil || studentID <= 0 {
+		h.log(c).Error("Invalid student ID for enrollment:", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid ID"})
+		return
+	}
+
+	var req EnrollmentRequest
+	if !h.bindJSON(c, &req) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := h.students.Get(ctx, studentID); errors.Is(err, ErrNotFound) {
+		h.log(c).Warn("Student not found for enrollment with ID:", studentID)
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Student not found"})
+		return
+	} else if err != nil {
+		h.log(c).Error("Failed to fetch student for enrollment:", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to enroll student"})
+		return
+	}
+
+	enrollment, err := h.courses.Enroll(ctx, studentID, req.CourseID)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		h.log(c).Warn("Enrollment into unknown course ID:", req.CourseID)
+		c.JSON(http.StatusUnprocessableEntity, ErrorResponse{
+			Error:   "Course not found",
+			Code:    CodeInvalidReference,
+			Details: []FieldError{{Field: "course_id", Rule: string(ConstraintForeignKey), Message: "course_id refers to a course that does not exist"}},
+		})
+		return
+	case errors.Is(err, ErrAlreadyEnrolled):
+		h.log(c).Warn("Student already enrolled, student ID:", studentID)
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Student is already enrolled in this course", Code: CodeAlreadyEnrolled})
+		return
+	case errors.Is(err, ErrCourseFull):
+		h.log(c).Warn("Course is full, course ID:", req.CourseID)
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Course is full", Code: CodeCourseFull})
+		return
+	case h.respondConstraintError(c, err):
+		return
+	case err != nil:
+		h.log(c).Error("Failed to enroll student:", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to enroll student"})
+		return
+	}
+
+	h.log(c).Info("Enrolled student ID:", studentID, " in course ID:", req.CourseID)
+	c.JSON(http.StatusCreated, enrollment)
+}
+
+// courseID parses the :id path parameter, answering 400 when it is invalid.
+func (h *Handler) courseID(c *gin.Context) (int, bool) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id <= 0 {
+		h.log(c).Error("Invalid course ID:", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid ID"})
+		return 0, false
+	}
+	return id, true
+}
+
+func (r CourseRequest) course(id int) Course {
+	return Course{ID: id, Code: r.Code, Title: r.Title, Credits: r.Credits, Capacity: r.Capacity, Term: r.Term}
+}
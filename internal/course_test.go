@@ -0,0 +1,262 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func doJSON(r *gin.Engine, method, path, body string, out any) int {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	if out != nil {
+		_ = json.Unmarshal(w.Body.Bytes(), out)
+	}
+	return w.Code
+}
+
+func TestCourseCRUD(t *testing.T) {
+	h, _ := newMemoryHandler(t)
+	r := testRouter(t, h, testAdmin, RouteMiddleware{})
+
+	var created Course
+	code := doJSON(r, http.MethodPost, "/courses", `{"code": "CS101", "title": "Intro to CS", "credits": 4, "capacity": 30, "term": "2025-fall"}`, &created)
+	assert.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, 1, created.ID)
+
+	var resp ErrorResponse
+	code = doJSON(r, http.MethodPost, "/courses", `{"code": "cs101", "title": "Again", "credits": 4, "capacity": 30, "term": "2025-fall"}`, &resp)
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Equal(t, "code", resp.Details[0].Field)
+
+	code = doJSON(r, http.MethodPost, "/courses", `{"code": "CS101", "title": "Intro to CS", "credits": 4, "capacity": 30, "term": "2026-spring"}`, nil)
+	assert.Equal(t, http.StatusCreated, code)
+
+	var updated Course
+	code = doJSON(r, http.MethodPut, "/courses/1", `{"code": "CS101", "title": "Introduction to CS", "credits": 5, "capacity": 25, "term": "2025-fall"}`, &updated)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "Introduction to CS", updated.Title)
+
+	var list CourseListResponse
+	code = doJSON(r, http.MethodGet, "/courses?term=2025-fall", "", &list)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 1, list.Total)
+
+	assert.Equal(t, http.StatusNoContent, doJSON(r, http.MethodDelete, "/courses/1", "", nil))
+	assert.Equal(t, http.StatusNotFound, doJSON(r, http.MethodGet, "/courses/1", "", nil))
+}
+
+func TestEnrollStudentEnforcesCapacityAndDuplicates(t *testing.T) {
+	h, repos := newMemoryHandler(t)
+	r := testRouter(t, h, testAdmin, RouteMiddleware{})
+	ctx := context.Background()
+	alice, _ := repos.Students.Create(ctx, Student{Name: "Alice", Age: 20, Email: "alice@example.com"})
+	_, _ = repos.Students.Create(ctx, Student{Name: "Bob", Age: 21, Email: "bob@example.com"})
+	course, _ := repos.Courses.Create(ctx, Course{Code: "MA201", Title: "Linear Algebra", Credits: 3, Capacity: 1, Term: "2025-fall"})
+
+	var enrollment Enrollment
+	code := doJSON(r, http.MethodPost, "/students/1/enrollments", `{"course_id": 1}`, &enrollment)
+	assert.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, alice.ID, enrollment.StudentID)
+	assert.Equal(t, course.ID, enrollment.CourseID)
+	assert.False(t, enrollment.EnrolledAt.IsZero())
+
+	var resp ErrorResponse
+	code = doJSON(r, http.MethodPost, "/students/1/enrollments", `{"course_id": 1}`, &resp)
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Equal(t, CodeAlreadyEnrolled, resp.Code)
+
+	code = doJSON(r, http.MethodPost, "/students/2/enrollments", `{"course_id": 1}`, &resp)
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Equal(t, CodeCourseFull, resp.Code)
+
+	code = doJSON(r, http.MethodPost, "/students/2/enrollments", `{"course_id": 99}`, &resp)
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	assert.Equal(t, CodeInvalidReference, resp.Code)
+
+	assert.Equal(t, http.StatusNotFound, doJSON(r, http.MethodPost, "/students/42/enrollments", `{"course_id": 1}`, nil))
+
+	var got Course
+	doJSON(r, http.MethodGet, "/courses/1", "", &got)
+	assert.Equal(t, 1, got.Enrolled)
+
+	var students StudentListResponse
+	code = doJSON(r, http.MethodGet, "/courses/1/students", "", &students)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 1, students.Total)
+	assert.Equal(t, "Alice", students.Data[0].Name)
+
+	assert.Equal(t, http.StatusNotFound, doJSON(r, http.MethodGet, "/courses/99/students", "", nil))
+}
+
+func TestUpdateCourseKeepsCapacityAboveEnrollment(t *testing.T) {
+	h, repos := newMemoryHandler(t)
+	r := testRouter(t, h, testAdmin, RouteMiddleware{})
+	ctx := context.Background()
+	alice, _ := repos.Students.Create(ctx, Student{Name: "Alice", Age: 20, Email: "alice@example.com"})
+	bob, _ := repos.Students.Create(ctx, Student{Name: "Bob", Age: 21, Email: "bob@example.com"})
+	course, _ := repos.Courses.Create(ctx, Course{Code: "CS101", Title: "Intro", Credits: 3, Capacity: 3, Term: "2025-fall"})
+	_, _ = repos.Courses.Enroll(ctx, alice.ID, course.ID)
+	_, _ = repos.Courses.Enroll(ctx, bob.ID, course.ID)
+
+	var resp ErrorResponse
+	code := doJSON(r, http.MethodPut, "/courses/1", `{"code": "CS101", "title": "Intro", "credits": 3, "capacity": 1, "term": "2025-fall"}`, &resp)
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Equal(t, CodeCapacityBelowEnrollment, resp.Code)
+	assert.Equal(t, "capacity", resp.Details[0].Field)
+	got, _ := repos.Courses.Get(ctx, course.ID)
+	assert.Equal(t, 3, got.Capacity)
+
+	// A deleted student's seat does not count.
+	assert.NoError(t, repos.Students.Delete(ctx, bob.ID, 0))
+	var updated Course
+	code = doJSON(r, http.MethodPut, "/courses/1", `{"code": "CS101", "title": "Intro", "credits": 3, "capacity": 1, "term": "2025-fall"}`, &updated)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 1, updated.Capacity)
+	assert.Equal(t, 1, updated.Enrolled)
+}
+
+func TestPostgresUpdateCourseLocksAndChecksEnrollment(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+	repo := NewPostgresRepositories(&Db{db: mockDB}).Courses
+	columns := []string{"id", "code", "title", "credits", "capacity", "term", "count"}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM courses WHERE id = \$1 FOR UPDATE`).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(7, "CS101", "Intro", 3, 30, "2025-fall", 12))
+	mock.ExpectRollback()
+
+	_, err = repo.Update(context.Background(), Course{ID: 7, Code: "CS101", Title: "Intro", Credits: 3, Capacity: 10, Term: "2025-fall"})
+	assert.ErrorIs(t, err, ErrCapacityBelowEnrollment)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("FOR UPDATE").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(7, "CS101", "Intro", 3, 30, "2025-fall", 12))
+	mock.ExpectQuery("UPDATE courses SET").
+		WithArgs("CS101", "Intro", 3, 12, "2025-fall", 7).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(7, "CS101", "Intro", 3, 12, "2025-fall", 12))
+	mock.ExpectCommit()
+
+	updated, err := repo.Update(context.Background(), Course{ID: 7, Code: "CS101", Title: "Intro", Credits: 3, Capacity: 12, Term: "2025-fall"})
+	assert.NoError(t, err)
+	assert.Equal(t, 12, updated.Capacity)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresEnrollLocksCourse(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+	repo := NewPostgresRepositories(&Db{db: mockDB}).Courses
+
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT capacity FROM courses WHERE id = \$1 FOR UPDATE`).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"capacity"}).AddRow(2))
+	mock.ExpectQuery("SELECT COUNT").
+		WithArgs(7, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"count", "bool_or"}).AddRow(1, false))
+	mock.ExpectQuery("INSERT INTO enrollments").
+		WithArgs(3, 7).
+		WillReturnRows(sqlmock.NewRows([]string{"enrolled_at"}).AddRow(now))
+	mock.ExpectCommit()
+
+	e, err := repo.Enroll(context.Background(), 3, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, now, e.EnrolledAt)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("FOR UPDATE").
+		WillReturnRows(sqlmock.NewRows([]string{"capacity"}).AddRow(1))
+	mock.ExpectQuery("SELECT COUNT").
+		WillReturnRows(sqlmock.NewRows([]string{"count", "bool_or"}).AddRow(1, false))
+	mock.ExpectRollback()
+
+	_, err = repo.Enroll(context.Background(), 4, 7)
+	assert.ErrorIs(t, err, ErrCourseFull)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeletedStudentsDoNotHoldSeats(t *testing.T) {
+	_, repos := newMemoryHandler(t)
+	ctx := context.Background()
+	alice, _ := repos.Students.Create(ctx, Student{Name: "Alice", Age: 20, Email: "alice@example.com"})
+	bob, _ := repos.Students.Create(ctx, Student{Name: "Bob", Age: 21, Email: "bob@example.com"})
+	course, err := repos.Courses.Create(ctx, Course{Code: "CS101", Title: "Intro", Credits: 3, Capacity: 1, Term: "2025-fall"})
+	assert.NoError(t, err)
+
+	_, err = repos.Courses.Enroll(ctx, alice.ID, course.ID)
+	assert.NoError(t, err)
+	_, err = repos.Courses.Enroll(ctx, bob.ID, course.ID)
+	assert.ErrorIs(t, err, ErrCourseFull)
+
+	// Deleting Alice frees her seat.
+	assert.NoError(t, repos.Students.Delete(ctx, alice.ID, 0))
+	got, _ := repos.Courses.Get(ctx, course.ID)
+	assert.Equal(t, 0, got.Enrolled)
+	_, err = repos.Courses.Enroll(ctx, bob.ID, course.ID)
+	assert.NoError(t, err)
+	got, _ = repos.Courses.Get(ctx, course.ID)
+	assert.Equal(t, 1, got.Enrolled)
+
+	day := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	err = repos.Attendance.Record(ctx, day, []AttendanceEntry{{StudentID: bob.ID, Status: AttendancePresent}})
+	assert.NoError(t, err)
+	_, err = repos.Students.Restore(ctx, alice.ID)
+	assert.NoError(t, err)
+	err = repos.Attendance.Record(ctx, day, []AttendanceEntry{{StudentID: alice.ID, Status: AttendanceLate}})
+	assert.NoError(t, err)
+	assert.NoError(t, repos.Students.Delete(ctx, alice.ID, 0))
+	report, err := repos.Attendance.Report(ctx, day, day)
+	assert.NoError(t, err)
+	assert.Len(t, report, 1)
+	assert.Equal(t, bob.ID, report[0].StudentID)
+}
+
+func TestPostgresSeatCountsSkipDeletedStudents(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+	repos := NewPostgresRepositories(&Db{db: mockDB})
+	ctx := context.Background()
+
+	mock.ExpectQuery(`SELECT id, code, title, credits, capacity, term, \(SELECT COUNT\(\*\) FROM enrollments e JOIN students s ON s.id = e.student_id WHERE e.course_id = courses.id AND s.deleted_at IS NULL\) FROM courses WHERE id = \$1`).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "code", "title", "credits", "capacity", "term", "count"}).AddRow(7, "CS101", "Intro", 3, 1, "2025-fall", 0))
+	_, err = repos.Courses.Get(ctx, 7)
+	assert.NoError(t, err)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("FOR UPDATE").
+		WillReturnRows(sqlmock.NewRows([]string{"capacity"}).AddRow(1))
+	mock.ExpectQuery(`FROM enrollments e JOIN students s ON s.id = e.student_id WHERE e.course_id = \$1 AND s.deleted_at IS NULL`).
+		WithArgs(7, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"count", "bool_or"}).AddRow(0, false))
+	mock.ExpectQuery("INSERT INTO enrollments").
+		WillReturnRows(sqlmock.NewRows([]string{"enrolled_at"}).AddRow(time.Now()))
+	mock.ExpectCommit()
+	_, err = repos.Courses.Enroll(ctx, 3, 7)
+	assert.NoError(t, err)
+
+	day := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`FROM attendance\s+JOIN students s ON s.id = attendance.student_id\s+WHERE day BETWEEN \$1::date AND \$2::date AND s.deleted_at IS NULL GROUP BY student_id`).
+		WithArgs(day, day).
+		WillReturnRows(sqlmock.NewRows([]string{"student_id", "days", "present", "late", "absent", "excused", "rate"}))
+	_, err = repos.Attendance.Report(ctx, day, day)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -1,44 +1,28 @@
 package internal
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"log"
-	"os"
 
-	"github.com/joho/godotenv"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Db struct {
 	db *sql.DB
 }
 
-func InitDB() (*Db, error) {
-	// Load local env file if present; otherwise rely on environment variables
-	if err := godotenv.Load("config/dev.env"); err != nil {
-		log.Println("config/dev.env not found; falling back to environment variables")
-	}
-
-	host := os.Getenv("DB_HOST")
-	port := os.Getenv("DB_PORT")
-	user := os.Getenv("DB_USER")
-	password := os.Getenv("DB_PASSWORD")
-	dbname := os.Getenv("DB_NAME")
-
-	if port == "" {
-		port = "5432"
-	}
-
-	connStr := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		host, port, user, password, dbname,
-	)
-
-	db, err := sql.Open("postgres", connStr)
+// InitDB connects to Postgres. Every query is traced as a span of tp.
+func InitDB(cfg DBConfig, tp trace.TracerProvider) (*Db, error) {
+	connector, err := pq.NewConnector(cfg.DSN())
 	if err != nil {
 		return nil, fmt.Errorf("error connecting to database: %w", err)
 	}
+	db := sql.OpenDB(newTracedConnector(connector, tp))
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
 
 	if err := db.Ping(); err != nil {
 		return nil, fmt.Errorf("cannot reach database: %w", err)
@@ -48,3 +32,13 @@ func InitDB() (*Db, error) {
 
 	return &Db{db: db}, nil
 }
+
+// Close waits for in-use connections to be returned and closes the pool.
+func (d *Db) Close() error {
+	return d.db.Close()
+}
+
+// Ping verifies a connection to the database can be used.
+func (d *Db) Ping(ctx context.Context) error {
+	return d.db.PingContext(ctx)
+}
@@ -0,0 +1,165 @@
+package internal
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Attributes of query spans.
+const (
+	dbSystemKey       = attribute.Key("db.system.name")
+	dbQueryTextKey    = attribute.Key("db.query.text")
+	dbQuerySummaryKey = attribute.Key("db.query.summary")
+	dbOperationKey    = attribute.Key("db.operation.name")
+	dbCollectionKey   = attribute.Key("db.collection.name")
+	dbRowsAffectedKey = attribute.Key("db.response.rows_affected")
+)
+
+// tracedConnector opens connections that start a span for every query and
+// statement they run, as a child of the span in the caller's context.
+// Arguments are never recorded, as they may hold personal data.
+type tracedConnector struct {
+	driver.Connector
+	tracer trace.Tracer
+}
+
+func newTracedConnector(c driver.Connector, tp trace.TracerProvider) driver.Connector {
+	return &tracedConnector{Connector: c, tracer: tp.Tracer(tracerName)}
+}
+
+func (c *tracedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedConn{Conn: conn, tracer: c.tracer}, nil
+}
+
+// tracedConn passes everything through to the driver's connection, tracing
+// ExecContext and QueryContext. The optional interfaces database/sql looks
+// for are implemented by deferring to the driver, so wrapping it does not
+// change how statements are run.
+type tracedConn struct {
+	driver.Conn
+	tracer trace.Tracer
+}
+
+func (c *tracedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	ctx, span := c.start(ctx, query)
+	res, err := execer.ExecContext(ctx, query, args)
+	if err == nil {
+		if n, err := res.RowsAffected(); err == nil {
+			span.SetAttributes(dbRowsAffectedKey.Int64(n))
+		}
+	}
+	endSpan(span, err)
+	return res, err
+}
+
+func (c *tracedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	ctx, span := c.start(ctx, query)
+	rows, err := queryer.QueryContext(ctx, query, args)
+	endSpan(span, err)
+	return rows, err
+}
+
+func (c *tracedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return p.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c *tracedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	if opts.Isolation != 0 || opts.ReadOnly {
+		return nil, errors.New("driver does not support transaction options")
+	}
+	return c.Conn.Begin()
+}
+
+func (c *tracedConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *tracedConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *tracedConn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *tracedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if ch, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return ch.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+func (c *tracedConn) start(ctx context.Context, query string) (context.Context, trace.Span) {
+	operation, collection := summarizeQuery(query)
+	summary := strings.TrimSpace(operation + " " + collection)
+	attrs := []attribute.KeyValue{
+		dbSystemKey.String("postgresql"),
+		dbQueryTextKey.String(query),
+		dbQuerySummaryKey.String(summary),
+		dbOperationKey.String(operation),
+	}
+	if collection != "" {
+		attrs = append(attrs, dbCollectionKey.String(collection))
+	}
+	return c.tracer.Start(ctx, summary, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// queryTable finds the first table a statement reads or writes.
+var queryTable = regexp.MustCompile(`(?i)\b(?:from|into|update|join)\s+([a-z_][a-z0-9_.]*)`)
+
+// summarizeQuery names a statement by its operation and first table, e.g.
+// "SELECT students", which groups spans without the cardinality of the full
+// text.
+func summarizeQuery(query string) (operation, collection string) {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "", ""
+	}
+	operation = strings.ToUpper(fields[0])
+	if m := queryTable.FindStringSubmatch(query); m != nil {
+		collection = strings.ToLower(m[1])
+	}
+	return operation, collection
+}
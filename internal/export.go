@@ -0,0 +1,302 @@
+package internal
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+)
+
+// exportFlushEvery is how many rows the streaming writers buffer before
+// flushing to the client.
+const exportFlushEvery = 500
+
+// exportRow is a record that can be written to every export format.
+type exportRow interface {
+	// exportValues returns the record's cells in column order; nil leaves a
+	// cell empty.
+	exportValues() []any
+}
+
+var (
+	studentExportColumns = []string{"id", "name", "age", "email"}
+	userExportColumns    = []string{"id", "name", "email", "role", "student_id"}
+)
+
+func (s Student) exportValues() []any {
+	return []any{s.ID, s.Name, s.Age, s.Email}
+}
+
+func (u User) exportValues() []any {
+	var studentID any
+	if u.StudentID != nil {
+		studentID = *u.StudentID
+	}
+	return []any{u.ID, u.Name, u.Email, string(u.Role), studentID}
+}
+
+// exportWriter encodes rows in one file format.
+type exportWriter interface {
+	Write(row exportRow) error
+	// Close finishes the file; nothing may be written after it.
+	Close() error
+	// Discard abandons an unfinished file.
+	Discard()
+}
+
+type exportFormat struct {
+	contentType string
+	newWriter   func(w io.Writer, columns []string) (exportWriter, error)
+}
+
+var exportFormats = map[string]exportFormat{
+	"csv":   {contentType: "text/csv; charset=utf-8", newWriter: newCSVExportWriter},
+	"jsonl": {contentType: "application/x-ndjson", newWriter: newJSONLExportWriter},
+	"xlsx":  {contentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", newWriter: newXLSXExportWriter},
+}
+
+type csvExportWriter struct {
+	w    *csv.Writer
+	rows int
+}
+
+func newCSVExportWriter(w io.Writer, columns []string) (exportWriter, error) {
+	cw := csv.NewWriter(w)
+	return &csvExportWriter{w: cw}, cw.Write(columns)
+}
+
+func (e *csvExportWriter) Write(row exportRow) error {
+	values := row.exportValues()
+	record := make([]string, len(values))
+	for i, v := range values {
+		if s, ok := v.(string); ok {
+			record[i] = escapeCSVFormula(s)
+		} else if v != nil {
+			record[i] = fmt.Sprint(v)
+		}
+	}
+	if err := e.w.Write(record); err != nil {
+		return err
+	}
+	if e.rows++; e.rows%exportFlushEvery == 0 {
+		e.w.Flush()
+		return e.w.Error()
+	}
+	return nil
+}
+
+// escapeCSVFormula prefixes text a spreadsheet would run as a formula with
+// a quote, so a name like =HYPERLINK(...) is shown rather than evaluated.
+// Numbers are written by Write unescaped.
+func escapeCSVFormula(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func (e *csvExportWriter) Close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvExportWriter) Discard() {}
+
+type jsonlExportWriter struct {
+	enc *json.Encoder
+}
+
+func newJSONLExportWriter(w io.Writer, _ []string) (exportWriter, error) {
+	return &jsonlExportWriter{enc: json.NewEncoder(w)}, nil
+}
+
+// Write encodes the record as the list endpoints would.
+func (e *jsonlExportWriter) Write(row exportRow) error {
+	return e.enc.Encode(row)
+}
+
+func (e *jsonlExportWriter) Close() error {
+	return nil
+}
+
+func (e *jsonlExportWriter) Discard() {}
+
+// xlsxExportWriter uses excelize's stream writer, which spills rows to a
+// temporary file rather than keeping the sheet in memory. The workbook is
+// only sent once complete, since the format cannot be streamed.
+type xlsxExportWriter struct {
+	out  io.Writer
+	file *excelize.File
+	sw   *excelize.StreamWriter
+	row  int
+}
+
+func newXLSXExportWriter(w io.Writer, columns []string) (exportWriter, error) {
+	f := excelize.NewFile()
+	sw, err := f.NewStreamWriter("Sheet1")
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	header := make([]any, len(columns))
+	for i, c := range columns {
+		header[i] = c
+	}
+	if err := sw.SetRow("A1", header); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return &xlsxExportWriter{out: w, file: f, sw: sw, row: 1}, nil
+}
+
+func (e *xlsxExportWriter) Write(row exportRow) error {
+	e.row++
+	cell, err := excelize.CoordinatesToCellName(1, e.row)
+	if err != nil {
+		return err
+	}
+	return e.sw.SetRow(cell, row.exportValues())
+}
+
+func (e *xlsxExportWriter) Close() error {
+	defer e.file.Close()
+	if err := e.sw.Flush(); err != nil {
+		return err
+	}
+	return e.file.Write(e.out)
+}
+
+// Discard removes the temporary files without sending the workbook.
+func (e *xlsxExportWriter) Discard() {
+	_ = e.file.Close()
+}
+
+// export streams the rows run produces to the client in the format chosen
+// by the format query parameter. Errors are answered with JSON until the
+// first bytes reach the client; after that the response is cut short.
+func (h *Handler) export(c *gin.Context, name string, columns []string, run func(emit func(exportRow) error) error) {
+	formatName := c.DefaultQuery("format", "csv")
+	format, ok := exportFormats[formatName]
+	if !ok {
+		h.log(c).Warn("Unknown export format:", formatName)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "format must be one of csv, jsonl, xlsx"})
+		return
+	}
+
+	filename := fmt.Sprintf("%s-%s.%s", name, time.Now().UTC().Format("20060102T150405Z"), formatName)
+	c.Header("Content-Type", format.contentType)
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+
+	w, err := format.newWriter(c.Writer, columns)
+	rows := 0
+	if err == nil {
+		err = run(func(row exportRow) error {
+			rows++
+			return w.Write(row)
+		})
+		if err == nil {
+			err = w.Close()
+		} else {
+			w.Discard()
+		}
+	}
+	if err != nil {
+		h.log(c).Error("Failed to export ", name, ":", err)
+		if !c.Writer.Written() {
+			c.Writer.Header().Del("Content-Disposition")
+			c.Writer.Header().Del("Content-Type")
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to export " + name})
+		} else {
+			c.Abort()
+		}
+		return
+	}
+
+	h.log(c).Info("Exported ", rows, " ", name, " as ", formatName)
+}
+
+// ExportStudents godoc
+// @Summary      Export students
+// @Description  Streams every student matching the filters as a CSV, JSON Lines or XLSX download. Filters and sort work as for GET /students; there is no paging. CSV cells a spreadsheet would run as a formula are prefixed with a quote.
+// @Tags         Students
+// @Produce      text/csv
+// @Produce      application/x-ndjson
+// @Produce      application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param        format           query     string  false  "File format"  Enums(csv, jsonl, xlsx)  default(csv)
+// @Param        sort             query     string  false  "Comma separated sort keys (id, name, age, email); prefix with - for descending"  example(-age,name)
+// @Param        min_age          query     int     false  "Minimum age (inclusive)"
+// @Param        max_age          query     int     false  "Maximum age (inclusive)"
+// @Param        email_domain     query     string  false  "Only emails at this domain"  example(example.com)
+// @Param        name_contains    query     string  false  "Case-insensitive substring of the name"
+// @Param        include_deleted  query     bool    false  "Also export deleted records (admins only)"
+// @Success      200              {file}    file
+// @Failure      400              {object}  ErrorResponse
+// @Failure      401              {object}  ErrorResponse
+// @Failure      403              {object}  ErrorResponse
+// @Failure      500              {object}  ErrorResponse
+// @Security     BearerAuth
+// @Router       /students/export [get]
+func (h *Handler) ExportStudents(c *gin.Context) {
+	filter, err := parseStudentFilter(c)
+	if err != nil {
+		h.log(c).Warn("Invalid student export filter:", err)
+		c.JSON(filterErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+	sort, err := parseSort(c, studentSortColumns)
+	if err != nil {
+		h.log(c).Warn("Invalid student export sort:", err)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	h.export(c, "students", studentExportColumns, func(emit func(exportRow) error) error {
+		return h.students.Export(c.Request.Context(), filter, sort, func(s Student) error { return emit(s) })
+	})
+}
+
+// ExportUsers godoc
+// @Summary      Export users
+// @Description  Streams every user matching the filters as a CSV, JSON Lines or XLSX download. Filters and sort work as for GET /users; there is no paging. CSV cells a spreadsheet would run as a formula are prefixed with a quote.
+// @Tags         Users
+// @Produce      text/csv
+// @Produce      application/x-ndjson
+// @Produce      application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param        format           query     string  false  "File format"  Enums(csv, jsonl, xlsx)  default(csv)
+// @Param        sort             query     string  false  "Comma separated sort keys (id, name, email, role); prefix with - for descending"  example(role,name)
+// @Param        role             query     string  false  "Only users with this role"  Enums(admin, teacher, student)
+// @Param        email_domain     query     string  false  "Only emails at this domain"  example(example.com)
+// @Param        name_contains    query     string  false  "Case-insensitive substring of the name"
+// @Param        include_deleted  query     bool    false  "Also export deleted records (admins only)"
+// @Success      200              {file}    file
+// @Failure      400              {object}  ErrorResponse
+// @Failure      401              {object}  ErrorResponse
+// @Failure      403              {object}  ErrorResponse
+// @Failure      500              {object}  ErrorResponse
+// @Security     BearerAuth
+// @Router       /users/export [get]
+func (h *Handler) ExportUsers(c *gin.Context) {
+	filter, err := parseUserFilter(c)
+	if err != nil {
+		h.log(c).Warn("Invalid user export filter:", err)
+		c.JSON(filterErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+	sort, err := parseSort(c, userSortColumns)
+	if err != nil {
+		h.log(c).Warn("Invalid user export sort:", err)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	h.export(c, "users", userExportColumns, func(emit func(exportRow) error) error {
+		return h.users.Export(c.Request.Context(), filter, sort, func(u User) error { return emit(u) })
+	})
+}
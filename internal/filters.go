@@ -0,0 +1,352 @@
+package internal
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// studentSortColumns whitelists the sort keys accepted by GET /students.
+var studentSortColumns = map[string]string{
+	"id":    "id",
+	"name":  "name",
+	"age":   "age",
+	"email": "email",
+}
+
+// userSortColumns whitelists the sort keys accepted by GET /users.
+var userSortColumns = map[string]string{
+	"id":    "id",
+	"name":  "name",
+	"email": "email",
+	"role":  "role",
+}
+
+// courseSortColumns whitelists the sort keys accepted by GET /courses.
+var courseSortColumns = map[string]string{
+	"id":      "id",
+	"code":    "code",
+	"title":   "title",
+	"credits": "credits",
+	"term":    "term",
+}
+
+// auditSortColumns whitelists the sort keys accepted by GET /audit. Ids grow
+// with time, so sorting by id orders events chronologically.
+var auditSortColumns = map[string]string{
+	"id": "id",
+}
+
+// errDeletedForbidden rejects include_deleted from callers who may not
+// delete the records themselves.
+var errDeletedForbidden = errors.New("only admins may include deleted records")
+
+// parseIncludeDeleted reads the include_deleted parameter. Seeing deleted
+// records requires perm, the permission to delete them.
+func parseIncludeDeleted(c *gin.Context, perm Permission) (bool, error) {
+	raw := c.Query("include_deleted")
+	if raw == "" {
+		return false, nil
+	}
+	include, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, errors.New("include_deleted must be true or false")
+	}
+	if include {
+		claims, ok := CurrentClaims(c)
+		if !ok || !claims.Role.Can(perm) {
+			return false, errDeletedForbidden
+		}
+	}
+	return include, nil
+}
+
+// filterErrorStatus is the response status for a rejected filter.
+func filterErrorStatus(err error) int {
+	if errors.Is(err, errDeletedForbidden) {
+		return http.StatusForbidden
+	}
+	return http.StatusBadRequest
+}
+
+// StudentFilter narrows the result of GET /students.
+type StudentFilter struct {
+	MinAge       *int
+	MaxAge       *int
+	EmailDomain  string
+	NameContains string
+	// IncludeDeleted lists deleted students alongside the others.
+	IncludeDeleted bool
+}
+
+func parseStudentFilter(c *gin.Context) (StudentFilter, error) {
+	var (
+		f   StudentFilter
+		err error
+	)
+	if f.MinAge, err = queryInt(c, "min_age"); err != nil {
+		return f, err
+	}
+	if f.MaxAge, err = queryInt(c, "max_age"); err != nil {
+		return f, err
+	}
+	if f.MinAge != nil && f.MaxAge != nil && *f.MinAge > *f.MaxAge {
+		return f, errors.New("min_age must not be greater than max_age")
+	}
+	f.EmailDomain = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(c.Query("email_domain")), "@"))
+	f.NameContains = strings.TrimSpace(c.Query("name_contains"))
+	f.IncludeDeleted, err = parseIncludeDeleted(c, PermStudentsDelete)
+	return f, err
+}
+
+func (f StudentFilter) apply(w *whereBuilder) {
+	if f.MinAge != nil {
+		w.add("age >= ?", *f.MinAge)
+	}
+	if f.MaxAge != nil {
+		w.add("age <= ?", *f.MaxAge)
+	}
+	if f.EmailDomain != "" {
+		w.add("lower(email) LIKE ?", "%@"+likeEscaper.Replace(f.EmailDomain))
+	}
+	if f.NameContains != "" {
+		w.add("name ILIKE ?", "%"+likeEscaper.Replace(f.NameContains)+"%")
+	}
+	if !f.IncludeDeleted {
+		w.add("deleted_at IS NULL")
+	}
+}
+
+// matches mirrors apply for in-memory storage.
+func (f StudentFilter) matches(s Student) bool {
+	if !f.IncludeDeleted && s.DeletedAt != nil {
+		return false
+	}
+	if f.MinAge != nil && s.Age < *f.MinAge {
+		return false
+	}
+	if f.MaxAge != nil && s.Age > *f.MaxAge {
+		return false
+	}
+	if f.EmailDomain != "" && !strings.HasSuffix(strings.ToLower(s.Email), "@"+f.EmailDomain) {
+		return false
+	}
+	if f.NameContains != "" && !strings.Contains(strings.ToLower(s.Name), strings.ToLower(f.NameContains)) {
+		return false
+	}
+	return true
+}
+
+// UserFilter narrows the result of GET /users.
+type UserFilter struct {
+	Role         Role
+	EmailDomain  string
+	NameContains string
+	// IncludeDeleted lists deleted users alongside the others.
+	IncludeDeleted bool
+}
+
+func parseUserFilter(c *gin.Context) (UserFilter, error) {
+	f := UserFilter{
+		Role:         Role(c.Query("role")),
+		EmailDomain:  strings.ToLower(strings.TrimPrefix(strings.TrimSpace(c.Query("email_domain")), "@")),
+		NameContains: strings.TrimSpace(c.Query("name_contains")),
+	}
+	if f.Role != "" && !f.Role.Valid() {
+		return f, errors.New("unknown role")
+	}
+	var err error
+	f.IncludeDeleted, err = parseIncludeDeleted(c, PermUsersDelete)
+	return f, err
+}
+
+func (f UserFilter) apply(w *whereBuilder) {
+	if f.Role != "" {
+		w.add("role = ?", f.Role)
+	}
+	if f.EmailDomain != "" {
+		w.add("lower(email) LIKE ?", "%@"+likeEscaper.Replace(f.EmailDomain))
+	}
+	if f.NameContains != "" {
+		w.add("name ILIKE ?", "%"+likeEscaper.Replace(f.NameContains)+"%")
+	}
+	if !f.IncludeDeleted {
+		w.add("deleted_at IS NULL")
+	}
+}
+
+// matches mirrors apply for in-memory storage.
+func (f UserFilter) matches(u User) bool {
+	if !f.IncludeDeleted && u.DeletedAt != nil {
+		return false
+	}
+	if f.Role != "" && u.Role != f.Role {
+		return false
+	}
+	if f.EmailDomain != "" && !strings.HasSuffix(strings.ToLower(u.Email), "@"+f.EmailDomain) {
+		return false
+	}
+	if f.NameContains != "" && !strings.Contains(strings.ToLower(u.Name), strings.ToLower(f.NameContains)) {
+		return false
+	}
+	return true
+}
+
+// sortValues returns the student's values for the sort columns, in order,
+// for building the next page cursor.
+func (s Student) sortValues(sort []SortField) []any {
+	values := make([]any, len(sort))
+	for i, f := range sort {
+		switch f.Column {
+		case "id":
+			values[i] = s.ID
+		case "name":
+			values[i] = s.Name
+		case "age":
+			values[i] = s.Age
+		case "email":
+			values[i] = s.Email
+		}
+	}
+	return values
+}
+
+// sortValues returns the user's values for the sort columns, in order, for
+// building the next page cursor.
+func (u User) sortValues(sort []SortField) []any {
+	values := make([]any, len(sort))
+	for i, f := range sort {
+		switch f.Column {
+		case "id":
+			values[i] = u.ID
+		case "name":
+			values[i] = u.Name
+		case "email":
+			values[i] = u.Email
+		case "role":
+			values[i] = u.Role
+		}
+	}
+	return values
+}
+
+// CourseFilter narrows the result of GET /courses.
+type CourseFilter struct {
+	Term          string
+	TitleContains string
+}
+
+func parseCourseFilter(c *gin.Context) CourseFilter {
+	return CourseFilter{
+		Term:          strings.TrimSpace(c.Query("term")),
+		TitleContains: strings.TrimSpace(c.Query("title_contains")),
+	}
+}
+
+func (f CourseFilter) apply(w *whereBuilder) {
+	if f.Term != "" {
+		w.add("term = ?", f.Term)
+	}
+	if f.TitleContains != "" {
+		w.add("title ILIKE ?", "%"+likeEscaper.Replace(f.TitleContains)+"%")
+	}
+}
+
+// matches mirrors apply for in-memory storage.
+func (f CourseFilter) matches(c Course) bool {
+	if f.Term != "" && c.Term != f.Term {
+		return false
+	}
+	if f.TitleContains != "" && !strings.Contains(strings.ToLower(c.Title), strings.ToLower(f.TitleContains)) {
+		return false
+	}
+	return true
+}
+
+// sortValues returns the course's values for the sort columns, in order, for
+// building the next page cursor.
+func (c Course) sortValues(sort []SortField) []any {
+	values := make([]any, len(sort))
+	for i, f := range sort {
+		switch f.Column {
+		case "id":
+			values[i] = c.ID
+		case "code":
+			values[i] = c.Code
+		case "title":
+			values[i] = c.Title
+		case "credits":
+			values[i] = c.Credits
+		case "term":
+			values[i] = c.Term
+		}
+	}
+	return values
+}
+
+// AuditFilter narrows the result of GET /audit.
+type AuditFilter struct {
+	EntityType string
+	EntityID   *int
+	ActorID    *int
+}
+
+func parseAuditFilter(c *gin.Context) (AuditFilter, error) {
+	var (
+		f   AuditFilter
+		err error
+	)
+	f.EntityType = strings.TrimSpace(c.Query("entity_type"))
+	if f.EntityType != "" && f.EntityType != AuditEntityStudent && f.EntityType != AuditEntityUser {
+		return f, errors.New("entity_type must be student or user")
+	}
+	if f.EntityID, err = queryInt(c, "entity_id"); err != nil {
+		return f, err
+	}
+	if f.EntityID != nil && f.EntityType == "" {
+		return f, errors.New("entity_id requires entity_type")
+	}
+	f.ActorID, err = queryInt(c, "actor_id")
+	return f, err
+}
+
+func (f AuditFilter) apply(w *whereBuilder) {
+	if f.EntityType != "" {
+		w.add("entity_type = ?", f.EntityType)
+	}
+	if f.EntityID != nil {
+		w.add("entity_id = ?", *f.EntityID)
+	}
+	if f.ActorID != nil {
+		w.add("actor_id = ?", *f.ActorID)
+	}
+}
+
+// matches mirrors apply for in-memory storage.
+func (f AuditFilter) matches(e AuditEvent) bool {
+	if f.EntityType != "" && e.EntityType != f.EntityType {
+		return false
+	}
+	if f.EntityID != nil && e.EntityID != *f.EntityID {
+		return false
+	}
+	if f.ActorID != nil && (e.ActorID == nil || *e.ActorID != *f.ActorID) {
+		return false
+	}
+	return true
+}
+
+// sortValues returns the event's values for the sort columns, in order, for
+// building the next page cursor.
+func (e AuditEvent) sortValues(sort []SortField) []any {
+	values := make([]any, len(sort))
+	for i, f := range sort {
+		if f.Column == "id" {
+			values[i] = e.ID
+		}
+	}
+	return values
+}
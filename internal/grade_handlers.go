@@ -0,0 +1,138 @@
+package internal
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CodeNotEnrolled marks a grade for a course the student is not enrolled in.
+const CodeNotEnrolled = "not_enrolled"
+
+// SetGradingScale replaces the scale transcripts are graded on. The scale
+// must already be valid.
+func (h *Handler) SetGradingScale(scale GradingScale) {
+	h.grading = scale
+}
+
+// RecordGrade godoc
+// @Summary      Record a grade
+// @Description  Records the student's score on one assessment of a course they are enrolled in. Posting the same assessment again replaces the earlier grade.
+// @Tags         Grades
+// @Accept       json
+// @Produce      json
+// @Param        id               path      int           true   "Student ID"
+// @Param        grade            body      GradeRequest  true   "Grade payload"
+// @Param        Idempotency-Key  header    string        false  "Unique key that makes retrying this request safe"
+// @Success      201              {object}  Grade
+// @Failure      400              {object}  ErrorResponse
+// @Failure      401              {object}  ErrorResponse
+// @Failure      403              {object}  ErrorResponse
+// @Failure      404              {object}  ErrorResponse
+// @Failure      422              {object}  ErrorResponse
+// @Failure      500              {object}  ErrorResponse
+// @Security     BearerAuth
+// @Router       /students/{id}/grades [post]
+func (h *Handler) RecordGrade(c *gin.Context) {
+	idStr := c.Param("id")
+	studentID, err := strconv.Atoi(idStr)
+	if err != nil || studentID <= 0 {
+		h.log(c).Error("Invalid student ID for grade:", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid ID"})
+		return
+	}
+
+	var req GradeRequest
+	if !h.bindJSON(c, &req) {
+		return
+	}
+	weight := req.Weight
+	if weight == 0 {
+		weight = 1
+	}
+
+	ctx := c.Request.Context()
+	if _, err := h.students.Get(ctx, studentID); errors.Is(err, ErrNotFound) {
+		h.log(c).Warn("Student not found for grade with ID:", studentID)
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Student not found"})
+		return
+	} else if err != nil {
+		h.log(c).Error("Failed to fetch student for grade:", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to record grade"})
+		return
+	}
+
+	grade, err := h.grades.Record(ctx, Grade{
+		StudentID:  studentID,
+		CourseID:   req.CourseID,
+		Assessment: req.Assessment,
+		Score:      *req.Score,
+		Weight:     weight,
+	})
+	if errors.Is(err, ErrNotEnrolled) {
+		h.log(c).Warn("Grade for a course the student is not enrolled in, student ID:", studentID)
+		c.JSON(http.StatusUnprocessableEntity, ErrorResponse{
+			Error:   "Student is not enrolled in this course",
+			Code:    CodeNotEnrolled,
+			Details: []FieldError{{Field: "course_id", Rule: "enrolled", Message: "course_id must be a course the student is enrolled in"}},
+		})
+		return
+	} else if h.respondConstraintError(c, err) {
+		return
+	} else if err != nil {
+		h.log(c).Error("Failed to record grade:", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to record grade"})
+		return
+	}
+
+	GradeSubmissionsTotal.Inc()
+	h.log(c).Info("Recorded grade successfully with ID:", grade.ID)
+	c.JSON(http.StatusCreated, grade)
+}
+
+// GetTranscript godoc
+// @Summary      Get a student's transcript
+// @Description  Lists the student's graded courses by term, oldest term first, with per-term and cumulative GPA. A course's score is the weighted mean of its assessments, converted to grade points on the configured grading scale; GPAs are weighted by course credits. Student accounts may only read their own transcript.
+// @Tags         Grades
+// @Produce      json
+// @Param        id   path      int  true  "Student ID"
+// @Success      200  {object}  Transcript
+// @Failure      400  {object}  ErrorResponse
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Security     BearerAuth
+// @Router       /students/{id}/transcript [get]
+func (h *Handler) GetTranscript(c *gin.Context) {
+	idStr := c.Param("id")
+	studentID, err := strconv.Atoi(idStr)
+	if err != nil || studentID <= 0 {
+		h.log(c).Error("Invalid student ID for transcript:", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := h.students.Get(ctx, studentID); errors.Is(err, ErrNotFound) {
+		h.log(c).Warn("Student not found for transcript with ID:", studentID)
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Student not found"})
+		return
+	} else if err != nil {
+		h.log(c).Error("Failed to fetch student for transcript:", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch transcript"})
+		return
+	}
+
+	graded, err := h.grades.ListByStudent(ctx, studentID)
+	if err != nil {
+		h.log(c).Error("Failed to fetch grades:", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch transcript"})
+		return
+	}
+
+	h.log(c).Info("Fetched transcript successfully for student ID:", studentID)
+	c.JSON(http.StatusOK, BuildTranscript(studentID, graded, h.grading))
+}
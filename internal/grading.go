@@ -0,0 +1,185 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GradeBand maps course scores of at least MinScore to a letter grade worth
+// Points towards the GPA.
+type GradeBand struct {
+	Letter   string  `yaml:"letter" json:"letter" example:"A"`
+	MinScore float64 `yaml:"min_score" json:"min_score" example:"93"`
+	Points   float64 `yaml:"points" json:"points" example:"4"`
+}
+
+// GradingScale lists bands from the highest MinScore down; a score earns the
+// first band it reaches.
+type GradingScale []GradeBand
+
+// DefaultGradingScale is the common US 4.0 scale with plus and minus grades.
+func DefaultGradingScale() GradingScale {
+	return GradingScale{
+		{Letter: "A", MinScore: 93, Points: 4.0},
+		{Letter: "A-", MinScore: 90, Points: 3.7},
+		{Letter: "B+", MinScore: 87, Points: 3.3},
+		{Letter: "B", MinScore: 83, Points: 3.0},
+		{Letter: "B-", MinScore: 80, Points: 2.7},
+		{Letter: "C+", MinScore: 77, Points: 2.3},
+		{Letter: "C", MinScore: 73, Points: 2.0},
+		{Letter: "C-", MinScore: 70, Points: 1.7},
+		{Letter: "D+", MinScore: 67, Points: 1.3},
+		{Letter: "D", MinScore: 63, Points: 1.0},
+		{Letter: "D-", MinScore: 60, Points: 0.7},
+		{Letter: "F", MinScore: 0, Points: 0},
+	}
+}
+
+// Validate checks that every score from 0 to 100 falls into exactly one band.
+func (s GradingScale) Validate() error {
+	if len(s) == 0 {
+		return errors.New("at least one band is required")
+	}
+	letters := map[string]bool{}
+	for i, b := range s {
+		switch {
+		case b.Letter == "":
+			return fmt.Errorf("band %d has no letter", i+1)
+		case letters[b.Letter]:
+			return fmt.Errorf("letter %q is used twice", b.Letter)
+		case b.MinScore < 0 || b.MinScore > 100:
+			return fmt.Errorf("%s min_score must be between 0 and 100", b.Letter)
+		case b.Points < 0:
+			return fmt.Errorf("%s points must not be negative", b.Letter)
+		case i > 0 && b.MinScore >= s[i-1].MinScore:
+			return fmt.Errorf("bands must be ordered by descending min_score (%s after %s)", b.Letter, s[i-1].Letter)
+		}
+		letters[b.Letter] = true
+	}
+	if s[len(s)-1].MinScore != 0 {
+		return errors.New("the lowest band must start at min_score 0")
+	}
+	return nil
+}
+
+// Band returns the band the score falls into.
+func (s GradingScale) Band(score float64) GradeBand {
+	for _, b := range s {
+		if score >= b.MinScore {
+			return b
+		}
+	}
+	return s[len(s)-1]
+}
+
+// BuildTranscript grades each course by the weighted mean of its assessment
+// scores and averages the grade points per term and overall, weighted by
+// credits. Courses worth no credits are listed but do not count towards the
+// GPA.
+func BuildTranscript(studentID int, graded []CourseGrades, scale GradingScale) Transcript {
+	t := Transcript{StudentID: studentID, Terms: []TermTranscript{}}
+
+	byTerm := map[string]*TermTranscript{}
+	var total gpaSum
+	sums := map[string]*gpaSum{}
+	for _, cg := range graded {
+		if len(cg.Grades) == 0 {
+			continue
+		}
+		var weighted, weights float64
+		for _, g := range cg.Grades {
+			weighted += g.Score * g.Weight
+			weights += g.Weight
+		}
+		score := round2(weighted / weights)
+		band := scale.Band(score)
+
+		term, ok := byTerm[cg.Course.Term]
+		if !ok {
+			term = &TermTranscript{Term: cg.Course.Term}
+			byTerm[cg.Course.Term] = term
+			sums[cg.Course.Term] = &gpaSum{}
+		}
+		term.Courses = append(term.Courses, TranscriptCourse{
+			CourseID:    cg.Course.ID,
+			Code:        cg.Course.Code,
+			Title:       cg.Course.Title,
+			Credits:     cg.Course.Credits,
+			Score:       score,
+			Letter:      band.Letter,
+			Points:      band.Points,
+			Assessments: cg.Grades,
+		})
+		sums[cg.Course.Term].add(band.Points, cg.Course.Credits)
+		total.add(band.Points, cg.Course.Credits)
+	}
+
+	for name, term := range byTerm {
+		term.Credits, term.GPA = sums[name].result()
+		t.Terms = append(t.Terms, *term)
+	}
+	sort.Slice(t.Terms, func(i, j int) bool { return termBefore(t.Terms[i].Term, t.Terms[j].Term) })
+	t.Credits, t.CumulativeGPA = total.result()
+	return t
+}
+
+// termSeasons orders the seasons within a year.
+var termSeasons = map[string]int{"winter": 0, "spring": 1, "summer": 2, "fall": 3, "autumn": 3}
+
+// parseTerm reads a term named like "2025-fall".
+func parseTerm(term string) (year, season int, ok bool) {
+	y, name, found := strings.Cut(strings.ToLower(strings.TrimSpace(term)), "-")
+	if !found {
+		return 0, 0, false
+	}
+	year, err := strconv.Atoi(y)
+	if err != nil {
+		return 0, 0, false
+	}
+	season, ok = termSeasons[name]
+	return year, season, ok
+}
+
+// termBefore orders terms chronologically, so 2025-spring comes before
+// 2025-fall. Terms not named like that follow the others by name.
+func termBefore(a, b string) bool {
+	ay, as, aok := parseTerm(a)
+	by, bs, bok := parseTerm(b)
+	switch {
+	case aok && bok && ay != by:
+		return ay < by
+	case aok && bok && as != bs:
+		return as < bs
+	case aok != bok:
+		return aok
+	}
+	return a < b
+}
+
+// gpaSum accumulates credit weighted grade points.
+type gpaSum struct {
+	points  float64
+	credits int
+}
+
+func (s *gpaSum) add(points float64, credits int) {
+	s.points += points * float64(credits)
+	s.credits += credits
+}
+
+// result returns the credits and the GPA, which is nil when nothing counted.
+func (s *gpaSum) result() (int, *float64) {
+	if s.credits == 0 {
+		return 0, nil
+	}
+	gpa := round2(s.points / float64(s.credits))
+	return s.credits, &gpa
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,177 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGradingScaleBandsAndValidation(t *testing.T) {
+	scale := DefaultGradingScale()
+	assert.NoError(t, scale.Validate())
+	assert.Equal(t, "A", scale.Band(100).Letter)
+	assert.Equal(t, "B+", scale.Band(87).Letter)
+	assert.Equal(t, "B", scale.Band(86.99).Letter)
+	assert.Equal(t, "F", scale.Band(0).Letter)
+
+	assert.ErrorContains(t, GradingScale{}.Validate(), "at least one band")
+	assert.ErrorContains(t, GradingScale{{Letter: "P", MinScore: 50, Points: 1}}.Validate(), "min_score 0")
+	assert.ErrorContains(t, GradingScale{
+		{Letter: "B", MinScore: 80, Points: 3},
+		{Letter: "A", MinScore: 90, Points: 4},
+		{Letter: "F", MinScore: 0},
+	}.Validate(), "descending")
+}
+
+func TestBuildTranscriptWeightsScoresAndCredits(t *testing.T) {
+	graded := []CourseGrades{
+		{
+			Course: Course{ID: 1, Code: "CS101", Credits: 4, Term: "2025-fall"},
+			Grades: []Grade{{Assessment: "final", Score: 95, Weight: 3}, {Assessment: "midterm", Score: 75, Weight: 1}},
+		},
+		{
+			Course: Course{ID: 2, Code: "MA101", Credits: 2, Term: "2025-fall"},
+			Grades: []Grade{{Assessment: "final", Score: 81, Weight: 1}},
+		},
+		{
+			Course: Course{ID: 3, Code: "SEM1", Credits: 0, Term: "2025-fall"},
+			Grades: []Grade{{Assessment: "attendance", Score: 10, Weight: 1}},
+		},
+		{
+			Course: Course{ID: 4, Code: "CS201", Credits: 3, Term: "2026-spring"},
+			Grades: []Grade{{Assessment: "final", Score: 72, Weight: 1}},
+		},
+	}
+
+	tr := BuildTranscript(7, graded, DefaultGradingScale())
+	assert.Equal(t, 7, tr.StudentID)
+	assert.Len(t, tr.Terms, 2)
+
+	fall := tr.Terms[0]
+	assert.Equal(t, "2025-fall", fall.Term)
+	assert.Equal(t, 90.0, fall.Courses[0].Score)
+	assert.Equal(t, "A-", fall.Courses[0].Letter)
+	assert.Equal(t, "B-", fall.Courses[1].Letter)
+	assert.Equal(t, "F", fall.Courses[2].Letter)
+	// (3.7*4 + 2.7*2) / 6; the zero credit seminar does not count.
+	assert.Equal(t, 6, fall.Credits)
+	assert.Equal(t, 3.37, *fall.GPA)
+
+	assert.Equal(t, 1.7, *tr.Terms[1].GPA)
+	// (3.7*4 + 2.7*2 + 1.7*3) / 9
+	assert.Equal(t, 9, tr.Credits)
+	assert.Equal(t, 2.81, *tr.CumulativeGPA)
+
+	empty := BuildTranscript(7, nil, DefaultGradingScale())
+	assert.Empty(t, empty.Terms)
+	assert.Nil(t, empty.CumulativeGPA)
+}
+
+func TestBuildTranscriptOrdersTermsChronologically(t *testing.T) {
+	var graded []CourseGrades
+	for i, term := range []string{"2025-fall", "Summer-School", "2026-winter", "2025-summer", "2025-spring", "2024-fall"} {
+		graded = append(graded, CourseGrades{
+			Course: Course{ID: i + 1, Credits: 3, Term: term},
+			Grades: []Grade{{Assessment: "final", Score: 80, Weight: 1}},
+		})
+	}
+
+	tr := BuildTranscript(7, graded, DefaultGradingScale())
+	terms := make([]string, len(tr.Terms))
+	for i, term := range tr.Terms {
+		terms[i] = term.Term
+	}
+	// Terms not named year-season come last.
+	assert.Equal(t, []string{"2024-fall", "2025-spring", "2025-summer", "2025-fall", "2026-winter", "Summer-School"}, terms)
+}
+
+func TestLoadConfigGradingScale(t *testing.T) {
+	path := writeConfigFile(t, `
+db:
+  user: app
+  name: studentdb
+auth:
+  jwt_secret: secret
+grading:
+  scale:
+    - {letter: P, min_score: 50, points: 1}
+    - {letter: F, min_score: 0, points: 0}
+`)
+	t.Setenv("CONFIG_FILE", path)
+
+	cfg, _, err := LoadConfig(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, GradingScale{{Letter: "P", MinScore: 50, Points: 1}, {Letter: "F", MinScore: 0, Points: 0}}, cfg.Grading.Scale)
+
+	cfg.Grading.Scale = GradingScale{{Letter: "P", MinScore: 50, Points: 1}}
+	assert.ErrorContains(t, cfg.Validate(), "grading.scale")
+}
+
+func TestRecordGradeAndTranscript(t *testing.T) {
+	h, repos := newMemoryHandler(t)
+	ctx := context.Background()
+	student, _ := repos.Students.Create(ctx, Student{Name: "Alice", Age: 20, Email: "alice@example.com"})
+	course, _ := repos.Courses.Create(ctx, Course{Code: "CS101", Title: "Intro", Credits: 4, Capacity: 10, Term: "2025-fall"})
+	_, _ = repos.Courses.Create(ctx, Course{Code: "MA101", Title: "Calculus", Credits: 3, Capacity: 10, Term: "2025-fall"})
+	_, _ = repos.Courses.Enroll(ctx, student.ID, course.ID)
+
+	r := testRouter(t, h, testAdmin, RouteMiddleware{})
+
+	var grade Grade
+	code := doJSON(r, http.MethodPost, "/students/1/grades", `{"course_id": 1, "assessment": "final", "score": 0}`, &grade)
+	assert.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, 1.0, grade.Weight)
+
+	// Posting the same assessment again replaces the grade.
+	code = doJSON(r, http.MethodPost, "/students/1/grades", `{"course_id": 1, "assessment": "final", "score": 91}`, &grade)
+	assert.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, 1, grade.ID)
+
+	var resp ErrorResponse
+	code = doJSON(r, http.MethodPost, "/students/1/grades", `{"course_id": 2, "assessment": "final", "score": 80}`, &resp)
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	assert.Equal(t, CodeNotEnrolled, resp.Code)
+
+	code = doJSON(r, http.MethodPost, "/students/1/grades", `{"course_id": 1, "assessment": "quiz", "weight": 0}`, &resp)
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	assert.Equal(t, "score", resp.Details[0].Field)
+
+	assert.Equal(t, http.StatusNotFound, doJSON(r, http.MethodPost, "/students/9/grades", `{"course_id": 1, "assessment": "final", "score": 50}`, nil))
+
+	var tr Transcript
+	code = doJSON(r, http.MethodGet, "/students/1/transcript", "", &tr)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "A-", tr.Terms[0].Courses[0].Letter)
+	assert.Equal(t, 3.7, *tr.CumulativeGPA)
+
+	assert.Equal(t, http.StatusNotFound, doJSON(r, http.MethodGet, "/students/9/transcript", "", nil))
+}
+
+func TestPostgresListByStudentGroupsCourses(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+	repo := NewPostgresRepositories(&Db{db: mockDB}).Grades
+
+	now := time.Now()
+	cols := []string{"id", "code", "title", "credits", "term", "id", "assessment", "score", "weight", "graded_at"}
+	mock.ExpectQuery("FROM grades g").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows(cols).
+			AddRow(1, "CS101", "Intro", 4, "2025-fall", 10, "final", 90.0, 3.0, now).
+			AddRow(1, "CS101", "Intro", 4, "2025-fall", 11, "midterm", 70.0, 1.0, now).
+			AddRow(2, "MA101", "Calculus", 3, "2025-fall", 12, "final", 85.0, 1.0, now))
+
+	graded, err := repo.ListByStudent(context.Background(), 3)
+	assert.NoError(t, err)
+	assert.Len(t, graded, 2)
+	assert.Len(t, graded[0].Grades, 2)
+	assert.Equal(t, 1, graded[0].Grades[1].CourseID)
+	assert.Equal(t, 3, graded[1].Grades[0].StudentID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -1,74 +1,126 @@
 package internal
 
 import (
-	"database/sql"
+	"context"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync/atomic"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
 
 type Handler struct {
-	db     *sql.DB
-	logger *logrus.Logger
+	students   StudentRepository
+	users      UserRepository
+	sessions   SessionRepository
+	courses    CourseRepository
+	grades     GradeRepository
+	attendance AttendanceRepository
+	audit      AuditRepository
+	inTx       func(ctx context.Context, fn func(tx Repositories) error) error
+	logger     *logrus.Logger
+	tokens     *TokenManager
+	grading    GradingScale
+	// requireIfMatch rejects student writes that carry no If-Match.
+	requireIfMatch bool
+
+	// draining is set once shutdown starts so health checks fail while
+	// in-flight requests finish.
+	draining atomic.Bool
+	checks   []HealthCheck
 }
 
-func NewHandler(d *Db, logger *logrus.Logger) *Handler {
+func NewHandler(repos Repositories, logger *logrus.Logger, tokens *TokenManager) *Handler {
 	return &Handler{
-		db:     d.db,
-		logger: logger,
+		students:   repos.Students,
+		users:      repos.Users,
+		sessions:   repos.Sessions,
+		courses:    repos.Courses,
+		grades:     repos.Grades,
+		attendance: repos.Attendance,
+		audit:      repos.Audit,
+		inTx:       repos.InTx,
+		logger:     logger,
+		tokens:     tokens,
+		grading:    DefaultGradingScale(),
 	}
 }
 
 // Healthcheck godoc
 // @Summary      Health check
-// @Description  Returns the API health status.
+// @Description  Returns the API health status without checking dependencies. Prefer /health/live and /health/ready for probes.
 // @Tags         Health
 // @ID           healthcheck
 // @Produce      json
 // @Success      200  {object}  HealthResponse
+// @Failure      503  {object}  HealthResponse
 // @Router       /health [get]
 func (h *Handler) Healthcheck(c *gin.Context) {
-	h.logger.Info("Healthcheck endpoint called")
+	h.log(c).Info("Healthcheck endpoint called")
+	if h.draining.Load() {
+		c.JSON(http.StatusServiceUnavailable, HealthResponse{Message: "API is shutting down"})
+		return
+	}
 	c.JSON(http.StatusOK, HealthResponse{Message: "API is healthy"})
 }
 
+// StartDraining makes Healthcheck report the API as unavailable. It is called
+// at the start of a graceful shutdown.
+func (h *Handler) StartDraining() {
+	h.draining.Store(true)
+}
+
 // GetStudents godoc
 // @Summary      List students
-// @Description  Returns all students
+// @Description  Returns a page of students. Pages are keyset based: pass the returned next_cursor to fetch the following page with the same sort and filters.
 // @Tags         Students
 // @Produce      json
-// @Success      200  {array}   Student
-// @Failure      500  {object}  ErrorResponse
+// @Param        limit            query     int     false  "Page size (1-200)"  default(50)
+// @Param        cursor           query     string  false  "Cursor from a previous page's next_cursor"
+// @Param        sort             query     string  false  "Comma separated sort keys (id, name, age, email); prefix with - for descending"  example(name,-age)
+// @Param        min_age          query     int     false  "Minimum age (inclusive)"
+// @Param        max_age          query     int     false  "Maximum age (inclusive)"
+// @Param        email_domain     query     string  false  "Only emails at this domain"  example(example.com)
+// @Param        name_contains    query     string  false  "Case-insensitive substring of the name"
+// @Param        include_deleted  query     bool    false  "Also list deleted students (admins only)"
+// @Success      200              {object}  StudentListResponse
+// @Failure      400              {object}  ErrorResponse
+// @Failure      401              {object}  ErrorResponse
+// @Failure      403              {object}  ErrorResponse
+// @Failure      500              {object}  ErrorResponse
+// @Security     BearerAuth
 // @Router       /students [get]
 func (h *Handler) GetStudents(c *gin.Context) {
-	rows, err := h.db.Query("SELECT id, name, age, email FROM students")
+	opts, err := parseListOptions(c, studentSortColumns)
 	if err != nil {
-		h.logger.Error("Error querying students:", err)
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch students"})
+		h.log(c).Warn("Invalid student list options:", err)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 		return
 	}
-	defer rows.Close()
-
-	students := make([]Student, 0)
-	for rows.Next() {
-		var s Student
-		if err := rows.Scan(&s.ID, &s.Name, &s.Age, &s.Email); err != nil {
-			h.logger.Error("Error scanning student row:", err)
-			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to parse student record"})
-			return
-		}
-		students = append(students, s)
+	filter, err := parseStudentFilter(c)
+	if err != nil {
+		h.log(c).Warn("Invalid student filter:", err)
+		c.JSON(filterErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
 	}
-	if err := rows.Err(); err != nil {
-		h.logger.Error("Row iteration error:", err)
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Database error while fetching students"})
+
+	page, err := h.students.List(c.Request.Context(), filter, opts)
+	if err != nil {
+		h.log(c).Error("Error listing students:", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch students"})
 		return
 	}
 
-	h.logger.Info("Fetched students successfully")
-	c.JSON(http.StatusOK, students)
+	resp := StudentListResponse{Data: page.Items, Total: page.Total}
+	if page.HasMore {
+		resp.NextCursor = opts.NextCursor(page.Items[len(page.Items)-1].sortValues(opts.Sort))
+	}
+
+	h.log(c).Info("Fetched students successfully")
+	c.JSON(http.StatusOK, resp)
 }
 
 // CreateStudent godoc
@@ -77,196 +129,450 @@ func (h *Handler) GetStudents(c *gin.Context) {
 // @Tags         Students
 // @Accept       json
 // @Produce      json
-// @Param        student  body      StudentCreateRequest  true  "Student payload"
-// @Success      201      {object}  Student
-// @Failure      400      {object}  ErrorResponse
-// @Failure      500      {object}  ErrorResponse
+// @Param        student          body      StudentCreateRequest  true   "Student payload"
+// @Param        Idempotency-Key  header    string                false  "Unique key that makes retrying this request safe"
+// @Success      201              {object}  Student
+// @Failure      400              {object}  ErrorResponse
+// @Failure      401              {object}  ErrorResponse
+// @Failure      403              {object}  ErrorResponse
+// @Failure      409              {object}  ErrorResponse
+// @Failure      422              {object}  ErrorResponse
+// @Failure      500              {object}  ErrorResponse
+// @Security     BearerAuth
 // @Router       /students [post]
 func (h *Handler) CreateStudent(c *gin.Context) {
 	var req StudentCreateRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		h.logger.Error("Invalid request payload:", err)
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request payload"})
+	if !h.bindJSON(c, &req) {
 		return
 	}
 
 	var student Student
-	err := h.db.QueryRow(
-		"INSERT INTO students (name, age, email) VALUES ($1, $2, $3) RETURNING id",
-		req.Name, req.Age, req.Email,
-	).Scan(&student.ID)
-	if err != nil {
-		h.logger.Error("Failed to create student:", err)
+	err := h.audited(c, func(tx Repositories) (auditedChange, error) {
+		var err error
+		student, err = tx.Students.Create(c.Request.Context(), Student{Name: req.Name, Age: req.Age, Email: req.Email})
+		return auditedChange{action: AuditCreate, entityType: AuditEntityStudent, entityID: student.ID, after: student}, err
+	})
+	if h.respondConstraintError(c, err) {
+		return
+	} else if err != nil {
+		h.log(c).Error("Failed to create student:", err)
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to create student"})
 		return
 	}
-	student.Name = req.Name
-	student.Age = req.Age
-	student.Email = req.Email
 
-	h.logger.Info("Created student successfully with ID:", student.ID)
+	h.log(c).Info("Created student successfully with ID:", student.ID)
+	c.Header("ETag", studentETag(student))
 	c.JSON(http.StatusCreated, student)
 }
 
 // GetStudentByID godoc
 // @Summary      Get a student by ID
-// @Description  Retrieves a student by its ID
+// @Description  Retrieves a student by its ID. Student accounts may only read their own record. The ETag header carries the student's version: send it as If-None-Match to get 304 while the student is unchanged, or as If-Match when writing.
 // @Tags         Students
 // @Produce      json
-// @Param        id   path      int  true  "Student ID"
-// @Success      200  {object}  Student
-// @Failure      400  {object}  ErrorResponse
-// @Failure      404  {object}  ErrorResponse
-// @Failure      500  {object}  ErrorResponse
+// @Param        id               path      int     true   "Student ID"
+// @Param        include_deleted  query     bool    false  "Also find a deleted student (admins only)"
+// @Param        If-None-Match    header    string  false  "ETag of a cached copy"
+// @Success      200              {object}  Student
+// @Header       200              {string}  ETag    "Version of the student"
+// @Success      304              "The cached copy is current"
+// @Failure      400              {object}  ErrorResponse
+// @Failure      401              {object}  ErrorResponse
+// @Failure      403              {object}  ErrorResponse
+// @Failure      404              {object}  ErrorResponse
+// @Failure      500              {object}  ErrorResponse
+// @Security     BearerAuth
 // @Router       /students/{id} [get]
 func (h *Handler) GetStudentByID(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil || id <= 0 {
-		h.logger.Error("Invalid student ID:", idStr)
+		h.log(c).Error("Invalid student ID:", idStr)
 		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid ID"})
 		return
 	}
+	includeDeleted, err := parseIncludeDeleted(c, PermStudentsDelete)
+	if err != nil {
+		h.log(c).Warn("Invalid student lookup:", err)
+		c.JSON(filterErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
 
-	var s Student
-	err = h.db.QueryRow("SELECT id, name, age, email FROM students WHERE id = $1", id).
-		Scan(&s.ID, &s.Name, &s.Age, &s.Email)
-	if err == sql.ErrNoRows {
-		h.logger.Warn("Student not found with ID:", id)
+	get := h.students.Get
+	if includeDeleted {
+		get = h.students.GetIncludingDeleted
+	}
+	s, err := get(c.Request.Context(), id)
+	if errors.Is(err, ErrNotFound) {
+		h.log(c).Warn("Student not found with ID:", id)
 		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Student not found"})
 		return
 	} else if err != nil {
-		h.logger.Error("Failed to fetch student:", err)
+		h.log(c).Error("Failed to fetch student:", err)
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch student"})
 		return
 	}
 
-	h.logger.Info("Fetched student successfully with ID:", id)
+	etag := studentETag(s)
+	c.Header("ETag", etag)
+	if etagMatches(c.GetHeader("If-None-Match"), etag, true) {
+		h.log(c).Info("Student not modified with ID:", id)
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	h.log(c).Info("Fetched student successfully with ID:", id)
 	c.JSON(http.StatusOK, s)
 }
 
 // UpdateStudent godoc
 // @Summary      Update a student
-// @Description  Updates an existing student by ID
+// @Description  Updates an existing student by ID. With If-Match the update only applies while the student still has that ETag and fails with 412 otherwise; the server may be configured to require it (428).
 // @Tags         Students
 // @Accept       json
 // @Produce      json
-// @Param        id       path      int                      true  "Student ID"
-// @Param        student  body      StudentUpdateRequest     true  "Student payload"
-// @Success      200      {object}  Student
-// @Failure      400      {object}  ErrorResponse
-// @Failure      404      {object}  ErrorResponse
-// @Failure      500      {object}  ErrorResponse
+// @Param        id        path      int                   true   "Student ID"
+// @Param        student   body      StudentUpdateRequest  true   "Student payload"
+// @Param        If-Match  header    string                false  "ETag the student must still have"
+// @Success      200       {object}  Student
+// @Header       200       {string}  ETag                  "Version of the updated student"
+// @Failure      400       {object}  ErrorResponse
+// @Failure      401       {object}  ErrorResponse
+// @Failure      403       {object}  ErrorResponse
+// @Failure      404       {object}  ErrorResponse
+// @Failure      409       {object}  ErrorResponse
+// @Failure      412       {object}  ErrorResponse
+// @Failure      422       {object}  ErrorResponse
+// @Failure      428       {object}  ErrorResponse
+// @Failure      500       {object}  ErrorResponse
+// @Security     BearerAuth
 // @Router       /students/{id} [put]
 func (h *Handler) UpdateStudent(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil || id <= 0 {
-		h.logger.Error("Invalid student ID for update:", idStr)
+		h.log(c).Error("Invalid student ID for update:", idStr)
 		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid ID"})
 		return
 	}
 
+	if !h.preconditionPresent(c) {
+		return
+	}
 	var payload StudentUpdateRequest
-	if err := c.ShouldBindJSON(&payload); err != nil {
-		h.logger.Error("Invalid update payload:", err)
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request payload"})
+	if !h.bindJSON(c, &payload) {
 		return
 	}
 
 	// Update and return the updated row
 	var updated Student
-	err = h.db.QueryRow(
-		"UPDATE students SET name = $1, age = $2, email = $3 WHERE id = $4 RETURNING id, name, age, email",
-		payload.Name, payload.Age, payload.Email, id,
-	).Scan(&updated.ID, &updated.Name, &updated.Age, &updated.Email)
-	if err == sql.ErrNoRows {
-		h.logger.Warn("Student not found for update with ID:", id)
+	err = h.audited(c, func(tx Repositories) (auditedChange, error) {
+		ctx := c.Request.Context()
+		before, err := tx.Students.Get(ctx, id)
+		if err != nil {
+			return auditedChange{}, err
+		}
+		version, err := ifMatchVersion(c, before)
+		if err != nil {
+			return auditedChange{}, err
+		}
+		updated, err = tx.Students.Update(ctx, Student{ID: id, Name: payload.Name, Age: payload.Age, Email: payload.Email, Version: version})
+		return auditedChange{action: AuditUpdate, entityType: AuditEntityStudent, entityID: id, before: before, after: updated}, err
+	})
+	if errors.Is(err, ErrNotFound) {
+		h.log(c).Warn("Student not found for update with ID:", id)
 		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Student not found"})
 		return
+	} else if errors.Is(err, ErrVersionMismatch) {
+		h.log(c).Warn("Stale If-Match on update of student ID:", id)
+		c.JSON(http.StatusPreconditionFailed, ErrorResponse{Error: "Student has changed since it was read"})
+		return
+	} else if h.respondConstraintError(c, err) {
+		return
 	} else if err != nil {
-		h.logger.Error("Failed to update student:", err)
+		h.log(c).Error("Failed to update student:", err)
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update student"})
 		return
 	}
 
-	h.logger.Info("Updated student successfully with ID:", id)
+	h.log(c).Info("Updated student successfully with ID:", id)
+	c.Header("ETag", studentETag(updated))
+	c.JSON(http.StatusOK, updated)
+}
+
+// PatchStudent godoc
+// @Summary      Partially update a student
+// @Description  Applies a JSON Merge Patch (application/merge-patch+json, RFC 7396) or a JSON Patch (application/json-patch+json, RFC 6902) to a student. Only the fields that change are written; the patched record must still be valid. If-Match works as for PUT; without it, a student changed by another request while the patch was applied gets 409.
+// @Tags         Students
+// @Accept       application/merge-patch+json
+// @Accept       application/json-patch+json
+// @Produce      json
+// @Param        id        path      int                   true   "Student ID"
+// @Param        patch     body      StudentUpdateRequest  true   "Merge patch with any subset of the fields, or a JSON Patch operation array"
+// @Param        If-Match  header    string                false  "ETag the student must still have"
+// @Success      200       {object}  Student
+// @Header       200       {string}  ETag                  "Version of the patched student"
+// @Failure      400       {object}  ErrorResponse
+// @Failure      401       {object}  ErrorResponse
+// @Failure      403       {object}  ErrorResponse
+// @Failure      404       {object}  ErrorResponse
+// @Failure      409       {object}  ErrorResponse
+// @Failure      412       {object}  ErrorResponse
+// @Failure      415       {object}  ErrorResponse
+// @Failure      422       {object}  ErrorResponse
+// @Failure      428       {object}  ErrorResponse
+// @Failure      500       {object}  ErrorResponse
+// @Security     BearerAuth
+// @Router       /students/{id} [patch]
+func (h *Handler) PatchStudent(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id <= 0 {
+		h.log(c).Error("Invalid student ID for patch:", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid ID"})
+		return
+	}
+
+	if !h.preconditionPresent(c) {
+		return
+	}
+	body, err := c.GetRawData()
+	if err != nil {
+		h.log(c).Error("Failed to read patch body:", err)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request payload"})
+		return
+	}
+
+	// The patch is applied to the student as read in the transaction, and
+	// the write only succeeds if it is still that version. Without If-Match
+	// a concurrent change is reported as a conflict instead of being lost.
+	var (
+		updated  Student
+		patchErr error
+	)
+	err = h.audited(c, func(tx Repositories) (auditedChange, error) {
+		ctx := c.Request.Context()
+		current, err := tx.Students.Get(ctx, id)
+		if err != nil {
+			return auditedChange{}, err
+		}
+		if _, err := ifMatchVersion(c, current); err != nil {
+			return auditedChange{}, err
+		}
+		result, err := applyStudentPatch(c.ContentType(), current, body)
+		if err != nil {
+			patchErr = err
+			return auditedChange{}, err
+		}
+		patch := result.diff(current)
+		patch.Version = current.Version
+		updated, err = tx.Students.Patch(ctx, id, patch)
+		return auditedChange{action: AuditUpdate, entityType: AuditEntityStudent, entityID: id, before: current, after: updated}, err
+	})
+	var invalid invalidPatchResult
+	switch {
+	case errors.Is(patchErr, errUnsupportedPatch):
+		c.JSON(http.StatusUnsupportedMediaType, ErrorResponse{Error: "Content-Type must be application/merge-patch+json or application/json-patch+json"})
+		return
+	case errors.Is(patchErr, errPatchConflict):
+		h.log(c).Warn("Patch test operation failed for student ID:", id)
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Patch test operation failed"})
+		return
+	case errors.As(patchErr, &invalid):
+		h.log(c).Warn("Patch produced an invalid student:", invalid.err)
+		c.JSON(bindError(invalid.err))
+		return
+	case patchErr != nil:
+		h.log(c).Error("Invalid patch document:", patchErr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid patch document"})
+		return
+	case errors.Is(err, ErrNotFound):
+		h.log(c).Warn("Student not found for patch with ID:", id)
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Student not found"})
+		return
+	case errors.Is(err, ErrVersionMismatch) && c.GetHeader("If-Match") != "":
+		h.log(c).Warn("Stale If-Match on patch of student ID:", id)
+		c.JSON(http.StatusPreconditionFailed, ErrorResponse{Error: "Student has changed since it was read"})
+		return
+	case errors.Is(err, ErrVersionMismatch):
+		h.log(c).Warn("Concurrent change to student ID:", id, " while patching")
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Student was changed by another request; retry the patch"})
+		return
+	case h.respondConstraintError(c, err):
+		return
+	case err != nil:
+		h.log(c).Error("Failed to patch student:", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update student"})
+		return
+	}
+
+	h.log(c).Info("Patched student successfully with ID:", id)
+	c.Header("ETag", studentETag(updated))
 	c.JSON(http.StatusOK, updated)
 }
 
 // DeleteStudent godoc
 // @Summary      Delete a student
-// @Description  Deletes a student by ID
+// @Description  Marks a student as deleted. Deleted students disappear from every endpoint but can be restored until they are purged after the retention period. If-Match works as for PUT.
 // @Tags         Students
-// @Param        id  path  int  true  "Student ID"
+// @Param        id        path      int     true   "Student ID"
+// @Param        If-Match  header    string  false  "ETag the student must still have"
 // @Success      204
-// @Failure      400  {object}  ErrorResponse
-// @Failure      404  {object}  ErrorResponse
-// @Failure      500  {object}  ErrorResponse
+// @Failure      400       {object}  ErrorResponse
+// @Failure      401       {object}  ErrorResponse
+// @Failure      403       {object}  ErrorResponse
+// @Failure      404       {object}  ErrorResponse
+// @Failure      412       {object}  ErrorResponse
+// @Failure      428       {object}  ErrorResponse
+// @Failure      500       {object}  ErrorResponse
+// @Security     BearerAuth
 // @Router       /students/{id} [delete]
 func (h *Handler) DeleteStudent(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil || id <= 0 {
-		h.logger.Error("Invalid student ID for deletion:", idStr)
+		h.log(c).Error("Invalid student ID for deletion:", idStr)
 		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid ID"})
 		return
 	}
+	if !h.preconditionPresent(c) {
+		return
+	}
 
-	res, err := h.db.Exec("DELETE FROM students WHERE id = $1", id)
-	if err != nil {
-		h.logger.Error("Failed to delete student:", err)
+	err = h.audited(c, func(tx Repositories) (auditedChange, error) {
+		ctx := c.Request.Context()
+		before, err := tx.Students.Get(ctx, id)
+		if err != nil {
+			return auditedChange{}, err
+		}
+		version, err := ifMatchVersion(c, before)
+		if err != nil {
+			return auditedChange{}, err
+		}
+		if err := tx.Students.Delete(ctx, id, version); err != nil {
+			return auditedChange{}, err
+		}
+		after, err := tx.Students.GetIncludingDeleted(ctx, id)
+		return auditedChange{action: AuditDelete, entityType: AuditEntityStudent, entityID: id, before: before, after: after}, err
+	})
+	if errors.Is(err, ErrNotFound) {
+		h.log(c).Warn("Student not found for deletion with ID:", id)
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Student not found"})
+		return
+	} else if errors.Is(err, ErrVersionMismatch) {
+		h.log(c).Warn("Stale If-Match on deletion of student ID:", id)
+		c.JSON(http.StatusPreconditionFailed, ErrorResponse{Error: "Student has changed since it was read"})
+		return
+	} else if err != nil {
+		h.log(c).Error("Failed to delete student:", err)
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to delete student"})
 		return
 	}
-	affected, _ := res.RowsAffected()
-	if affected == 0 {
-		h.logger.Warn("Student not found for deletion with ID:", id)
+
+	h.log(c).Info("Deleted student successfully with ID:", id)
+	c.Status(http.StatusNoContent)
+}
+
+// RestoreStudent godoc
+// @Summary      Restore a deleted student
+// @Description  Brings back a deleted student that has not been purged yet. Restoring fails with 409 when another student has taken the email since; restoring a student that is not deleted changes nothing.
+// @Tags         Students
+// @Produce      json
+// @Param        id               path      int     true   "Student ID"
+// @Param        Idempotency-Key  header    string  false  "Unique key that makes retrying this request safe"
+// @Success      200              {object}  Student
+// @Failure      400              {object}  ErrorResponse
+// @Failure      401              {object}  ErrorResponse
+// @Failure      403              {object}  ErrorResponse
+// @Failure      404              {object}  ErrorResponse
+// @Failure      409              {object}  ErrorResponse
+// @Failure      500              {object}  ErrorResponse
+// @Security     BearerAuth
+// @Router       /students/{id}/restore [post]
+func (h *Handler) RestoreStudent(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id <= 0 {
+		h.log(c).Error("Invalid student ID for restore:", idStr)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid ID"})
+		return
+	}
+
+	var s Student
+	err = h.audited(c, func(tx Repositories) (auditedChange, error) {
+		ctx := c.Request.Context()
+		before, err := tx.Students.GetIncludingDeleted(ctx, id)
+		if err != nil {
+			return auditedChange{}, err
+		}
+		s, err = tx.Students.Restore(ctx, id)
+		return auditedChange{action: AuditRestore, entityType: AuditEntityStudent, entityID: id, before: before, after: s}, err
+	})
+	if errors.Is(err, ErrNotFound) {
+		h.log(c).Warn("Student not found for restore with ID:", id)
 		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Student not found"})
 		return
+	} else if h.respondConstraintError(c, err) {
+		return
+	} else if err != nil {
+		h.log(c).Error("Failed to restore student:", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to restore student"})
+		return
 	}
 
-	h.logger.Info("Deleted student successfully with ID:", id)
-	c.Status(http.StatusNoContent)
+	h.log(c).Info("Restored student successfully with ID:", id)
+	c.Header("ETag", studentETag(s))
+	c.JSON(http.StatusOK, s)
 }
 
 // GetUsers godoc
 // @Summary      List users
-// @Description  Returns all users
+// @Description  Returns a page of users. Pages are keyset based: pass the returned next_cursor to fetch the following page with the same sort and filters.
 // @Tags         Users
 // @Produce      json
-// @Success      200  {array}   User
-// @Failure      500  {object}  ErrorResponse
+// @Param        limit            query     int     false  "Page size (1-200)"  default(50)
+// @Param        cursor           query     string  false  "Cursor from a previous page's next_cursor"
+// @Param        sort             query     string  false  "Comma separated sort keys (id, name, email, role); prefix with - for descending"  example(-name)
+// @Param        role             query     string  false  "Only users with this role"  Enums(admin, teacher, student)
+// @Param        email_domain     query     string  false  "Only emails at this domain"  example(example.com)
+// @Param        name_contains    query     string  false  "Case-insensitive substring of the name"
+// @Param        include_deleted  query     bool    false  "Also list deleted users (admins only)"
+// @Success      200              {object}  UserListResponse
+// @Failure      400              {object}  ErrorResponse
+// @Failure      401              {object}  ErrorResponse
+// @Failure      403              {object}  ErrorResponse
+// @Failure      500              {object}  ErrorResponse
+// @Security     BearerAuth
 // @Router       /users [get]
-// GetUsers returns the list of users from the database.
+// GetUsers returns a page of users from the database.
 func (h *Handler) GetUsers(c *gin.Context) {
-	query := "SELECT id, name, email FROM users"
-	rows, err := h.db.Query(query)
+	opts, err := parseListOptions(c, userSortColumns)
 	if err != nil {
-		h.logger.Error("Error querying users:", err)
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch users"})
+		h.log(c).Warn("Invalid user list options:", err)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 		return
 	}
-
-	defer rows.Close()
-	users := make([]User, 0)
-	for rows.Next() {
-		var u User
-		if err := rows.Scan(&u.ID, &u.Name, &u.Email); err != nil {
-			h.logger.Error("Error scanning user row:", err)
-			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to parse user record"})
-			return
-		}
-		users = append(users, u)
+	filter, err := parseUserFilter(c)
+	if err != nil {
+		h.log(c).Warn("Invalid user filter:", err)
+		c.JSON(filterErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
 	}
-	if err := rows.Err(); err != nil {
-		h.logger.Error("Row iteration error:", err)
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Database error while fetching users"})
+
+	page, err := h.users.List(c.Request.Context(), filter, opts)
+	if err != nil {
+		h.log(c).Error("Error listing users:", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch users"})
 		return
 	}
 
-	h.logger.Info("Fetched users successfully")
-	c.JSON(http.StatusOK, users)
+	resp := UserListResponse{Data: page.Items, Total: page.Total}
+	if page.HasMore {
+		resp.NextCursor = opts.NextCursor(page.Items[len(page.Items)-1].sortValues(opts.Sort))
+	}
+
+	h.log(c).Info("Fetched users successfully")
+	c.JSON(http.StatusOK, resp)
 }
 
 // CreateUser godoc
@@ -275,33 +581,58 @@ func (h *Handler) GetUsers(c *gin.Context) {
 // @Tags         Users
 // @Accept       json
 // @Produce      json
-// @Param        user  body      UserCreateRequest  true  "User payload"
-// @Success      201   {object}  User
-// @Failure      400   {object}  ErrorResponse
-// @Failure      500   {object}  ErrorResponse
+// @Param        user             body      UserCreateRequest  true   "User payload"
+// @Param        Idempotency-Key  header    string             false  "Unique key that makes retrying this request safe"
+// @Success      201              {object}  User
+// @Failure      400              {object}  ErrorResponse
+// @Failure      401              {object}  ErrorResponse
+// @Failure      403              {object}  ErrorResponse
+// @Failure      409              {object}  ErrorResponse
+// @Failure      422              {object}  ErrorResponse
+// @Failure      500              {object}  ErrorResponse
+// @Security     BearerAuth
 // @Router       /users [post]
 func (h *Handler) CreateUser(c *gin.Context) {
 	var req UserCreateRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		h.logger.Error("Invalid user creation payload:", err)
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request payload"})
+	if !h.bindJSON(c, &req) {
 		return
 	}
 
+	if req.Role == "" {
+		req.Role = RoleStudent
+	}
+
+	// Accounts created without a password cannot log in until one is set.
+	var hash string
+	if req.Password != "" {
+		var err error
+		if hash, err = HashPassword(req.Password); err != nil {
+			h.log(c).Error("Failed to hash password:", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to create user"})
+			return
+		}
+	}
+
 	var user User
-	err := h.db.QueryRow(
-		"INSERT INTO users (name, email) VALUES ($1, $2) RETURNING id",
-		req.Name, req.Email,
-	).Scan(&user.ID)
-	if err != nil {
-		h.logger.Error("Failed to create user:", err)
+	err := h.audited(c, func(tx Repositories) (auditedChange, error) {
+		var err error
+		user, err = tx.Users.Create(c.Request.Context(), User{
+			Name:      req.Name,
+			Email:     strings.ToLower(strings.TrimSpace(req.Email)),
+			Role:      req.Role,
+			StudentID: req.StudentID,
+		}, hash)
+		return auditedChange{action: AuditCreate, entityType: AuditEntityUser, entityID: user.ID, after: user}, err
+	})
+	if h.respondConstraintError(c, err) {
+		return
+	} else if err != nil {
+		h.log(c).Error("Failed to create user:", err)
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to create user"})
 		return
 	}
-	user.Name = req.Name
-	user.Email = req.Email
 
-	h.logger.Info("Created user successfully with ID:", user.ID)
+	h.log(c).Info("Created user successfully with ID:", user.ID)
 	c.JSON(http.StatusCreated, user)
 }
 
@@ -310,70 +641,92 @@ func (h *Handler) CreateUser(c *gin.Context) {
 // @Description  Retrieve a single user by ID using query parameter ?id=
 // @Tags         Users
 // @Produce      json
-// @Param        id   query     int  true  "User ID"
-// @Success      200  {object}  User
-// @Failure      400  {object}  ErrorResponse
-// @Failure      404  {object}  ErrorResponse
-// @Failure      500  {object}  ErrorResponse
+// @Param        id               query     int   true   "User ID"
+// @Param        include_deleted  query     bool  false  "Also find a deleted user (admins only)"
+// @Success      200              {object}  User
+// @Failure      400              {object}  ErrorResponse
+// @Failure      401              {object}  ErrorResponse
+// @Failure      403              {object}  ErrorResponse
+// @Failure      404              {object}  ErrorResponse
+// @Failure      500              {object}  ErrorResponse
+// @Security     BearerAuth
 // @Router       /users/by-id [get]
 func (h *Handler) GetUserById(c *gin.Context) {
 	idStr := c.Query("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil || id <= 0 {
-		h.logger.Error("Invalid user ID:", idStr)
+		h.log(c).Error("Invalid user ID:", idStr)
 		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid ID"})
 		return
 	}
+	includeDeleted, err := parseIncludeDeleted(c, PermUsersDelete)
+	if err != nil {
+		h.log(c).Warn("Invalid user lookup:", err)
+		c.JSON(filterErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
 
-	var u User
-	err = h.db.QueryRow("SELECT id, name, email FROM users WHERE id = $1", id).
-		Scan(&u.ID, &u.Name, &u.Email)
-	if err == sql.ErrNoRows {
-		h.logger.Warn("User not found with ID:", id)
+	get := h.users.Get
+	if includeDeleted {
+		get = h.users.GetIncludingDeleted
+	}
+	u, err := get(c.Request.Context(), id)
+	if errors.Is(err, ErrNotFound) {
+		h.log(c).Warn("User not found with ID:", id)
 		c.JSON(http.StatusNotFound, ErrorResponse{Error: "User not found"})
 		return
 	} else if err != nil {
-		h.logger.Error("Failed to fetch user:", err)
+		h.log(c).Error("Failed to fetch user:", err)
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch user"})
 		return
 	}
 
-	h.logger.Info("Fetched user successfully with ID:", id)
+	h.log(c).Info("Fetched user successfully with ID:", id)
 	c.JSON(http.StatusOK, u)
 }
 
 // DeleteUserById godoc
 // @Summary      Delete a user
-// @Description  Deletes a user by ID
+// @Description  Marks a user as deleted and ends their sessions. The account is purged for good after the retention period.
 // @Tags         Users
-// @Param        id  path  int  true  "User ID"
+// @Param        id   path      int  true  "User ID"
 // @Success      204
 // @Failure      400  {object}  ErrorResponse
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
 // @Failure      404  {object}  ErrorResponse
 // @Failure      500  {object}  ErrorResponse
+// @Security     BearerAuth
 // @Router       /users/{id} [delete]
 func (h *Handler) DeleteUserById(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil || id <= 0 {
-		h.logger.Error("Invalid user ID for deletion:", idStr)
+		h.log(c).Error("Invalid user ID for deletion:", idStr)
 		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid ID"})
 		return
 	}
 
-	res, err := h.db.Exec("DELETE FROM users WHERE id = $1", id)
-	if err != nil {
-		h.logger.Error("Failed to delete user:", err)
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to delete user"})
-		return
-	}
-	affected, _ := res.RowsAffected()
-	if affected == 0 {
-		h.logger.Warn("User not found for deletion with ID:", id)
+	err = h.audited(c, func(tx Repositories) (auditedChange, error) {
+		ctx := c.Request.Context()
+		if err := tx.Users.Delete(ctx, id); err != nil {
+			return auditedChange{}, err
+		}
+		after, err := tx.Users.GetIncludingDeleted(ctx, id)
+		before := after
+		before.DeletedAt = nil
+		return auditedChange{action: AuditDelete, entityType: AuditEntityUser, entityID: id, before: before, after: after}, err
+	})
+	if errors.Is(err, ErrNotFound) {
+		h.log(c).Warn("User not found for deletion with ID:", id)
 		c.JSON(http.StatusNotFound, ErrorResponse{Error: "User not found"})
 		return
+	} else if err != nil {
+		h.log(c).Error("Failed to delete user:", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to delete user"})
+		return
 	}
 
-	h.logger.Info("Deleted user successfully with ID:", id)
+	h.log(c).Info("Deleted user successfully with ID:", id)
 	c.Status(http.StatusNoContent)
 }
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	sqlmock "github.com/DATA-DOG/go-sqlmock"
 	"github.com/gin-gonic/gin"
@@ -12,7 +13,7 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-// helper to create a handler with a mocked DB
+// helper to create a handler backed by Postgres repositories on a mocked DB
 func newTestHandler(t *testing.T) (*Handler, sqlmock.Sqlmock) {
 	mockDB, mock, err := sqlmock.New()
 	if err != nil {
@@ -20,12 +21,12 @@ func newTestHandler(t *testing.T) (*Handler, sqlmock.Sqlmock) {
 	}
 	d := &Db{db: mockDB}
 	logger := logrus.New()
-	return NewHandler(d, logger), mock
+	return NewHandler(NewPostgresRepositories(d), logger, NewTokenManager("test-secret", time.Minute, time.Hour)), mock
 }
 
 func TestHealthcheck(t *testing.T) {
 	gin.SetMode(gin.TestMode)
-	h := &Handler{db: nil, logger: logrus.New()}
+	h := &Handler{logger: logrus.New()}
 	r := gin.New()
 	r.GET("/health", h.Healthcheck)
 
@@ -39,26 +40,48 @@ func TestHealthcheck(t *testing.T) {
 	assert.Equal(t, "API is healthy", resp.Message)
 }
 
+func TestHealthcheckWhileDraining(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := &Handler{logger: logrus.New()}
+	h.StartDraining()
+	r := gin.New()
+	r.GET("/health", h.Healthcheck)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/health", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
 func TestGetUsersSuccess(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	h, mock := newTestHandler(t)
 	r := gin.New()
 	r.GET("/users", h.GetUsers)
 
-	rows := sqlmock.NewRows([]string{"id", "name", "email"}).
-		AddRow(1, "Alice", "alice@example.com").
-		AddRow(2, "Bob", "bob@example.com")
-	mock.ExpectQuery("SELECT id, name, email FROM users").WillReturnRows(rows)
+	rows := sqlmock.NewRows([]string{"id", "name", "email", "role", "student_id", "deleted_at"}).
+		AddRow(1, "Alice", "alice@example.com", "admin", nil, nil).
+		AddRow(2, "Bob", "bob@example.com", "student", 4, nil)
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM users").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectQuery("SELECT id, name, email, role, student_id, deleted_at FROM users WHERE deleted_at IS NULL ORDER BY id ASC LIMIT 51").WillReturnRows(rows)
 
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest(http.MethodGet, "/users", nil)
 	r.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusOK, w.Code)
-	var users []User
-	_ = json.Unmarshal(w.Body.Bytes(), &users)
+	var resp UserListResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	users := resp.Data
 	assert.Len(t, users, 2)
+	assert.Equal(t, 2, resp.Total)
+	assert.Empty(t, resp.NextCursor)
 	assert.Equal(t, "Alice", users[0].Name)
+	assert.Equal(t, RoleAdmin, users[0].Role)
+	assert.Nil(t, users[0].StudentID)
+	assert.Equal(t, 4, *users[1].StudentID)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -80,8 +103,8 @@ func TestGetUserByIdNotFound(t *testing.T) {
 	r := gin.New()
 	r.GET("/users/by-id", h.GetUserById)
 
-	rows := sqlmock.NewRows([]string{"id", "name", "email"}) // empty result set
-	mock.ExpectQuery("SELECT id, name, email FROM users WHERE id = \\$1").WithArgs(99).WillReturnRows(rows)
+	rows := sqlmock.NewRows([]string{"id", "name", "email", "role", "student_id", "deleted_at"}) // empty result set
+	mock.ExpectQuery("SELECT id, name, email, role, student_id, deleted_at FROM users WHERE id = \\$1 AND deleted_at IS NULL").WithArgs(99).WillReturnRows(rows)
 
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest(http.MethodGet, "/users/by-id?id=99", nil)
@@ -96,7 +119,15 @@ func TestDeleteUserByIdSuccess(t *testing.T) {
 	r := gin.New()
 	r.DELETE("/users/:id", h.DeleteUserById)
 
-	mock.ExpectExec("DELETE FROM users WHERE id = \\$1").WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 1))
+	deletedAt := time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE users SET deleted_at = now\\(\\) WHERE id = \\$1 AND deleted_at IS NULL").WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT id, name, email, role, student_id, deleted_at FROM users WHERE id = \\$1$").WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email", "role", "student_id", "deleted_at"}).AddRow(7, "Jane", "jane@example.com", "teacher", nil, deletedAt))
+	mock.ExpectExec("INSERT INTO audit_events").
+		WithArgs(nil, nil, AuditDelete, AuditEntityUser, 7, []byte(`{"deleted_at":{"before":null,"after":"2025-09-01T12:00:00Z"}}`), nil, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
 
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest(http.MethodDelete, "/users/7", nil)
@@ -111,7 +142,9 @@ func TestDeleteUserByIdNotFound(t *testing.T) {
 	r := gin.New()
 	r.DELETE("/users/:id", h.DeleteUserById)
 
-	mock.ExpectExec("DELETE FROM users WHERE id = \\$1").WithArgs(8).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE users SET deleted_at = now\\(\\) WHERE id = \\$1 AND deleted_at IS NULL").WithArgs(8).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
 
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest(http.MethodDelete, "/users/8", nil)
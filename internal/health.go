@@ -0,0 +1,132 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultHealthCheckTimeout = 2 * time.Second
+
+// HealthCheck is one dependency probed by the readiness endpoint.
+type HealthCheck struct {
+	Name string
+	// Critical checks make the API unready when they fail; the others are
+	// only reported.
+	Critical bool
+	// Timeout bounds a single run of Check; zero means two seconds.
+	Timeout time.Duration
+	Check   func(ctx context.Context) error
+}
+
+// AddReadinessCheck registers a dependency check for GET /health/ready.
+func (h *Handler) AddReadinessCheck(check HealthCheck) {
+	h.checks = append(h.checks, check)
+}
+
+// runChecks probes every dependency concurrently.
+func (h *Handler) runChecks(ctx context.Context) ReadinessResponse {
+	resp := ReadinessResponse{Status: "ok", Checks: make([]ComponentHealth, len(h.checks))}
+
+	var wg sync.WaitGroup
+	for i, check := range h.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp.Checks[i] = runCheck(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	for _, c := range resp.Checks {
+		if c.Status == "ok" {
+			continue
+		}
+		if c.Critical {
+			resp.Status = "unavailable"
+			break
+		}
+		resp.Status = "degraded"
+	}
+	return resp
+}
+
+func runCheck(ctx context.Context, check HealthCheck) ComponentHealth {
+	timeout := check.Timeout
+	if timeout <= 0 {
+		timeout = defaultHealthCheckTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Check(ctx)
+	result := ComponentHealth{
+		Name:      check.Name,
+		Status:    "ok",
+		Critical:  check.Critical,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = "fail"
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// HTTPReadyCheck probes url and expects a 200 response, e.g. Loki's /ready.
+func HTTPReadyCheck(client *http.Client, url string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		res, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return fmt.Errorf("unexpected status %d from %s", res.StatusCode, url)
+		}
+		return nil
+	}
+}
+
+// Liveness godoc
+// @Summary      Liveness probe
+// @Description  Reports that the process is up and serving HTTP. It does not check dependencies, so a failing database never gets the pod restarted.
+// @Tags         Health
+// @Produce      json
+// @Success      200  {object}  HealthResponse
+// @Router       /health/live [get]
+func (h *Handler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, HealthResponse{Message: "API is alive"})
+}
+
+// Readiness godoc
+// @Summary      Readiness probe
+// @Description  Checks the API's dependencies (database, schema migrations, Loki) and reports each with its latency. Returns 503 when a critical check fails or the API is shutting down.
+// @Tags         Health
+// @Produce      json
+// @Success      200  {object}  ReadinessResponse
+// @Failure      503  {object}  ReadinessResponse
+// @Router       /health/ready [get]
+func (h *Handler) Readiness(c *gin.Context) {
+	if h.draining.Load() {
+		c.JSON(http.StatusServiceUnavailable, ReadinessResponse{Status: "shutting_down", Checks: []ComponentHealth{}})
+		return
+	}
+
+	resp := h.runChecks(c.Request.Context())
+	if resp.Status == "unavailable" {
+		h.log(c).Warn("Readiness check failed:", resp.Checks)
+		c.JSON(http.StatusServiceUnavailable, resp)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func getReadiness(h *Handler) (int, ReadinessResponse) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/health/ready", h.Readiness)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/health/ready", nil)
+	r.ServeHTTP(w, req)
+
+	var resp ReadinessResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	return w.Code, resp
+}
+
+func passing(context.Context) error { return nil }
+func failing(context.Context) error { return errors.New("connection refused") }
+
+func TestReadinessAllChecksPass(t *testing.T) {
+	h := &Handler{logger: logrus.New()}
+	h.AddReadinessCheck(HealthCheck{Name: "database", Critical: true, Check: passing})
+	h.AddReadinessCheck(HealthCheck{Name: "loki", Check: passing})
+
+	code, resp := getReadiness(h)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok", resp.Status)
+	assert.Len(t, resp.Checks, 2)
+	assert.Equal(t, "database", resp.Checks[0].Name)
+	assert.Equal(t, "ok", resp.Checks[0].Status)
+}
+
+func TestReadinessNonCriticalFailureIsDegraded(t *testing.T) {
+	h := &Handler{logger: logrus.New()}
+	h.AddReadinessCheck(HealthCheck{Name: "database", Critical: true, Check: passing})
+	h.AddReadinessCheck(HealthCheck{Name: "loki", Check: failing})
+
+	code, resp := getReadiness(h)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "degraded", resp.Status)
+	assert.Equal(t, "connection refused", resp.Checks[1].Error)
+}
+
+func TestReadinessCriticalFailureAndTimeout(t *testing.T) {
+	h := &Handler{logger: logrus.New()}
+	h.AddReadinessCheck(HealthCheck{Name: "loki", Check: failing})
+	h.AddReadinessCheck(HealthCheck{
+		Name:     "database",
+		Critical: true,
+		Timeout:  10 * time.Millisecond,
+		Check: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	})
+
+	code, resp := getReadiness(h)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "unavailable", resp.Status)
+	assert.Equal(t, "fail", resp.Checks[1].Status)
+	assert.Equal(t, context.DeadlineExceeded.Error(), resp.Checks[1].Error)
+}
+
+func TestReadinessWhileDraining(t *testing.T) {
+	h := &Handler{logger: logrus.New()}
+	h.AddReadinessCheck(HealthCheck{Name: "database", Critical: true, Check: passing})
+	h.StartDraining()
+
+	code, resp := getReadiness(h)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "shutting_down", resp.Status)
+}
+
+func TestHTTPReadyCheck(t *testing.T) {
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/ready", r.URL.Path)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	cfg := LokiConfig{URL: srv.URL + "/loki/api/v1/push"}
+	check := HTTPReadyCheck(srv.Client(), cfg.ReadyURL())
+	assert.NoError(t, check(context.Background()))
+
+	status = http.StatusServiceUnavailable
+	assert.Error(t, check(context.Background()))
+}
@@ -0,0 +1,161 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+	// idempotentReplayedHeader marks a response answered from the stored
+	// response of an earlier request.
+	idempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
+// replayedHeaders are the response headers stored alongside the body.
+var replayedHeaders = []string{"Content-Type", "ETag", "Location"}
+
+// Idempotency lets clients retry a POST without repeating its effect. The
+// first request carrying an Idempotency-Key runs as usual and its response is
+// stored for ttl; retries with the same key and body get that response back
+// instead of running again. Reusing a key for a different request is refused
+// with 422, and a retry that arrives while the first request is still running
+// with 409. Server errors are not stored, so a request that failed that way
+// can be retried for real. Requests without the header pass straight through.
+//
+// Keys belong to the caller, so the middleware must run after authentication.
+func Idempotency(keys IdempotencyRepository, ttl time.Duration, logger *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(idempotencyKeyHeader)
+		claims, ok := CurrentClaims(c)
+		if key == "" || !ok {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, ErrorResponse{
+				Error: fmt.Sprintf("Idempotency-Key must be at most %d characters", maxIdempotencyKeyLength),
+			})
+			return
+		}
+
+		// The body is hashed up front, so it is read whole; imports are the
+		// largest bodies any POST takes.
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBytes))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, ErrorResponse{Error: "Request body is too large"})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusBadRequest, ErrorResponse{Error: "Failed to read request body"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		now := time.Now()
+		rec := IdempotencyRecord{
+			UserID:      claims.UserID,
+			Key:         key,
+			RequestHash: requestHash(c.Request, body),
+			ExpiresAt:   now.Add(ttl),
+		}
+		stored, err := keys.Reserve(c.Request.Context(), rec, now)
+		if errors.Is(err, ErrDuplicate) {
+			replayStored(c, rec, stored, logger)
+			return
+		} else if err != nil {
+			requestLog(c, logger).Error("Failed to reserve idempotency key:", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to check Idempotency-Key"})
+			return
+		}
+
+		// The outcome is stored even when the client has gone away, since
+		// that is exactly when it will retry.
+		ctx := context.WithoutCancel(c.Request.Context())
+		defer func() {
+			if p := recover(); p != nil {
+				_ = keys.Release(ctx, rec.UserID, rec.Key)
+				panic(p)
+			}
+		}()
+
+		w := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+
+		if w.Status() >= http.StatusInternalServerError {
+			if err := keys.Release(ctx, rec.UserID, rec.Key); err != nil {
+				requestLog(c, logger).Error("Failed to release idempotency key:", err)
+			}
+			return
+		}
+		rec.StatusCode = w.Status()
+		rec.Header = http.Header{}
+		for _, name := range replayedHeaders {
+			for _, v := range w.Header().Values(name) {
+				rec.Header.Add(name, v)
+			}
+		}
+		rec.Body = w.body.Bytes()
+		if err := keys.Complete(ctx, rec); err != nil {
+			requestLog(c, logger).Error("Failed to store idempotent response:", err)
+		}
+	}
+}
+
+// replayStored answers a request whose key was already claimed.
+func replayStored(c *gin.Context, rec, stored IdempotencyRecord, logger *logrus.Logger) {
+	switch {
+	case stored.RequestHash != rec.RequestHash:
+		requestLog(c, logger).Warn("Idempotency-Key reused for a different request:", rec.Key)
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, ErrorResponse{Error: "Idempotency-Key was already used for a different request"})
+	case stored.StatusCode == 0:
+		requestLog(c, logger).Warn("Idempotency-Key is still in use:", rec.Key)
+		c.AbortWithStatusJSON(http.StatusConflict, ErrorResponse{Error: "A request with this Idempotency-Key is still in progress"})
+	default:
+		requestLog(c, logger).Info("Replaying stored response for Idempotency-Key:", rec.Key)
+		for name, values := range stored.Header {
+			c.Writer.Header()[http.CanonicalHeaderKey(name)] = values
+		}
+		c.Header(idempotentReplayedHeader, "true")
+		c.Status(stored.StatusCode)
+		_, _ = c.Writer.Write(stored.Body)
+		c.Abort()
+	}
+}
+
+// requestHash identifies a request by its method, target and body.
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", r.Method, r.URL.RequestURI())
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recordingWriter keeps a copy of the response body as it is written.
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,186 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func postWithKey(r *gin.Engine, path, key, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(idempotencyKeyHeader, key)
+	}
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotentRetryReplaysTheFirstResponse(t *testing.T) {
+	h, repos := newMemoryHandler(t)
+	r := testRouter(t, h, testAdmin, RouteMiddleware{Idempotency: Idempotency(repos.Idempotency, time.Hour, logrus.New())})
+	body := `{"name":"John Doe","age":20,"email":"john@example.com"}`
+
+	first := postWithKey(r, "/students", "key-1", body)
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.Empty(t, first.Header().Get(idempotentReplayedHeader))
+
+	retry := postWithKey(r, "/students", "key-1", body)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, "true", retry.Header().Get(idempotentReplayedHeader))
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, first.Header().Get("ETag"), retry.Header().Get("ETag"))
+	assert.Equal(t, first.Header().Get("Content-Type"), retry.Header().Get("Content-Type"))
+	assert.Equal(t, 1, countStudents(t, repos))
+
+	// Without a key, or with another one, the request runs again.
+	assert.Equal(t, http.StatusConflict, postWithKey(r, "/students", "", body).Code)
+	assert.Equal(t, http.StatusConflict, postWithKey(r, "/students", "key-2", body).Code)
+
+	// Keys belong to the user who sent them.
+	other := testRouter(t, h, User{ID: 2, Role: RoleAdmin}, RouteMiddleware{Idempotency: Idempotency(repos.Idempotency, time.Hour, logrus.New())})
+	assert.Empty(t, postWithKey(other, "/students", "key-1", body).Header().Get(idempotentReplayedHeader))
+}
+
+func TestIdempotencyKeyReusedForAnotherRequest(t *testing.T) {
+	h, repos := newMemoryHandler(t)
+	r := testRouter(t, h, testAdmin, RouteMiddleware{Idempotency: Idempotency(repos.Idempotency, time.Hour, logrus.New())})
+
+	assert.Equal(t, http.StatusCreated, postWithKey(r, "/students", "key-1", `{"name":"John Doe","age":20,"email":"john@example.com"}`).Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, postWithKey(r, "/students", "key-1", `{"name":"Jane Doe","age":22,"email":"jane@example.com"}`).Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, postWithKey(r, "/users", "key-1", `{"name":"John Doe","age":20,"email":"john@example.com"}`).Code)
+	assert.Equal(t, 1, countStudents(t, repos))
+
+	assert.Equal(t, http.StatusBadRequest, postWithKey(r, "/students", strings.Repeat("k", maxIdempotencyKeyLength+1), "{}").Code)
+}
+
+func TestIdempotencyKeyInProgress(t *testing.T) {
+	h, repos := newMemoryHandler(t)
+	r := testRouter(t, h, testAdmin, RouteMiddleware{Idempotency: Idempotency(repos.Idempotency, time.Hour, logrus.New())})
+	body := `{"name":"John Doe","age":20,"email":"john@example.com"}`
+
+	req, _ := http.NewRequest(http.MethodPost, "/students", nil)
+	_, err := repos.Idempotency.Reserve(context.Background(), IdempotencyRecord{
+		UserID:      1,
+		Key:         "key-1",
+		RequestHash: requestHash(req, []byte(body)),
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}, time.Now())
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusConflict, postWithKey(r, "/students", "key-1", body).Code)
+	assert.Equal(t, 0, countStudents(t, repos))
+}
+
+func TestIdempotencyServerErrorsAreNotKept(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repos := NewMemoryRepositories()
+	calls := 0
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set(claimsContextKey, &Claims{UserID: 1})
+		c.Next()
+	})
+	r.Use(Idempotency(repos.Idempotency, time.Hour, logrus.New()))
+	r.POST("/flaky", func(c *gin.Context) {
+		calls++
+		if calls == 1 {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "try again"})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"call": calls})
+	})
+
+	assert.Equal(t, http.StatusInternalServerError, postWithKey(r, "/flaky", "key-1", "{}").Code)
+	assert.Equal(t, http.StatusCreated, postWithKey(r, "/flaky", "key-1", "{}").Code)
+	assert.Equal(t, http.StatusCreated, postWithKey(r, "/flaky", "key-1", "{}").Code)
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotencyKeysExpire(t *testing.T) {
+	ctx := context.Background()
+	keys := NewMemoryRepositories().Idempotency
+	now := time.Now()
+	rec := IdempotencyRecord{UserID: 1, Key: "key-1", RequestHash: "a", ExpiresAt: now.Add(time.Hour)}
+
+	_, err := keys.Reserve(ctx, rec, now)
+	assert.NoError(t, err)
+	assert.NoError(t, keys.Complete(ctx, IdempotencyRecord{UserID: 1, Key: "key-1", StatusCode: http.StatusCreated}))
+	stored, err := keys.Reserve(ctx, rec, now.Add(time.Minute))
+	assert.ErrorIs(t, err, ErrDuplicate)
+	assert.Equal(t, http.StatusCreated, stored.StatusCode)
+
+	// Once expired the key can be claimed again, for any request.
+	rec.RequestHash = "b"
+	stored, err = keys.Reserve(ctx, rec, now.Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.Zero(t, stored.StatusCode)
+
+	n, err := keys.Purge(ctx, now.Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+}
+
+func TestPostgresIdempotencyReserveReturnsTheStoredResponse(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+	keys := NewPostgresRepositories(&Db{db: mockDB}).Idempotency
+	now := time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC)
+	rec := IdempotencyRecord{UserID: 1, Key: "key-1", RequestHash: "abc", ExpiresAt: now.Add(time.Hour)}
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO idempotency_keys (user_id, key, request_hash, expires_at) VALUES ($1, $2, $3, $4)")).
+		WithArgs(1, "key-1", "abc", now.Add(time.Hour), now).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT request_hash, status_code, headers, body, expires_at FROM idempotency_keys WHERE user_id = $1 AND key = $2")).
+		WithArgs(1, "key-1").
+		WillReturnRows(sqlmock.NewRows([]string{"request_hash", "status_code", "headers", "body", "expires_at"}).
+			AddRow("abc", 201, []byte(`{"Content-Type":["application/json; charset=utf-8"]}`), []byte(`{"id":5}`), now.Add(30*time.Minute)))
+
+	stored, err := keys.Reserve(context.Background(), rec, now)
+	assert.ErrorIs(t, err, ErrDuplicate)
+	assert.Equal(t, http.StatusCreated, stored.StatusCode)
+	assert.Equal(t, "application/json; charset=utf-8", stored.Header.Get("Content-Type"))
+	assert.Equal(t, `{"id":5}`, string(stored.Body))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRegisterRoutesGuardsAuthenticatedPosts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tm := NewTokenManager("test-secret", time.Minute, time.Hour)
+	guarded := map[string]bool{}
+	mark := func(c *gin.Context) {
+		guarded[c.FullPath()] = true
+		c.Next()
+	}
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+
+	r := gin.New()
+	RegisterRoutes(r.Group("/api/v1"), RouteMiddleware{Auth: AuthMiddleware(tm, staticSessions{"s": true}), Idempotency: mark}, []Route{
+		{Method: http.MethodPost, Path: "/auth/login", Handler: ok, Public: true},
+		{Method: http.MethodPost, Path: "/students", Handler: ok},
+		{Method: http.MethodPut, Path: "/students/:id", Handler: ok},
+	})
+	token, _ := tm.IssueAccessToken(User{ID: 1, Role: RoleAdmin}, "s")
+	for _, rt := range []struct{ method, path string }{
+		{http.MethodPost, "/api/v1/auth/login"},
+		{http.MethodPost, "/api/v1/students"},
+		{http.MethodPut, "/api/v1/students/1"},
+	} {
+		req, _ := http.NewRequest(rt.method, rt.path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	assert.Equal(t, map[string]bool{"/api/v1/students": true}, guarded)
+}
@@ -0,0 +1,258 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+// SortField is one column of an ORDER BY clause.
+type SortField struct {
+	Column string
+	Desc   bool
+}
+
+// ListOptions controls paging and ordering of list endpoints.
+type ListOptions struct {
+	Limit int
+	Sort  []SortField
+	// After holds the sort key values of the last row of the previous page,
+	// decoded from the cursor. Empty for the first page.
+	After []any
+
+	sortSpec string
+}
+
+// cursorPayload is what an opaque next_cursor token decodes to. The sort spec
+// is embedded so a cursor cannot be replayed against a different ordering.
+type cursorPayload struct {
+	Sort   string `json:"s"`
+	Values []any  `json:"v"`
+}
+
+// parseListOptions reads limit, sort and cursor from the query string. Sort
+// keys are restricted to the sortable whitelist (API name -> column) and the
+// "id" column is always appended as a tiebreaker so ordering is total.
+func parseListOptions(c *gin.Context, sortable map[string]string) (ListOptions, error) {
+	opts := ListOptions{Limit: defaultPageSize}
+
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			return opts, fmt.Errorf("limit must be between 1 and %d", maxPageSize)
+		}
+		opts.Limit = limit
+	}
+
+	sort, err := parseSort(c, sortable)
+	if err != nil {
+		return opts, err
+	}
+	opts.Sort = sort
+
+	specs := make([]string, len(opts.Sort))
+	for i, f := range opts.Sort {
+		specs[i] = f.Column
+		if f.Desc {
+			specs[i] = "-" + f.Column
+		}
+	}
+	opts.sortSpec = strings.Join(specs, ",")
+
+	if v := c.Query("cursor"); v != "" {
+		after, err := decodeCursor(v, opts.sortSpec, len(opts.Sort))
+		if err != nil {
+			return opts, err
+		}
+		opts.After = after
+	}
+	return opts, nil
+}
+
+// parseSort reads the sort query parameter against the sortable whitelist,
+// appending "id" as a tiebreaker unless it is already a key.
+func parseSort(c *gin.Context, sortable map[string]string) ([]SortField, error) {
+	var sort []SortField
+	seen := map[string]bool{}
+	for _, key := range strings.Split(c.Query("sort"), ",") {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		desc := strings.HasPrefix(key, "-")
+		name := strings.TrimPrefix(key, "-")
+		col, ok := sortable[name]
+		if !ok {
+			return nil, fmt.Errorf("cannot sort by %q", name)
+		}
+		if seen[col] {
+			return nil, fmt.Errorf("duplicate sort key %q", name)
+		}
+		seen[col] = true
+		sort = append(sort, SortField{Column: col, Desc: desc})
+	}
+	if !seen["id"] {
+		sort = append(sort, SortField{Column: "id"})
+	}
+	return sort, nil
+}
+
+// NextCursor encodes the sort key values of the last row on a page.
+func (o ListOptions) NextCursor(values []any) string {
+	b, _ := json.Marshal(cursorPayload{Sort: o.sortSpec, Values: values})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(token, sortSpec string, n int) ([]any, error) {
+	errInvalid := errors.New("invalid cursor")
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errInvalid
+	}
+	var p cursorPayload
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	// Keep numbers as json.Number so integer keys round-trip exactly.
+	dec.UseNumber()
+	if err := dec.Decode(&p); err != nil || len(p.Values) != n {
+		return nil, errInvalid
+	}
+	if p.Sort != sortSpec {
+		return nil, errors.New("cursor does not match the requested sort order")
+	}
+	return p.Values, nil
+}
+
+// OrderBy renders the ORDER BY clause for the sort fields.
+func (o ListOptions) OrderBy() string {
+	parts := make([]string, len(o.Sort))
+	for i, f := range o.Sort {
+		dir := "ASC"
+		if f.Desc {
+			dir = "DESC"
+		}
+		parts[i] = f.Column + " " + dir
+	}
+	return "ORDER BY " + strings.Join(parts, ", ")
+}
+
+// compareKeys orders two tuples of sort key values according to sort,
+// returning a negative number when a sorts before b. It is the in-memory
+// counterpart of OrderBy and addKeyset.
+func compareKeys(sort []SortField, a, b []any) int {
+	for i, f := range sort {
+		c := compareValues(a[i], b[i])
+		if f.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// compareValues compares two sort key values. Cursor values decode as
+// json.Number or string, so both sides are normalised first.
+func compareValues(a, b any) int {
+	an, aIsNum := numericValue(a)
+	bn, bIsNum := numericValue(b)
+	if aIsNum && bIsNum {
+		return cmpOrdered(an, bn)
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func numericValue(v any) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int64:
+		return n, true
+	case json.Number:
+		i, err := n.Int64()
+		return i, err == nil
+	}
+	return 0, false
+}
+
+func cmpOrdered(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// whereBuilder accumulates AND-ed SQL conditions with positional arguments.
+type whereBuilder struct {
+	conds []string
+	args  []any
+}
+
+// add appends a condition; each "?" in cond is replaced by the next $n.
+func (w *whereBuilder) add(cond string, args ...any) {
+	for _, a := range args {
+		w.args = append(w.args, a)
+		cond = strings.Replace(cond, "?", "$"+strconv.Itoa(len(w.args)), 1)
+	}
+	w.conds = append(w.conds, cond)
+}
+
+// addKeyset restricts rows to those after the cursor position:
+// (a > x) OR (a = x AND b > y) OR ..., flipping > to < for DESC columns.
+func (w *whereBuilder) addKeyset(o ListOptions) {
+	if len(o.After) == 0 {
+		return
+	}
+	var ors []string
+	for i, f := range o.Sort {
+		var ands []string
+		for j := 0; j < i; j++ {
+			w.args = append(w.args, o.After[j])
+			ands = append(ands, fmt.Sprintf("%s = $%d", o.Sort[j].Column, len(w.args)))
+		}
+		op := ">"
+		if f.Desc {
+			op = "<"
+		}
+		w.args = append(w.args, o.After[i])
+		ands = append(ands, fmt.Sprintf("%s %s $%d", f.Column, op, len(w.args)))
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+	w.conds = append(w.conds, "("+strings.Join(ors, " OR ")+")")
+}
+
+func (w *whereBuilder) String() string {
+	if len(w.conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(w.conds, " AND ")
+}
+
+// likeEscaper escapes LIKE wildcards so user input matches literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func queryInt(c *gin.Context, key string) (*int, error) {
+	v := c.Query(key)
+	if v == "" {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be an integer", key)
+	}
+	return &n, nil
+}
@@ -0,0 +1,97 @@
+package internal
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func testContext(rawQuery string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/?"+rawQuery, nil)
+	return c
+}
+
+func TestParseListOptionsSort(t *testing.T) {
+	opts, err := parseListOptions(testContext("sort=name,-age&limit=10"), studentSortColumns)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, opts.Limit)
+	assert.Equal(t, []SortField{{Column: "name"}, {Column: "age", Desc: true}, {Column: "id"}}, opts.Sort)
+	assert.Equal(t, "ORDER BY name ASC, age DESC, id ASC", opts.OrderBy())
+
+	_, err = parseListOptions(testContext("sort=password"), studentSortColumns)
+	assert.Error(t, err)
+	_, err = parseListOptions(testContext("limit=1000"), studentSortColumns)
+	assert.Error(t, err)
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	first, _ := parseListOptions(testContext("sort=-age"), studentSortColumns)
+	cursor := first.NextCursor([]any{21, 7})
+
+	next, err := parseListOptions(testContext("sort=-age&cursor="+cursor), studentSortColumns)
+	assert.NoError(t, err)
+	assert.Equal(t, []any{json.Number("21"), json.Number("7")}, next.After)
+
+	// A cursor is bound to the ordering it was issued for.
+	_, err = parseListOptions(testContext("sort=name&cursor="+cursor), studentSortColumns)
+	assert.Error(t, err)
+	_, err = parseListOptions(testContext("cursor=garbage"), studentSortColumns)
+	assert.Error(t, err)
+}
+
+func TestKeysetCondition(t *testing.T) {
+	opts := ListOptions{
+		Sort:  []SortField{{Column: "name"}, {Column: "age", Desc: true}, {Column: "id"}},
+		After: []any{"Bob", 20, 5},
+	}
+	var w whereBuilder
+	w.add("age >= ?", 18)
+	w.addKeyset(opts)
+
+	assert.Equal(t,
+		" WHERE age >= $1 AND ((name > $2) OR (name = $3 AND age < $4) OR (name = $5 AND age = $6 AND id > $7))",
+		w.String())
+	assert.Equal(t, []any{18, "Bob", "Bob", 20, "Bob", 20, 5}, w.args)
+}
+
+func TestGetStudentsPaginatedAndFiltered(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h, mock := newTestHandler(t)
+	r := gin.New()
+	r.GET("/students", h.GetStudents)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM students WHERE age >= $1 AND lower(email) LIKE $2 AND deleted_at IS NULL")).
+		WithArgs(18, "%@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name, age, email, deleted_at, version FROM students WHERE age >= $1 AND lower(email) LIKE $2 AND deleted_at IS NULL ORDER BY name ASC, id ASC LIMIT 3")).
+		WithArgs(18, "%@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "age", "email", "deleted_at", "version"}).
+			AddRow(2, "Alice", 20, "alice@example.com", nil, 1).
+			AddRow(1, "Bob", 22, "bob@example.com", nil, 1).
+			AddRow(3, "Carol", 19, "carol@example.com", nil, 1))
+
+	q := url.Values{"limit": {"2"}, "sort": {"name"}, "min_age": {"18"}, "email_domain": {"@Example.com"}}
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/students?"+q.Encode(), nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp StudentListResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.Len(t, resp.Data, 2)
+	assert.Equal(t, 3, resp.Total)
+	assert.NotEmpty(t, resp.NextCursor)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	next, err := parseListOptions(testContext("sort=name&cursor="+resp.NextCursor), studentSortColumns)
+	assert.NoError(t, err)
+	assert.Equal(t, []any{"Bob", json.Number("1")}, next.After)
+}
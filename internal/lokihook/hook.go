@@ -0,0 +1,344 @@
+// Package lokihook ships logrus entries to Grafana Loki. Entries are queued
+// by the logging goroutine and pushed in batches by a goroutine of the hook,
+// so a slow or unavailable Loki never holds up a request.
+package lokihook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/sirupsen/logrus"
+)
+
+// LevelField promotes the entry's level, which is not one of its fields.
+const LevelField = "level"
+
+// otherValue replaces the values of a promoted label past its cap.
+const otherValue = "other"
+
+const (
+	defaultMaxLabelValues = 100
+	defaultBufferSize     = 10000
+	defaultBatchSize      = 500
+	defaultBatchWait      = time.Second
+	defaultPushTimeout    = 10 * time.Second
+
+	// maxAttempts bounds how often a batch is pushed before it is dropped.
+	maxAttempts  = 3
+	retryBackoff = 500 * time.Millisecond
+)
+
+// Config configures a Hook. Zero sizes and durations take the defaults.
+type Config struct {
+	// URL is Loki's push endpoint, e.g. http://loki:3100/loki/api/v1/push.
+	URL string
+	// Labels are attached to every entry.
+	Labels map[string]string
+	// PromotedFields are entry fields also sent as labels, so Loki can
+	// select streams by them. LevelField promotes the level.
+	PromotedFields []string
+	// MaxLabelValues caps the distinct values of each promoted label. Later
+	// values are sent as "other", so a field with unbounded values cannot
+	// create unbounded streams.
+	MaxLabelValues int
+	// BufferSize is how many entries may wait to be shipped. Entries logged
+	// while the buffer is full are dropped.
+	BufferSize int
+	// BatchSize is the most entries one push carries.
+	BatchSize int
+	// BatchWait is how long an entry waits for its batch to fill up.
+	BatchWait time.Duration
+	// Client sends the pushes; its Timeout bounds each attempt.
+	Client *http.Client
+}
+
+// Hook is a logrus.Hook that ships entries to Loki. Close it to flush the
+// entries still buffered.
+type Hook struct {
+	cfg     Config
+	guard   *labelGuard
+	entries chan entry
+
+	// mu guards closing entries against concurrent Fire calls.
+	mu     sync.RWMutex
+	closed bool
+	done   chan struct{}
+}
+
+type entry struct {
+	labels map[string]string
+	time   time.Time
+	line   string
+}
+
+// New validates cfg and starts shipping.
+func New(cfg Config) (*Hook, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("lokihook: URL is required")
+	}
+	for name, value := range cfg.Labels {
+		if !model.LabelName(name).IsValidLegacy() || !model.LabelValue(value).IsValid() {
+			return nil, fmt.Errorf("lokihook: invalid label %q", name)
+		}
+	}
+	for _, name := range cfg.PromotedFields {
+		if !model.LabelName(name).IsValidLegacy() {
+			return nil, fmt.Errorf("lokihook: field %q cannot be a label name", name)
+		}
+		if _, ok := cfg.Labels[name]; ok {
+			return nil, fmt.Errorf("lokihook: promoted field %q is also a static label", name)
+		}
+	}
+	if cfg.MaxLabelValues <= 0 {
+		cfg.MaxLabelValues = defaultMaxLabelValues
+	}
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = defaultBufferSize
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultBatchSize
+	}
+	if cfg.BatchWait <= 0 {
+		cfg.BatchWait = defaultBatchWait
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: defaultPushTimeout}
+	}
+
+	h := &Hook{
+		cfg:     cfg,
+		guard:   &labelGuard{max: cfg.MaxLabelValues, seen: map[string]map[string]bool{}},
+		entries: make(chan entry, cfg.BufferSize),
+		done:    make(chan struct{}),
+	}
+	go h.run()
+	return h, nil
+}
+
+func (h *Hook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire formats the entry and queues it without waiting. It never reports
+// shipping problems, which would only be logged again; they are counted in
+// the metrics instead.
+func (h *Hook) Fire(e *logrus.Entry) error {
+	line, err := e.String()
+	if err != nil {
+		return err
+	}
+
+	labels := make(map[string]string, len(h.cfg.Labels)+len(h.cfg.PromotedFields))
+	for name, value := range h.cfg.Labels {
+		labels[name] = value
+	}
+	for _, name := range h.cfg.PromotedFields {
+		value := e.Level.String()
+		if name != LevelField {
+			v, ok := e.Data[name]
+			if !ok {
+				continue
+			}
+			value = fmt.Sprint(v)
+		}
+		labels[name] = h.guard.admit(name, value)
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.closed {
+		EntriesDroppedTotal.WithLabelValues("closed").Inc()
+		return nil
+	}
+	select {
+	case h.entries <- entry{labels: labels, time: e.Time, line: strings.TrimSuffix(line, "\n")}:
+	default:
+		EntriesDroppedTotal.WithLabelValues("buffer_full").Inc()
+	}
+	return nil
+}
+
+// Close stops accepting entries and waits until the buffered ones have been
+// pushed or ctx is done.
+func (h *Hook) Close(ctx context.Context) error {
+	h.mu.Lock()
+	if !h.closed {
+		h.closed = true
+		close(h.entries)
+	}
+	h.mu.Unlock()
+
+	select {
+	case <-h.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run collects entries into batches, pushing each once it is full or its
+// first entry has waited BatchWait.
+func (h *Hook) run() {
+	defer close(h.done)
+	batch := make([]entry, 0, h.cfg.BatchSize)
+	wait := time.NewTimer(h.cfg.BatchWait)
+	wait.Stop()
+	flush := func() {
+		wait.Stop()
+		if len(batch) > 0 {
+			h.push(batch)
+			batch = batch[:0]
+		}
+	}
+
+	for {
+		select {
+		case e, ok := <-h.entries:
+			if !ok {
+				flush()
+				return
+			}
+			if len(batch) == 0 {
+				wait.Reset(h.cfg.BatchWait)
+			}
+			batch = append(batch, e)
+			if len(batch) == h.cfg.BatchSize {
+				flush()
+			}
+		case <-wait.C:
+			flush()
+		}
+	}
+}
+
+// push sends the batch, retrying failures Loki may recover from.
+func (h *Hook) push(batch []entry) {
+	body, err := encode(batch)
+	if err != nil {
+		EntriesDroppedTotal.WithLabelValues("encode_failed").Add(float64(len(batch)))
+		return
+	}
+	for attempt := 1; ; attempt++ {
+		err := h.send(body)
+		if err == nil {
+			EntriesSentTotal.Add(float64(len(batch)))
+			return
+		}
+		var status statusError
+		if attempt == maxAttempts || (errors.As(err, &status) && !status.retryable()) {
+			EntriesDroppedTotal.WithLabelValues("push_failed").Add(float64(len(batch)))
+			return
+		}
+		time.Sleep(retryBackoff * time.Duration(attempt))
+	}
+}
+
+func (h *Hook) send(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, h.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := h.cfg.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return statusError(resp.StatusCode)
+	}
+	return nil
+}
+
+// statusError is a push Loki answered with an unsuccessful status.
+type statusError int
+
+func (s statusError) Error() string {
+	return "loki answered " + strconv.Itoa(int(s))
+}
+
+// retryable reports whether Loki may accept the same push later.
+func (s statusError) retryable() bool {
+	return s == http.StatusTooManyRequests || s >= http.StatusInternalServerError
+}
+
+// pushRequest is the JSON body of Loki's push API.
+type pushRequest struct {
+	Streams []pushStream `json:"streams"`
+}
+
+type pushStream struct {
+	Stream map[string]string `json:"stream"`
+	// Values are [unix nanoseconds, line] pairs.
+	Values [][2]string `json:"values"`
+}
+
+// encode groups the batch into one stream per label set.
+func encode(batch []entry) ([]byte, error) {
+	var req pushRequest
+	streams := map[string]int{}
+	for _, e := range batch {
+		key := labelKey(e.labels)
+		i, ok := streams[key]
+		if !ok {
+			i = len(req.Streams)
+			streams[key] = i
+			req.Streams = append(req.Streams, pushStream{Stream: e.labels})
+		}
+		req.Streams[i].Values = append(req.Streams[i].Values, [2]string{strconv.FormatInt(e.time.UnixNano(), 10), e.line})
+	}
+	return json.Marshal(req)
+}
+
+func labelKey(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(labels[name]))
+		b.WriteByte(',')
+	}
+	return b.String()
+}
+
+// labelGuard admits the first max values of each promoted label.
+type labelGuard struct {
+	mu   sync.Mutex
+	max  int
+	seen map[string]map[string]bool
+}
+
+func (g *labelGuard) admit(label, value string) string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	values := g.seen[label]
+	if values == nil {
+		values = map[string]bool{}
+		g.seen[label] = values
+	}
+	if values[value] {
+		return value
+	}
+	if len(values) >= g.max {
+		LabelValuesCappedTotal.WithLabelValues(label).Inc()
+		return otherValue
+	}
+	values[value] = true
+	return value
+}
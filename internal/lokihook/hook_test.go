@@ -0,0 +1,190 @@
+package lokihook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLoki records the pushes it receives and answers them with the next of
+// statuses, then 204.
+type fakeLoki struct {
+	*httptest.Server
+	pushes   chan pushRequest
+	statuses chan int
+	// hold, when set, keeps every push waiting until it is closed.
+	hold chan struct{}
+}
+
+func newFakeLoki(t *testing.T, statuses ...int) *fakeLoki {
+	f := &fakeLoki{pushes: make(chan pushRequest, 100), statuses: make(chan int, len(statuses))}
+	for _, s := range statuses {
+		f.statuses <- s
+	}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/loki/api/v1/push", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		var req pushRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		f.pushes <- req
+		if f.hold != nil {
+			<-f.hold
+		}
+		select {
+		case s := <-f.statuses:
+			w.WriteHeader(s)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeLoki) next(t *testing.T) pushRequest {
+	t.Helper()
+	select {
+	case req := <-f.pushes:
+		return req
+	case <-time.After(5 * time.Second):
+		t.Fatal("no push reached Loki")
+		return pushRequest{}
+	}
+}
+
+func newTestLogger(t *testing.T, f *fakeLoki, cfg Config) (*logrus.Logger, *Hook) {
+	cfg.URL = f.URL + "/loki/api/v1/push"
+	if cfg.BatchWait == 0 {
+		// Batches are only sent when full or on Close unless a test says so.
+		cfg.BatchWait = time.Hour
+	}
+	h, err := New(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = h.Close(context.Background()) })
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	logger.SetFormatter(&logrus.JSONFormatter{})
+	logger.AddHook(h)
+	return logger, h
+}
+
+func TestHookPushesBatchesGroupedByLabels(t *testing.T) {
+	f := newFakeLoki(t)
+	logger, _ := newTestLogger(t, f, Config{
+		Labels:         map[string]string{"job": "student-api", "env": "test"},
+		PromotedFields: []string{LevelField, "route"},
+		BatchSize:      3,
+	})
+
+	logger.WithField("route", "/students").Info("one")
+	logger.WithField("route", "/students").Info("two")
+	logger.Warn("three")
+
+	req := f.next(t)
+	require.Len(t, req.Streams, 2)
+	assert.Equal(t, map[string]string{"job": "student-api", "env": "test", "level": "info", "route": "/students"}, req.Streams[0].Stream)
+	require.Len(t, req.Streams[0].Values, 2)
+	assert.Contains(t, req.Streams[0].Values[0][1], `"msg":"one"`)
+	assert.Contains(t, req.Streams[0].Values[1][1], `"msg":"two"`)
+	assert.NotContains(t, req.Streams[0].Values[0][1], "\n")
+
+	// Entries without a promoted field are sent without that label.
+	assert.Equal(t, map[string]string{"job": "student-api", "env": "test", "level": "warning"}, req.Streams[1].Stream)
+	require.Len(t, req.Streams[1].Values, 1)
+	assert.Contains(t, req.Streams[1].Values[0][1], `"msg":"three"`)
+}
+
+func TestHookFlushesOnBatchWaitAndClose(t *testing.T) {
+	f := newFakeLoki(t)
+	logger, h := newTestLogger(t, f, Config{BatchSize: 100, BatchWait: 20 * time.Millisecond})
+
+	logger.Info("waited")
+	assert.Len(t, f.next(t).Streams[0].Values, 1)
+
+	logger.Info("closing")
+	require.NoError(t, h.Close(context.Background()))
+	assert.Contains(t, f.next(t).Streams[0].Values[0][1], "closing")
+
+	before := testutil.ToFloat64(EntriesDroppedTotal.WithLabelValues("closed"))
+	logger.Info("too late")
+	assert.Equal(t, before+1, testutil.ToFloat64(EntriesDroppedTotal.WithLabelValues("closed")))
+}
+
+func TestHookCapsPromotedLabelValues(t *testing.T) {
+	f := newFakeLoki(t)
+	logger, h := newTestLogger(t, f, Config{PromotedFields: []string{"route"}, MaxLabelValues: 2})
+
+	before := testutil.ToFloat64(LabelValuesCappedTotal.WithLabelValues("route"))
+	for _, route := range []string{"/a", "/b", "/c", "/a", "/d"} {
+		logger.WithField("route", route).Info("hit")
+	}
+	require.NoError(t, h.Close(context.Background()))
+
+	var routes []string
+	for _, s := range f.next(t).Streams {
+		for range s.Values {
+			routes = append(routes, s.Stream["route"])
+		}
+	}
+	assert.ElementsMatch(t, []string{"/a", "/a", "/b", otherValue, otherValue}, routes)
+	assert.Equal(t, before+2, testutil.ToFloat64(LabelValuesCappedTotal.WithLabelValues("route")))
+}
+
+func TestHookDropsEntriesWhenBufferIsFull(t *testing.T) {
+	f := newFakeLoki(t)
+	f.hold = make(chan struct{})
+	logger, h := newTestLogger(t, f, Config{BufferSize: 1, BatchSize: 1})
+	before := testutil.ToFloat64(EntriesDroppedTotal.WithLabelValues("buffer_full"))
+
+	// The first entry is being pushed, the second waits in the buffer and
+	// the third has nowhere to go; logging never blocks on Loki.
+	logger.Info("pushing")
+	f.next(t)
+	start := time.Now()
+	logger.Info("buffered")
+	logger.Info("dropped")
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, before+1, testutil.ToFloat64(EntriesDroppedTotal.WithLabelValues("buffer_full")))
+
+	close(f.hold)
+	require.NoError(t, h.Close(context.Background()))
+	assert.Contains(t, f.next(t).Streams[0].Values[0][1], "buffered")
+}
+
+func TestHookRetriesOnlyRecoverableFailures(t *testing.T) {
+	f := newFakeLoki(t, http.StatusServiceUnavailable, http.StatusBadRequest)
+	logger, h := newTestLogger(t, f, Config{BatchSize: 1})
+	sent := testutil.ToFloat64(EntriesSentTotal)
+	failed := testutil.ToFloat64(EntriesDroppedTotal.WithLabelValues("push_failed"))
+
+	// 503 is retried and the retry gets the 400, which is not.
+	logger.Info("rejected")
+	f.next(t)
+	f.next(t)
+	require.NoError(t, h.Close(context.Background()))
+	assert.Empty(t, f.pushes)
+	assert.Equal(t, sent, testutil.ToFloat64(EntriesSentTotal))
+	assert.Equal(t, failed+1, testutil.ToFloat64(EntriesDroppedTotal.WithLabelValues("push_failed")))
+}
+
+func TestNewRejectsInvalidConfig(t *testing.T) {
+	for name, cfg := range map[string]Config{
+		"no url":        {},
+		"bad label":     {URL: "http://loki", Labels: map[string]string{"bad-label": "x"}},
+		"bad field":     {URL: "http://loki", PromotedFields: []string{"user.id"}},
+		"label clashes": {URL: "http://loki", Labels: map[string]string{"env": "dev"}, PromotedFields: []string{"env"}},
+	} {
+		_, err := New(cfg)
+		assert.Error(t, err, name)
+	}
+}
@@ -0,0 +1,31 @@
+package lokihook
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	EntriesSentTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "loki_entries_sent_total",
+			Help: "Total number of log entries pushed to Loki",
+		},
+	)
+
+	EntriesDroppedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "loki_entries_dropped_total",
+			Help: "Total number of log entries never shipped to Loki, labeled by reason",
+		},
+		[]string{"reason"},
+	)
+
+	LabelValuesCappedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "loki_label_values_capped_total",
+			Help: "Total number of log entries whose promoted label value was replaced by \"other\", labeled by label",
+		},
+		[]string{"label"},
+	)
+)
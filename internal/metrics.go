@@ -31,6 +31,29 @@ var (
 		},
 	)
 
+	GradeSubmissionsTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "grade_submissions_total",
+			Help: "Total number of grades recorded",
+		},
+	)
+
+	RecordsPurgedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "records_purged_total",
+			Help: "Total number of deleted records and expired idempotency keys removed for good, labeled by table",
+		},
+		[]string{"table"},
+	)
+
+	RateLimitedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_rate_limited_total",
+			Help: "Total number of HTTP requests refused by the rate limiter, labeled by route and method",
+		},
+		[]string{"route", "method"},
+	)
+
 	StatusCodesTotal = promauto.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "http_response_status_codes_total",
@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmbeddedMigrationsLoad(t *testing.T) {
+	d := &Db{}
+	m, err := NewMigrator(d)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, m.migrations)
+	for i, mig := range m.migrations {
+		assert.Equal(t, i+1, mig.Version, "migration versions must be contiguous")
+	}
+}
+
+func TestLoadMigrationsRejectsBadSets(t *testing.T) {
+	_, err := loadMigrations(fstest.MapFS{
+		"0001_init.up.sql": {Data: []byte("CREATE TABLE a ();")},
+	})
+	assert.ErrorContains(t, err, "needs both an up and a down file")
+
+	_, err = loadMigrations(fstest.MapFS{
+		"0001_init.up.sql":    {Data: []byte("CREATE TABLE a ();")},
+		"0001_init.down.sql":  {Data: []byte("DROP TABLE a;")},
+		"0001_other.up.sql":   {Data: []byte("CREATE TABLE b ();")},
+		"0001_other.down.sql": {Data: []byte("DROP TABLE b;")},
+	})
+	assert.ErrorContains(t, err, "used by both")
+
+	_, err = loadMigrations(fstest.MapFS{"README.md": {Data: []byte("hi")}})
+	assert.ErrorContains(t, err, "unexpected file")
+}
+
+func TestMigratorUpAppliesPendingUnderLock(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	m := &Migrator{db: mockDB, migrations: []Migration{
+		{Version: 1, Name: "one", Up: "CREATE TABLE one ()", Down: "DROP TABLE one"},
+		{Version: 2, Name: "two", Up: "CREATE TABLE two ()", Down: "DROP TABLE two"},
+	}}
+
+	mock.ExpectExec("SELECT pg_advisory_lock").WithArgs(migrationLockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version, applied_at FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "applied_at"}).AddRow(1, time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectExec("CREATE TABLE two").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO schema_migrations").WithArgs(2, "two").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec("SELECT pg_advisory_unlock").WithArgs(migrationLockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	n, err := m.Up(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigratorStatusOnlyReads(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	m := &Migrator{db: mockDB, migrations: []Migration{
+		{Version: 1, Name: "one", Up: "CREATE TABLE one ()", Down: "DROP TABLE one"},
+		{Version: 2, Name: "two", Up: "CREATE TABLE two ()", Down: "DROP TABLE two"},
+	}}
+
+	// No CREATE TABLE is expected, so sqlmock fails the test if one is sent.
+	mock.ExpectQuery("SELECT version, applied_at FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "applied_at"}).AddRow(1, time.Now()))
+	assert.EqualError(t, m.CheckApplied(context.Background()), "1 pending migration(s), starting with 0002_two")
+
+	mock.ExpectQuery("SELECT version, applied_at FROM schema_migrations").
+		WillReturnError(&pq.Error{Code: "42P01", Message: `relation "schema_migrations" does not exist`})
+	statuses, err := m.Status(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, statuses, 2)
+	for _, st := range statuses {
+		assert.Nil(t, st.AppliedAt)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
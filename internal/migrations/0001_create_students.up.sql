@@ -0,0 +1,14 @@
+-- IF NOT EXISTS lets databases whose tables were created by hand adopt the
+-- migration history without failing.
+CREATE TABLE IF NOT EXISTS students (
+    id    SERIAL PRIMARY KEY,
+    name  TEXT    NOT NULL,
+    age   INTEGER NOT NULL,
+    email TEXT    NOT NULL
+);
+
+-- Hand-made tables had the same columns but not necessarily the NOT NULLs.
+ALTER TABLE students
+    ALTER COLUMN name SET NOT NULL,
+    ALTER COLUMN age SET NOT NULL,
+    ALTER COLUMN email SET NOT NULL;
@@ -0,0 +1,42 @@
+-- IF NOT EXISTS lets databases whose tables were created by hand adopt the
+-- migration history without failing.
+CREATE TABLE IF NOT EXISTS users (
+    id            SERIAL PRIMARY KEY,
+    name          TEXT NOT NULL,
+    email         TEXT NOT NULL UNIQUE,
+    -- bcrypt hash; NULL for accounts created without a password
+    password_hash TEXT,
+    role          TEXT NOT NULL DEFAULT 'student'
+        CHECK (role IN ('admin', 'teacher', 'student')),
+    -- links a student account to its own student record
+    student_id    INTEGER REFERENCES students (id) ON DELETE SET NULL
+);
+
+-- A hand-made users table only has id, name and email, which CREATE TABLE IF
+-- NOT EXISTS leaves alone, so bring it up to the definition above.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS password_hash TEXT,
+    ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'student',
+    ADD COLUMN IF NOT EXISTS student_id INTEGER,
+    ALTER COLUMN name SET NOT NULL,
+    ALTER COLUMN email SET NOT NULL;
+
+-- Postgres has no ADD CONSTRAINT IF NOT EXISTS. The names match the ones
+-- generated for the inline constraints above.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint
+                   WHERE conrelid = 'users'::regclass AND conname = 'users_email_key') THEN
+        ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint
+                   WHERE conrelid = 'users'::regclass AND conname = 'users_role_check') THEN
+        ALTER TABLE users ADD CONSTRAINT users_role_check
+            CHECK (role IN ('admin', 'teacher', 'student'));
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint
+                   WHERE conrelid = 'users'::regclass AND conname = 'users_student_id_fkey') THEN
+        ALTER TABLE users ADD CONSTRAINT users_student_id_fkey
+            FOREIGN KEY (student_id) REFERENCES students (id) ON DELETE SET NULL;
+    END IF;
+END $$;
@@ -0,0 +1,14 @@
+-- One row per issued refresh token. Tokens rotated from the same login share
+-- a family_id; revoking the family ends the session.
+CREATE TABLE refresh_tokens (
+    id          BIGSERIAL   PRIMARY KEY,
+    user_id     INTEGER     NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    family_id   TEXT        NOT NULL,
+    token_hash  TEXT        NOT NULL UNIQUE,
+    expires_at  TIMESTAMPTZ NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    revoked_at  TIMESTAMPTZ,
+    replaced_by BIGINT      REFERENCES refresh_tokens (id)
+);
+
+CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens (family_id);
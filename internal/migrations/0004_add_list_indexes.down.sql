@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS users_name_id_idx;
+DROP INDEX IF EXISTS students_age_id_idx;
+DROP INDEX IF EXISTS students_name_id_idx;
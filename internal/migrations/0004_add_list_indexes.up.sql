@@ -0,0 +1,4 @@
+-- Support keyset pagination on the common sort orders.
+CREATE INDEX students_name_id_idx ON students (name, id);
+CREATE INDEX students_age_id_idx ON students (age, id);
+CREATE INDEX users_name_id_idx ON users (name, id);
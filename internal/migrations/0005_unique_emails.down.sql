@@ -0,0 +1,3 @@
+ALTER TABLE students DROP CONSTRAINT IF EXISTS students_age_check;
+DROP INDEX IF EXISTS users_email_lower_key;
+DROP INDEX IF EXISTS students_email_key;
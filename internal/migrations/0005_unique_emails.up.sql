@@ -0,0 +1,7 @@
+-- Emails identify people, so they must be unique regardless of case. Creating
+-- the indexes fails if duplicates already exist; merge those rows first.
+CREATE UNIQUE INDEX students_email_key ON students (lower(email));
+CREATE UNIQUE INDEX users_email_lower_key ON users (lower(email));
+
+-- NOT VALID enforces the rule for new writes without rejecting legacy rows.
+ALTER TABLE students ADD CONSTRAINT students_age_check CHECK (age BETWEEN 1 AND 150) NOT VALID;
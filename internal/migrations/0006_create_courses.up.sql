@@ -0,0 +1,22 @@
+CREATE TABLE courses (
+    id       SERIAL  PRIMARY KEY,
+    code     TEXT    NOT NULL,
+    title    TEXT    NOT NULL,
+    credits  INTEGER NOT NULL CONSTRAINT courses_credits_check CHECK (credits BETWEEN 0 AND 30),
+    capacity INTEGER NOT NULL CONSTRAINT courses_capacity_check CHECK (capacity > 0),
+    -- academic term the course runs in, e.g. 2025-fall
+    term     TEXT    NOT NULL
+);
+
+-- A course code is offered at most once per term.
+CREATE UNIQUE INDEX courses_code_term_key ON courses (lower(code), term);
+CREATE INDEX courses_code_id_idx ON courses (code, id);
+
+CREATE TABLE enrollments (
+    student_id  INTEGER     NOT NULL REFERENCES students (id) ON DELETE CASCADE,
+    course_id   INTEGER     NOT NULL REFERENCES courses (id) ON DELETE CASCADE,
+    enrolled_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT enrollments_pkey PRIMARY KEY (student_id, course_id)
+);
+
+CREATE INDEX enrollments_course_id_idx ON enrollments (course_id);
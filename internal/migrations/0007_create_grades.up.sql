@@ -0,0 +1,15 @@
+CREATE TABLE grades (
+    id         SERIAL       PRIMARY KEY,
+    student_id INTEGER      NOT NULL,
+    course_id  INTEGER      NOT NULL,
+    -- free-form assessment name, e.g. midterm or final-exam
+    assessment TEXT         NOT NULL,
+    score      NUMERIC(5,2) NOT NULL CONSTRAINT grades_score_check CHECK (score BETWEEN 0 AND 100),
+    weight     NUMERIC(5,2) NOT NULL DEFAULT 1 CONSTRAINT grades_weight_check CHECK (weight > 0),
+    graded_at  TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    -- Only enrolled students can be graded, and dropping the enrollment
+    -- drops its grades.
+    CONSTRAINT grades_enrollment_fkey FOREIGN KEY (student_id, course_id)
+        REFERENCES enrollments (student_id, course_id) ON DELETE CASCADE,
+    CONSTRAINT grades_assessment_key UNIQUE (student_id, course_id, assessment)
+);
@@ -0,0 +1,11 @@
+CREATE TABLE attendance (
+    student_id  INTEGER     NOT NULL REFERENCES students (id) ON DELETE CASCADE,
+    day         DATE        NOT NULL,
+    status      TEXT        NOT NULL CONSTRAINT attendance_status_check
+        CHECK (status IN ('present', 'absent', 'late', 'excused')),
+    recorded_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT attendance_pkey PRIMARY KEY (student_id, day)
+);
+
+-- Reports scan a date range across all students.
+CREATE INDEX attendance_day_idx ON attendance (day);
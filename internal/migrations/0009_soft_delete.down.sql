@@ -0,0 +1,14 @@
+-- Rows that are still deleted cannot be told apart once the column is gone,
+-- so they are removed rather than brought back.
+DELETE FROM users WHERE deleted_at IS NOT NULL;
+DELETE FROM students WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS users_deleted_at_idx;
+DROP INDEX IF EXISTS students_deleted_at_idx;
+DROP INDEX IF EXISTS users_email_lower_key;
+DROP INDEX IF EXISTS students_email_key;
+CREATE UNIQUE INDEX students_email_key ON students (lower(email));
+CREATE UNIQUE INDEX users_email_lower_key ON users (lower(email));
+
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE students DROP COLUMN IF EXISTS deleted_at;
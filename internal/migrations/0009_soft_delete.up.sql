@@ -0,0 +1,17 @@
+-- Deleted students and users keep their rows until the purge job removes
+-- them, so a mistaken delete can be undone.
+ALTER TABLE students ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMPTZ;
+
+-- Emails only need to be unique among records that are not deleted, so a
+-- deleted person can sign up again. The case-sensitive constraint from the
+-- users table definition would still block that.
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+DROP INDEX students_email_key;
+DROP INDEX users_email_lower_key;
+CREATE UNIQUE INDEX students_email_key ON students (lower(email)) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX users_email_lower_key ON users (lower(email)) WHERE deleted_at IS NULL;
+
+-- The purge job looks for rows deleted before a cutoff.
+CREATE INDEX students_deleted_at_idx ON students (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX users_deleted_at_idx ON users (deleted_at) WHERE deleted_at IS NOT NULL;
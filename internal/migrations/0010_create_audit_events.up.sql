@@ -0,0 +1,20 @@
+CREATE TABLE audit_events (
+    id          BIGSERIAL   PRIMARY KEY,
+    occurred_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    -- The acting user is copied rather than referenced so the trail
+    -- outlives purged accounts.
+    actor_id    INTEGER,
+    actor_email TEXT,
+    action      TEXT        NOT NULL CONSTRAINT audit_events_action_check
+        CHECK (action IN ('create', 'update', 'delete', 'restore')),
+    entity_type TEXT        NOT NULL,
+    entity_id   INTEGER     NOT NULL,
+    -- {"field": {"before": ..., "after": ...}} for every changed field
+    changes     JSONB       NOT NULL,
+    request_id  TEXT,
+    client_ip   TEXT        NOT NULL
+);
+
+-- The audit endpoint filters by entity or by actor and pages by id.
+CREATE INDEX audit_events_entity_idx ON audit_events (entity_type, entity_id, id);
+CREATE INDEX audit_events_actor_idx ON audit_events (actor_id, id);
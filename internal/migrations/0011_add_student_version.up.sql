@@ -0,0 +1,3 @@
+-- Every write to a student bumps its version, which clients see as the ETag
+-- and send back in If-Match so concurrent edits are detected.
+ALTER TABLE students ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
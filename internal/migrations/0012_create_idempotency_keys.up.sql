@@ -0,0 +1,18 @@
+-- Responses to requests sent with an Idempotency-Key, replayed when the client
+-- retries. Keys are scoped to the user who sent them.
+CREATE TABLE idempotency_keys (
+    user_id      INTEGER     NOT NULL,
+    key          TEXT        NOT NULL,
+    -- SHA-256 of the method, path and body, to spot a key reused for a
+    -- different request
+    request_hash TEXT        NOT NULL,
+    -- NULL until the first request has finished
+    status_code  INTEGER,
+    headers      JSONB,
+    body         BYTEA,
+    expires_at   TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (user_id, key)
+);
+
+-- The purge removes expired keys.
+CREATE INDEX idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
@@ -0,0 +1,12 @@
+-- Token buckets of the rate limiter when replicas share their limits. The
+-- table is unlogged: after a crash the buckets start full again, which is
+-- cheaper than logging every request.
+CREATE UNLOGGED TABLE rate_limit_buckets (
+    key        TEXT             PRIMARY KEY,
+    tokens     DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMPTZ      NOT NULL,
+    -- A bucket that has refilled is dropped; a missing one starts full.
+    full_at    TIMESTAMPTZ      NOT NULL
+);
+
+CREATE INDEX rate_limit_buckets_full_at_idx ON rate_limit_buckets (full_at);
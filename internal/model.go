@@ -1,11 +1,40 @@
 package internal
 
+import "time"
+
 type HealthResponse struct {
 	Message string `json:"message" example:"API is healthy"`
 }
 
+// ReadinessResponse is the body of GET /health/ready. Status is "ok",
+// "degraded" when only non-critical checks fail, "unavailable" or
+// "shutting_down".
+type ReadinessResponse struct {
+	Status string            `json:"status" example:"ok"`
+	Checks []ComponentHealth `json:"checks"`
+}
+
+type ComponentHealth struct {
+	Name      string  `json:"name" example:"database"`
+	Status    string  `json:"status" example:"ok"`
+	Critical  bool    `json:"critical" example:"true"`
+	LatencyMs float64 `json:"latency_ms" example:"1.25"`
+	Error     string  `json:"error,omitempty"`
+}
+
 type ErrorResponse struct {
 	Error string `json:"error" example:"internal server error"`
+	// Code is a machine-readable error code, e.g. "validation_failed".
+	Code string `json:"code,omitempty" example:"validation_failed"`
+	// Details lists the offending fields of a rejected payload.
+	Details []FieldError `json:"details,omitempty"`
+}
+
+// FieldError describes one field that failed validation.
+type FieldError struct {
+	Field   string `json:"field" example:"email"`
+	Rule    string `json:"rule" example:"email"`
+	Message string `json:"message" example:"email must be a valid email address"`
 }
 
 type Student struct {
@@ -13,29 +42,307 @@ type Student struct {
 	Name  string `json:"name" example:"John Doe"`
 	Age   int    `json:"age" example:"20"`
 	Email string `json:"email" example:"john@example.com"`
+	// DeletedAt is set once the student is deleted; deleted students are only
+	// listed for admins who ask for them.
+	DeletedAt *time.Time `json:"deleted_at,omitempty" example:"2025-09-01T12:00:00Z"`
+	// Version counts the writes to the student; the ETag is derived from it.
+	Version int `json:"version" example:"3"`
+}
+
+// ImportResult reports the outcome of a bulk student import.
+type ImportResult struct {
+	DryRun bool `json:"dry_run" example:"false"`
+	// Rows is the number of data rows read from the upload.
+	Rows int `json:"rows" example:"250"`
+	// Imported is the number of students created; it stays 0 for dry runs
+	// and rejected uploads.
+	Imported int              `json:"imported" example:"250"`
+	Errors   []ImportRowError `json:"errors"`
+}
+
+// ImportRowError lists the problems found in one row of an import.
+type ImportRowError struct {
+	// Line is the row's line number in the upload; a CSV header is line 1.
+	Line   int          `json:"line" example:"7"`
+	Errors []FieldError `json:"errors"`
+}
+
+// StudentListResponse is one page of GET /students.
+type StudentListResponse struct {
+	Data []Student `json:"data"`
+	// NextCursor is passed back as ?cursor= to fetch the following page;
+	// empty on the last page.
+	NextCursor string `json:"next_cursor,omitempty" example:"eyJzIjoiaWQiLCJ2IjpbNTBdfQ"`
+	// Total counts every row matching the filters, across all pages.
+	Total int `json:"total" example:"1234"`
 }
 
 // StudentCreateRequest represents the payload to create a new student.
 type StudentCreateRequest struct {
-	Name  string `json:"name" example:"John Doe"`
-	Age   int    `json:"age" example:"20"`
-	Email string `json:"email" example:"john@example.com"`
+	Name  string `json:"name" binding:"required,max=100" example:"John Doe"`
+	Age   int    `json:"age" binding:"required,min=1,max=150" example:"20"`
+	Email string `json:"email" binding:"required,email,max=254" example:"john@example.com"`
 }
 
 // StudentUpdateRequest represents the payload to update an existing student.
 type StudentUpdateRequest struct {
-	Name  string `json:"name" example:"John Doe"`
-	Age   int    `json:"age" example:"21"`
-	Email string `json:"email" example:"john.new@example.com"`
+	Name  string `json:"name" binding:"required,max=100" example:"John Doe"`
+	Age   int    `json:"age" binding:"required,min=1,max=150" example:"21"`
+	Email string `json:"email" binding:"required,email,max=254" example:"john.new@example.com"`
+}
+
+// Course is a class offered in a term.
+type Course struct {
+	ID       int    `json:"id" example:"1"`
+	Code     string `json:"code" example:"CS101"`
+	Title    string `json:"title" example:"Introduction to Programming"`
+	Credits  int    `json:"credits" example:"4"`
+	Capacity int    `json:"capacity" example:"30"`
+	Term     string `json:"term" example:"2025-fall"`
+	// Enrolled is the number of students currently enrolled.
+	Enrolled int `json:"enrolled" example:"12"`
+}
+
+// CourseListResponse is one page of GET /courses.
+type CourseListResponse struct {
+	Data       []Course `json:"data"`
+	NextCursor string   `json:"next_cursor,omitempty" example:"eyJzIjoiaWQiLCJ2IjpbNTBdfQ"`
+	Total      int      `json:"total" example:"8"`
+}
+
+// CourseRequest is the payload to create or replace a course.
+type CourseRequest struct {
+	Code     string `json:"code" binding:"required,max=20" example:"CS101"`
+	Title    string `json:"title" binding:"required,max=200" example:"Introduction to Programming"`
+	Credits  int    `json:"credits" binding:"min=0,max=30" example:"4"`
+	Capacity int    `json:"capacity" binding:"required,min=1,max=1000" example:"30"`
+	Term     string `json:"term" binding:"required,max=20" example:"2025-fall"`
+}
+
+// EnrollmentRequest enrolls a student in a course.
+type EnrollmentRequest struct {
+	CourseID int `json:"course_id" binding:"required,min=1" example:"1"`
+}
+
+// Enrollment links a student to a course.
+type Enrollment struct {
+	StudentID  int       `json:"student_id" example:"1"`
+	CourseID   int       `json:"course_id" example:"1"`
+	EnrolledAt time.Time `json:"enrolled_at" example:"2025-09-01T09:00:00Z"`
+}
+
+// Grade is a student's score on one assessment of a course.
+type Grade struct {
+	ID         int    `json:"id" example:"1"`
+	StudentID  int    `json:"student_id" example:"1"`
+	CourseID   int    `json:"course_id" example:"1"`
+	Assessment string `json:"assessment" example:"final-exam"`
+	// Score is a percentage from 0 to 100.
+	Score float64 `json:"score" example:"88.5"`
+	// Weight is the assessment's share of the course score relative to the
+	// course's other assessments.
+	Weight   float64   `json:"weight" example:"0.4"`
+	GradedAt time.Time `json:"graded_at" example:"2025-12-15T14:00:00Z"`
+}
+
+// GradeRequest records or replaces the grade for one assessment.
+type GradeRequest struct {
+	CourseID   int      `json:"course_id" binding:"required,min=1" example:"1"`
+	Assessment string   `json:"assessment" binding:"required,max=50" example:"final-exam"`
+	Score      *float64 `json:"score" binding:"required,min=0,max=100" example:"88.5"`
+	// Weight defaults to 1 when omitted.
+	Weight float64 `json:"weight,omitempty" binding:"omitempty,gt=0,max=100" example:"0.4"`
+}
+
+// CourseGrades is a course together with a student's grades in it.
+type CourseGrades struct {
+	Course Course
+	Grades []Grade
+}
+
+// Transcript lists a student's graded courses by term with their GPAs.
+type Transcript struct {
+	StudentID int              `json:"student_id" example:"1"`
+	Terms     []TermTranscript `json:"terms"`
+	// Credits is the number of credits counted towards the GPA.
+	Credits int `json:"credits" example:"24"`
+	// CumulativeGPA is null until a course with credits has been graded.
+	CumulativeGPA *float64 `json:"cumulative_gpa" example:"3.45"`
+}
+
+// TermTranscript is one term of a transcript.
+type TermTranscript struct {
+	Term    string             `json:"term" example:"2025-fall"`
+	Courses []TranscriptCourse `json:"courses"`
+	Credits int                `json:"credits" example:"12"`
+	GPA     *float64           `json:"gpa" example:"3.6"`
+}
+
+// TranscriptCourse is the final grade of one course.
+type TranscriptCourse struct {
+	CourseID int    `json:"course_id" example:"1"`
+	Code     string `json:"code" example:"CS101"`
+	Title    string `json:"title" example:"Introduction to Programming"`
+	Credits  int    `json:"credits" example:"4"`
+	// Score is the weighted mean of the assessment scores.
+	Score       float64 `json:"score" example:"88.5"`
+	Letter      string  `json:"letter" example:"B+"`
+	Points      float64 `json:"points" example:"3.3"`
+	Assessments []Grade `json:"assessments"`
+}
+
+// AttendanceStatus is how a student attended one school day.
+type AttendanceStatus string
+
+const (
+	AttendancePresent AttendanceStatus = "present"
+	AttendanceAbsent  AttendanceStatus = "absent"
+	AttendanceLate    AttendanceStatus = "late"
+	AttendanceExcused AttendanceStatus = "excused"
+)
+
+// AttendanceEntry is one student's status in an attendance submission.
+type AttendanceEntry struct {
+	StudentID int              `json:"student_id" binding:"required,min=1" example:"1"`
+	Status    AttendanceStatus `json:"status" binding:"required,oneof=present absent late excused" example:"present"`
+}
+
+// AttendanceRequest records a class's attendance for one day. Entries replace
+// any status already recorded for that student and day.
+type AttendanceRequest struct {
+	Date    string            `json:"date" binding:"required,datetime=2006-01-02" example:"2025-09-01"`
+	Records []AttendanceEntry `json:"records" binding:"required,min=1,max=500,unique=StudentID,dive"`
+}
+
+// AttendanceBatchResponse acknowledges an attendance submission.
+type AttendanceBatchResponse struct {
+	Date     string `json:"date" example:"2025-09-01"`
+	Recorded int    `json:"recorded" example:"28"`
+}
+
+// AttendanceSummary counts a student's recorded days by status.
+type AttendanceSummary struct {
+	StudentID int `json:"student_id" example:"1"`
+	Days      int `json:"days" example:"20"`
+	Present   int `json:"present" example:"17"`
+	Late      int `json:"late" example:"1"`
+	Absent    int `json:"absent" example:"1"`
+	Excused   int `json:"excused" example:"1"`
+	// Rate is the share of non-excused days the student was present or
+	// late, from 0 to 1; null when there are none.
+	Rate *float64 `json:"rate" example:"0.9474"`
+}
+
+// StudentAttendance is one student's attendance over a date range.
+type StudentAttendance struct {
+	From string `json:"from" example:"2025-09-01"`
+	To   string `json:"to" example:"2025-09-30"`
+	AttendanceSummary
+}
+
+// AttendanceReport lists the attendance of every student with records in
+// the date range.
+type AttendanceReport struct {
+	From string              `json:"from" example:"2025-09-01"`
+	To   string              `json:"to" example:"2025-09-30"`
+	Data []AttendanceSummary `json:"data"`
 }
 
 type User struct {
 	ID    int    `json:"id" example:"1"`
 	Name  string `json:"name" example:"John Doe"`
 	Email string `json:"email" example:"john@example.com"`
+	Role  Role   `json:"role" example:"student"`
+	// StudentID links a student account to its student record.
+	StudentID *int `json:"student_id,omitempty" example:"1"`
+	// DeletedAt is set once the user is deleted.
+	DeletedAt *time.Time `json:"deleted_at,omitempty" example:"2025-09-01T12:00:00Z"`
+}
+
+// UserListResponse is one page of GET /users.
+type UserListResponse struct {
+	Data       []User `json:"data"`
+	NextCursor string `json:"next_cursor,omitempty" example:"eyJzIjoiaWQiLCJ2IjpbNTBdfQ"`
+	Total      int    `json:"total" example:"42"`
 }
 
 type UserCreateRequest struct {
-	Name  string `json:"name" example:"John Doe"`
-	Email string `json:"email" example:"john@example.com"`
+	Name  string `json:"name" binding:"required,max=100" example:"John Doe"`
+	Email string `json:"email" binding:"required,email,max=254" example:"john@example.com"`
+	// Role defaults to "student" when omitted.
+	Role      Role   `json:"role,omitempty" binding:"omitempty,role" example:"teacher"`
+	StudentID *int   `json:"student_id,omitempty" binding:"omitempty,min=1" example:"1"`
+	Password  string `json:"password,omitempty" binding:"omitempty,min=8,max=72" example:"s3cret-passw0rd"`
+}
+
+// SignupRequest represents the payload to register a new account.
+type SignupRequest struct {
+	Name     string `json:"name" binding:"required,max=100" example:"John Doe"`
+	Email    string `json:"email" binding:"required,email,max=254" example:"john@example.com"`
+	Password string `json:"password" binding:"required,min=8,max=72" example:"s3cret-passw0rd"`
+}
+
+// LoginRequest represents the credentials used to obtain an access token.
+type LoginRequest struct {
+	Email    string `json:"email" binding:"required" example:"john@example.com"`
+	Password string `json:"password" binding:"required" example:"s3cret-passw0rd"`
+}
+
+// RefreshRequest carries the refresh token to exchange for a new token pair.
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required" example:"3q2-7wX9..."`
+}
+
+// TokenResponse is returned after a successful signup, login or refresh.
+type TokenResponse struct {
+	AccessToken  string `json:"access_token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	TokenType    string `json:"token_type" example:"Bearer"`
+	ExpiresIn    int    `json:"expires_in" example:"900"`
+	RefreshToken string `json:"refresh_token" example:"3q2-7wX9..."`
+}
+
+// AuditAction is the kind of change an audit event records.
+type AuditAction string
+
+const (
+	AuditCreate  AuditAction = "create"
+	AuditUpdate  AuditAction = "update"
+	AuditDelete  AuditAction = "delete"
+	AuditRestore AuditAction = "restore"
+)
+
+// Entity types named in audit events.
+const (
+	AuditEntityStudent = "student"
+	AuditEntityUser    = "user"
+)
+
+// FieldChange is a field's value before and after a change; null on the
+// side where the field did not exist.
+type FieldChange struct {
+	Before any `json:"before" swaggertype:"string" example:"john@example.com"`
+	After  any `json:"after" swaggertype:"string" example:"john.doe@example.com"`
+}
+
+// AuditEvent records who changed which record, when and how.
+type AuditEvent struct {
+	ID         int64     `json:"id" example:"981"`
+	OccurredAt time.Time `json:"occurred_at" example:"2025-09-01T12:00:00Z"`
+	// ActorID is the user who made the change; null for anonymous callers.
+	ActorID    *int        `json:"actor_id" example:"1"`
+	ActorEmail string      `json:"actor_email,omitempty" example:"admin@example.com"`
+	Action     AuditAction `json:"action" enums:"create,update,delete,restore" example:"update"`
+	EntityType string      `json:"entity_type" enums:"student,user" example:"student"`
+	EntityID   int         `json:"entity_id" example:"42"`
+	// Changes lists every field whose value differs, keyed by field name.
+	Changes   map[string]FieldChange `json:"changes"`
+	RequestID string                 `json:"request_id,omitempty" example:"b6f3c1a2-4d5e-4f60-9a7b-8c9d0e1f2a3b"`
+	ClientIP  string                 `json:"client_ip" example:"203.0.113.7"`
+}
+
+// AuditListResponse is one page of GET /audit.
+type AuditListResponse struct {
+	Data       []AuditEvent `json:"data"`
+	NextCursor string       `json:"next_cursor,omitempty" example:"eyJzIjoiaWQiLCJ2IjpbNTBdfQ"`
+	Total      int          `json:"total" example:"310"`
 }
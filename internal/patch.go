@@ -0,0 +1,105 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+)
+
+const (
+	mergePatchContentType = "application/merge-patch+json"
+	jsonPatchContentType  = "application/json-patch+json"
+)
+
+var (
+	// errUnsupportedPatch is returned for content types that are not a
+	// patch format we understand.
+	errUnsupportedPatch = errors.New("unsupported patch content type")
+	// errMalformedPatch is returned when the patch document is not valid.
+	errMalformedPatch = errors.New("malformed patch document")
+	// errPatchConflict is returned when a JSON Patch "test" operation fails.
+	errPatchConflict = errors.New("patch test operation failed")
+)
+
+// applyPatch applies a JSON Merge Patch (RFC 7396) or JSON Patch (RFC 6902)
+// document to doc, chosen by content type. Plain application/json is treated
+// as a merge patch.
+func applyPatch(contentType string, doc, patch []byte) ([]byte, error) {
+	switch contentType {
+	case mergePatchContentType, "application/json":
+		if !json.Valid(patch) || bytes.HasPrefix(bytes.TrimSpace(patch), []byte("[")) {
+			return nil, errMalformedPatch
+		}
+		out, err := jsonpatch.MergePatch(doc, patch)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errMalformedPatch, err)
+		}
+		return out, nil
+	case jsonPatchContentType:
+		ops, err := jsonpatch.DecodePatch(patch)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errMalformedPatch, err)
+		}
+		out, err := ops.Apply(doc)
+		if errors.Is(err, jsonpatch.ErrTestFailed) {
+			return nil, errPatchConflict
+		} else if err != nil {
+			return nil, fmt.Errorf("%w: %v", errMalformedPatch, err)
+		}
+		return out, nil
+	}
+	return nil, errUnsupportedPatch
+}
+
+// invalidPatchResult is returned when a patch applies but leaves a student
+// that a PUT body could not describe.
+type invalidPatchResult struct{ err error }
+
+func (e invalidPatchResult) Error() string { return e.err.Error() }
+func (e invalidPatchResult) Unwrap() error { return e.err }
+
+// applyStudentPatch applies a patch document to the writable fields of s, so
+// "id" is unknown to it and cannot be changed. The result must pass the same
+// rules as a PUT body.
+func applyStudentPatch(contentType string, s Student, patch []byte) (StudentUpdateRequest, error) {
+	doc, _ := json.Marshal(StudentUpdateRequest{Name: s.Name, Age: s.Age, Email: s.Email})
+	patched, err := applyPatch(contentType, doc, patch)
+	if err != nil {
+		return StudentUpdateRequest{}, err
+	}
+	var result StudentUpdateRequest
+	err = decodeStrict(patched, &result)
+	if err == nil {
+		err = validateStruct(&result)
+	}
+	if err != nil {
+		return StudentUpdateRequest{}, invalidPatchResult{err}
+	}
+	return result, nil
+}
+
+// decodeStrict unmarshals data into v, rejecting unknown fields so a patch
+// cannot touch attributes that are not writable, such as id.
+func decodeStrict(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// diff returns the fields of r that differ from s.
+func (r StudentUpdateRequest) diff(s Student) StudentPatch {
+	var p StudentPatch
+	if r.Name != s.Name {
+		p.Name = &r.Name
+	}
+	if r.Age != s.Age {
+		p.Age = &r.Age
+	}
+	if r.Email != s.Email {
+		p.Email = &r.Email
+	}
+	return p
+}
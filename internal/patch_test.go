@@ -0,0 +1,103 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func patchStudent(t *testing.T, h *Handler, contentType, body string) (int, Student) {
+	t.Helper()
+	r := testRouter(t, h, testAdmin, RouteMiddleware{})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPatch, "/students/1", strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	r.ServeHTTP(w, req)
+
+	var s Student
+	_ = json.Unmarshal(w.Body.Bytes(), &s)
+	return w.Code, s
+}
+
+func seededPatchHandler(t *testing.T) *Handler {
+	h, repos := newMemoryHandler(t)
+	_, err := repos.Students.Create(context.Background(), Student{Name: "John Doe", Age: 20, Email: "john@example.com"})
+	assert.NoError(t, err)
+	return h
+}
+
+func TestPatchStudentMergePatchKeepsOtherFields(t *testing.T) {
+	h := seededPatchHandler(t)
+
+	code, s := patchStudent(t, h, mergePatchContentType, `{"age": 21}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, Student{ID: 1, Name: "John Doe", Age: 21, Email: "john@example.com", Version: 2}, s)
+}
+
+func TestPatchStudentJSONPatch(t *testing.T) {
+	h := seededPatchHandler(t)
+
+	ops := `[{"op":"test","path":"/age","value":20},{"op":"replace","path":"/email","value":"jd@example.com"}]`
+	code, s := patchStudent(t, h, jsonPatchContentType, ops)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "jd@example.com", s.Email)
+	assert.Equal(t, 20, s.Age)
+
+	// The age is no longer what the client expected.
+	code, _ = patchStudent(t, h, jsonPatchContentType, `[{"op":"test","path":"/age","value":99}]`)
+	assert.Equal(t, http.StatusConflict, code)
+}
+
+func TestPatchStudentRejectsInvalidResults(t *testing.T) {
+	h := seededPatchHandler(t)
+
+	cases := map[string]struct {
+		contentType string
+		body        string
+		want        int
+	}{
+		"null removes required name": {mergePatchContentType, `{"name": null}`, http.StatusUnprocessableEntity},
+		"age out of range":           {mergePatchContentType, `{"age": 0}`, http.StatusUnprocessableEntity},
+		"bad email":                  {mergePatchContentType, `{"email": "nope"}`, http.StatusUnprocessableEntity},
+		"id is not writable":         {mergePatchContentType, `{"id": 5}`, http.StatusUnprocessableEntity},
+		"wrong type":                 {mergePatchContentType, `{"age": "old"}`, http.StatusUnprocessableEntity},
+		"array as merge patch":       {mergePatchContentType, `[]`, http.StatusBadRequest},
+		"missing path":               {jsonPatchContentType, `[{"op":"remove","path":"/nickname"}]`, http.StatusBadRequest},
+		"unsupported content type":   {"text/plain", `age=21`, http.StatusUnsupportedMediaType},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			code, _ := patchStudent(t, h, tc.contentType, tc.body)
+			assert.Equal(t, tc.want, code)
+		})
+	}
+
+	_, s := patchStudent(t, h, mergePatchContentType, `{}`)
+	assert.Equal(t, Student{ID: 1, Name: "John Doe", Age: 20, Email: "john@example.com", Version: 1}, s)
+}
+
+func TestPostgresPatchUpdatesOnlySuppliedFields(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	repos := NewPostgresRepositories(&Db{db: mockDB})
+
+	age, email := 21, "jd@example.com"
+	mock.ExpectQuery(regexp.QuoteMeta("UPDATE students SET age = $1, email = $2, version = version + 1 WHERE id = $3 AND deleted_at IS NULL RETURNING id, name, age, email, deleted_at, version")).
+		WithArgs(21, "jd@example.com", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "age", "email", "deleted_at", "version"}).AddRow(1, "John Doe", 21, "jd@example.com", nil, 2))
+
+	s, err := repos.Students.Patch(context.Background(), 1, StudentPatch{Age: &age, Email: &email})
+	assert.NoError(t, err)
+	assert.Equal(t, "John Doe", s.Name)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
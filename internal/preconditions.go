@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetRequireIfMatch makes If-Match mandatory on student writes, so clients
+// cannot overwrite a change they have not seen.
+func (h *Handler) SetRequireIfMatch(require bool) {
+	h.requireIfMatch = require
+}
+
+// studentETag is the strong entity tag of the student's current version.
+func studentETag(s Student) string {
+	return `"` + strconv.Itoa(s.Version) + `"`
+}
+
+// etagMatches reports whether the If-Match or If-None-Match header lists
+// etag. If-Match compares strongly, so weak tags never match it; If-None-Match
+// compares weakly and ignores the W/ prefix.
+func etagMatches(header, etag string, weak bool) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+		if strings.HasPrefix(tag, "W/") {
+			if !weak {
+				continue
+			}
+			tag = strings.TrimPrefix(tag, "W/")
+		}
+		if tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// preconditionPresent answers 428 and returns false when If-Match is
+// required but missing.
+func (h *Handler) preconditionPresent(c *gin.Context) bool {
+	if !h.requireIfMatch || c.GetHeader("If-Match") != "" {
+		return true
+	}
+	h.log(c).Warn("Missing If-Match on ", c.Request.Method, " ", c.Request.URL.Path)
+	c.JSON(http.StatusPreconditionRequired, ErrorResponse{Error: "If-Match is required; send the ETag of the student"})
+	return false
+}
+
+// ifMatchVersion evaluates If-Match against the student as stored. It
+// returns the version the write must still find, or 0 when the request has
+// no If-Match and the write is unconditional.
+func ifMatchVersion(c *gin.Context, current Student) (int, error) {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		return 0, nil
+	}
+	if !etagMatches(header, studentETag(current), false) {
+		return 0, ErrVersionMismatch
+	}
+	return current.Version, nil
+}
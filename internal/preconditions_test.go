@@ -0,0 +1,123 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// doConditional sends a request with one precondition header and returns the
+// recorder so callers can inspect the ETag.
+func doConditional(r *gin.Engine, method, path, header, etag, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if header != "" {
+		req.Header.Set(header, etag)
+	}
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestGetStudentETagAndIfNoneMatch(t *testing.T) {
+	h, repos := newMemoryHandler(t)
+	_, _ = repos.Students.Create(context.Background(), Student{Name: "John Doe", Age: 20, Email: "john@example.com"})
+	r := testRouter(t, h, testAdmin, RouteMiddleware{})
+
+	w := doConditional(r, http.MethodGet, "/students/1", "", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"1"`, w.Header().Get("ETag"))
+
+	w = doConditional(r, http.MethodGet, "/students/1", "If-None-Match", `"0", W/"1"`, "")
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.Equal(t, `"1"`, w.Header().Get("ETag"))
+
+	w = doConditional(r, http.MethodGet, "/students/1", "If-None-Match", `"0"`, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestStudentWritesHonourIfMatch(t *testing.T) {
+	h, repos := newMemoryHandler(t)
+	_, _ = repos.Students.Create(context.Background(), Student{Name: "John Doe", Age: 20, Email: "john@example.com"})
+	r := testRouter(t, h, testAdmin, RouteMiddleware{})
+	body := `{"name":"John Doe","age":21,"email":"john@example.com"}`
+
+	w := doConditional(r, http.MethodPut, "/students/1", "If-Match", `"1"`, body)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+
+	// The first write moved the student on, so a client still holding "1"
+	// is refused by every kind of write.
+	assert.Equal(t, http.StatusPreconditionFailed, doConditional(r, http.MethodPut, "/students/1", "If-Match", `"1"`, body).Code)
+	assert.Equal(t, http.StatusPreconditionFailed, doConditional(r, http.MethodPatch, "/students/1", "If-Match", `"1"`, `{"age":22}`).Code)
+	assert.Equal(t, http.StatusPreconditionFailed, doConditional(r, http.MethodDelete, "/students/1", "If-Match", `"1"`, "").Code)
+	// Weak tags never satisfy If-Match.
+	assert.Equal(t, http.StatusPreconditionFailed, doConditional(r, http.MethodPatch, "/students/1", "If-Match", `W/"2"`, `{"age":22}`).Code)
+
+	w = doConditional(r, http.MethodPatch, "/students/1", "If-Match", `"2"`, `{"age":22}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+
+	assert.Equal(t, http.StatusNoContent, doConditional(r, http.MethodDelete, "/students/1", "If-Match", "*", "").Code)
+	assert.Equal(t, http.StatusNotFound, doConditional(r, http.MethodPut, "/students/1", "If-Match", `"4"`, body).Code)
+}
+
+func TestRequireIfMatch(t *testing.T) {
+	h, repos := newMemoryHandler(t)
+	_, _ = repos.Students.Create(context.Background(), Student{Name: "John Doe", Age: 20, Email: "john@example.com"})
+	h.SetRequireIfMatch(true)
+	r := testRouter(t, h, testAdmin, RouteMiddleware{})
+
+	assert.Equal(t, http.StatusPreconditionRequired, doConditional(r, http.MethodPatch, "/students/1", "", "", `{"age":21}`).Code)
+	assert.Equal(t, http.StatusPreconditionRequired, doConditional(r, http.MethodDelete, "/students/1", "", "", "").Code)
+	assert.Equal(t, http.StatusOK, doConditional(r, http.MethodPatch, "/students/1", "If-Match", `"1"`, `{"age":21}`).Code)
+}
+
+func TestPostgresConditionalUpdateReportsVersionMismatch(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+	repo := NewPostgresRepositories(&Db{db: mockDB}).Students
+
+	mock.ExpectQuery(`UPDATE students SET name = \$1, age = \$2, email = \$3, version = version \+ 1 WHERE id = \$4 AND deleted_at IS NULL AND version = \$5 RETURNING`).
+		WithArgs("John Doe", 21, "john@example.com", 1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "age", "email", "deleted_at", "version"}))
+	mock.ExpectQuery(`SELECT id, name, age, email, deleted_at, version FROM students WHERE id = \$1 AND deleted_at IS NULL`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "age", "email", "deleted_at", "version"}).AddRow(1, "John Doe", 20, "john@example.com", nil, 3))
+
+	_, err = repo.Update(context.Background(), Student{ID: 1, Name: "John Doe", Age: 21, Email: "john@example.com", Version: 2})
+	assert.ErrorIs(t, err, ErrVersionMismatch)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPatchWithoutIfMatchRefusesToOverwriteConcurrentChange(t *testing.T) {
+	h, mock := newTestHandler(t)
+	r := testRouter(t, h, testAdmin, RouteMiddleware{})
+	cols := []string{"id", "name", "age", "email", "deleted_at", "version"}
+
+	// The patch is computed from version 3, but another request moved the
+	// student to version 4 before the update ran.
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id, name, age, email, deleted_at, version FROM students WHERE id = \$1 AND deleted_at IS NULL`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(cols).AddRow(1, "John Doe", 20, "john@example.com", nil, 3))
+	mock.ExpectQuery(`UPDATE students SET age = \$1, version = version \+ 1 WHERE id = \$2 AND deleted_at IS NULL AND version = \$3 RETURNING`).
+		WithArgs(21, 1, 3).
+		WillReturnRows(sqlmock.NewRows(cols))
+	mock.ExpectQuery(`SELECT id, name, age, email, deleted_at, version FROM students WHERE id = \$1 AND deleted_at IS NULL`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(cols).AddRow(1, "John Doe", 30, "john@example.com", nil, 4))
+	mock.ExpectRollback()
+
+	w := doConditional(r, http.MethodPatch, "/students/1", "", "", `{"age":21}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Purger permanently removes students and users that were deleted longer
+// ago than the retention period, along with expired idempotency keys.
+type Purger struct {
+	students StudentRepository
+	users    UserRepository
+	keys     IdempotencyRepository
+	cfg      PurgeConfig
+	logger   *logrus.Logger
+}
+
+func NewPurger(repos Repositories, cfg PurgeConfig, logger *logrus.Logger) *Purger {
+	return &Purger{students: repos.Students, users: repos.Users, keys: repos.Idempotency, cfg: cfg, logger: logger}
+}
+
+// Run purges once at start and then every interval until ctx is done.
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		if err := p.Purge(ctx, time.Now()); err != nil && ctx.Err() == nil {
+			p.logger.Error("Failed to purge deleted records:", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge removes the idempotency keys that expired by now and the records
+// deleted before now minus the retention period. A zero retention keeps
+// deleted records forever.
+func (p *Purger) Purge(ctx context.Context, now time.Time) error {
+	keys, err := p.keys.Purge(ctx, now)
+	if err != nil {
+		return fmt.Errorf("error purging idempotency keys: %w", err)
+	}
+	RecordsPurgedTotal.WithLabelValues("idempotency_keys").Add(float64(keys))
+	if keys > 0 {
+		p.logger.Debug("Purged ", keys, " expired idempotency keys")
+	}
+	if p.cfg.Retention <= 0 {
+		return nil
+	}
+
+	cutoff := now.Add(-p.cfg.Retention)
+	students, err := p.students.Purge(ctx, cutoff)
+	if err != nil {
+		return fmt.Errorf("error purging students: %w", err)
+	}
+	RecordsPurgedTotal.WithLabelValues("students").Add(float64(students))
+	users, err := p.users.Purge(ctx, cutoff)
+	if err != nil {
+		return fmt.Errorf("error purging users: %w", err)
+	}
+	RecordsPurgedTotal.WithLabelValues("users").Add(float64(users))
+
+	if students+users > 0 {
+		p.logger.Info("Purged ", students, " students and ", users, " users deleted before ", cutoff.Format(time.RFC3339))
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// apiKeyHeader identifies a client registered in the rate limit config.
+const apiKeyHeader = "X-API-Key"
+
+// RateLimit is a token bucket: a client may send Burst requests at once, and
+// the bucket refills at Requests per Per.
+type RateLimit struct {
+	Requests int           `yaml:"requests"`
+	Per      time.Duration `yaml:"per"`
+	// Burst defaults to Requests.
+	Burst int `yaml:"burst"`
+}
+
+func (l RateLimit) burst() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return l.Requests
+}
+
+// Validate reports a limit that would never let a request through.
+func (l RateLimit) Validate() error {
+	if l.Requests <= 0 || l.Per <= 0 {
+		return fmt.Errorf("requests and per must be positive")
+	}
+	if l.Burst < 0 {
+		return fmt.Errorf("burst must not be negative")
+	}
+	return nil
+}
+
+// refillTime is how long the bucket takes to gain tokens.
+func (l RateLimit) refillTime(tokens float64) time.Duration {
+	return time.Duration(tokens * float64(l.Per) / float64(l.Requests))
+}
+
+// tokenBucket is the stored state of one client's bucket. A zero bucket has
+// never been used and starts full.
+type tokenBucket struct {
+	Tokens    float64
+	UpdatedAt time.Time
+	// FullAt is when the bucket will have refilled. From then on it is no
+	// different from a bucket that was never used, so stores may drop it.
+	FullAt time.Time
+}
+
+// RateLimitResult is the outcome of taking a token.
+type RateLimitResult struct {
+	Allowed bool
+	// Limit is the size of the bucket.
+	Limit     int
+	Remaining int
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long until the next token when none was left.
+	RetryAfter time.Duration
+}
+
+// take refills b for the time since it was last used and removes a token if
+// a whole one is left.
+func (l RateLimit) take(b tokenBucket, now time.Time) (tokenBucket, RateLimitResult) {
+	burst := float64(l.burst())
+	switch {
+	case b.UpdatedAt.IsZero():
+		b.Tokens = burst
+	case now.After(b.UpdatedAt):
+		b.Tokens = math.Min(burst, b.Tokens+float64(now.Sub(b.UpdatedAt))*float64(l.Requests)/float64(l.Per))
+	}
+	// Replicas sharing a bucket may disagree slightly on the time; it never
+	// goes backwards.
+	if now.After(b.UpdatedAt) {
+		b.UpdatedAt = now
+	}
+
+	res := RateLimitResult{Limit: l.burst()}
+	if b.Tokens >= 1 {
+		b.Tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = l.refillTime(1 - b.Tokens)
+	}
+	res.Remaining = int(b.Tokens)
+	res.Reset = l.refillTime(burst - b.Tokens)
+	b.FullAt = b.UpdatedAt.Add(res.Reset)
+	return b, res
+}
+
+// RateLimitStore keeps the token buckets of the rate limiter.
+type RateLimitStore interface {
+	// Take removes a token from the bucket named key, after refilling it
+	// per limit for the time since it was last used.
+	Take(ctx context.Context, key string, limit RateLimit, now time.Time) (RateLimitResult, error)
+}
+
+// RateLimiter enforces per-client request limits. Clients are told apart by
+// the authenticated user, else by a known API key, else by IP address.
+type RateLimiter struct {
+	store RateLimitStore
+	cfg   RateLimitConfig
+	// clients maps API keys to the client names they were registered under.
+	clients map[string]string
+	logger  *logrus.Logger
+}
+
+func NewRateLimiter(store RateLimitStore, cfg RateLimitConfig, logger *logrus.Logger) *RateLimiter {
+	clients := make(map[string]string, len(cfg.APIKeys))
+	for name, key := range cfg.APIKeys {
+		clients[key] = name
+	}
+	return &RateLimiter{store: store, cfg: cfg, clients: clients, logger: logger}
+}
+
+// Limit returns the middleware enforcing rt's limit: the one configured for
+// the route, else the one the route declares, else the default. Routes on
+// the default limit share one bucket per client; the others get their own.
+//
+// When the store fails the request is let through, since refusing every
+// request would be worse than not limiting them for a while.
+func (l *RateLimiter) Limit(rt Route) gin.HandlerFunc {
+	name := rt.Method + " " + rt.Path
+	limit, scope := l.cfg.Default, "default"
+	if rt.RateLimit != nil {
+		limit, scope = *rt.RateLimit, name
+	}
+	if override, ok := l.cfg.Routes[name]; ok {
+		limit, scope = override, name
+	}
+
+	return func(c *gin.Context) {
+		client := l.client(c)
+		res, err := l.store.Take(c.Request.Context(), scope+" "+client, limit, time.Now())
+		if err != nil {
+			requestLog(c, l.logger).Error("Rate limiter store failed, not limiting:", err)
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Limit", strconv.Itoa(res.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		c.Header("RateLimit-Reset", ceilSeconds(res.Reset))
+		if !res.Allowed {
+			RateLimitedTotal.WithLabelValues(c.FullPath(), c.Request.Method).Inc()
+			requestLog(c, l.logger).Warn("Rate limited ", client, " on ", name)
+			retry := ceilSeconds(res.RetryAfter)
+			c.Header("Retry-After", retry)
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, ErrorResponse{Error: "Too many requests; retry in " + retry + " seconds"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// client names the caller whose bucket the request draws from. API keys only
+// count when they are registered, so a made-up key cannot buy a fresh bucket.
+func (l *RateLimiter) client(c *gin.Context) string {
+	if claims, ok := CurrentClaims(c); ok {
+		return "user:" + strconv.Itoa(claims.UserID)
+	}
+	if key := c.GetHeader(apiKeyHeader); key != "" {
+		if name, ok := l.clients[key]; ok {
+			return "key:" + name
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// ceilSeconds formats d in whole seconds, rounding up so clients that wait
+// that long find a token.
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,194 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenBucketRefills(t *testing.T) {
+	limit := RateLimit{Requests: 60, Per: time.Minute, Burst: 2}
+	now := time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC)
+
+	b, res := limit.take(tokenBucket{}, now)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 1, res.Remaining)
+	assert.Equal(t, time.Second, res.Reset)
+	b, res = limit.take(b, now)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+	b, res = limit.take(b, now.Add(500*time.Millisecond))
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 500*time.Millisecond, res.RetryAfter)
+
+	// One token a second: after a second there is one again, and after a
+	// long pause the bucket holds no more than the burst.
+	b, res = limit.take(b, now.Add(time.Second))
+	assert.True(t, res.Allowed)
+	_, res = limit.take(b, now.Add(time.Hour))
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 1, res.Remaining)
+}
+
+// fakeRateLimitStore fails every Take.
+type fakeRateLimitStore struct{}
+
+func (fakeRateLimitStore) Take(context.Context, string, RateLimit, time.Time) (RateLimitResult, error) {
+	return RateLimitResult{}, errors.New("connection refused")
+}
+
+func rateLimitedRouter(store RateLimitStore, cfg RateLimitConfig, routes []Route) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	mountTestRoutes(r, NewTokenManager("test-secret", time.Minute, time.Hour), RouteMiddleware{
+		RateLimiter: NewRateLimiter(store, cfg, logrus.New()),
+	}, routes)
+	return r
+}
+
+func sendLimited(r *gin.Engine, method, path string, header http.Header) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, nil)
+	for name, values := range header {
+		for _, v := range values {
+			req.Header.Add(name, v)
+		}
+	}
+	req.RemoteAddr = "203.0.113.7:4321"
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimiterRefusesWithRetryAfter(t *testing.T) {
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r := rateLimitedRouter(NewMemoryRateLimitStore(), RateLimitConfig{
+		Default: RateLimit{Requests: 2, Per: time.Minute},
+		Routes:  map[string]RateLimit{"GET /students": {Requests: 1, Per: time.Hour}},
+	}, []Route{
+		{Method: http.MethodGet, Path: "/health", Handler: ok, Public: true},
+		{Method: http.MethodGet, Path: "/courses", Handler: ok, Public: true},
+		{Method: http.MethodPost, Path: "/auth/login", Handler: ok, Public: true, RateLimit: &RateLimit{Requests: 1, Per: time.Minute}},
+		{Method: http.MethodGet, Path: "/students", Handler: ok, Public: true, RateLimit: &RateLimit{Requests: 100, Per: time.Minute}},
+	})
+	before := testutil.ToFloat64(RateLimitedTotal.WithLabelValues("/health", http.MethodGet))
+
+	w := sendLimited(r, http.MethodGet, "/health", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "30", w.Header().Get("RateLimit-Reset"))
+
+	// Routes on the default limit share the bucket.
+	assert.Equal(t, http.StatusOK, sendLimited(r, http.MethodGet, "/courses", nil).Code)
+	w = sendLimited(r, http.MethodGet, "/health", nil)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "30", w.Header().Get("Retry-After"))
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+	assert.Contains(t, w.Body.String(), "Too many requests")
+	assert.Equal(t, before+1, testutil.ToFloat64(RateLimitedTotal.WithLabelValues("/health", http.MethodGet)))
+
+	// A route with its own limit has its own bucket, and the config
+	// overrides what the route declares.
+	assert.Equal(t, http.StatusOK, sendLimited(r, http.MethodPost, "/auth/login", nil).Code)
+	assert.Equal(t, http.StatusTooManyRequests, sendLimited(r, http.MethodPost, "/auth/login", nil).Code)
+	assert.Equal(t, http.StatusOK, sendLimited(r, http.MethodGet, "/students", nil).Code)
+	w = sendLimited(r, http.MethodGet, "/students", nil)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "3600", w.Header().Get("Retry-After"))
+}
+
+func TestRateLimiterIgnoresForwardedForFromUntrustedPeers(t *testing.T) {
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	routes := []Route{{Method: http.MethodPost, Path: "/auth/login", Handler: ok, Public: true}}
+	cfg := RateLimitConfig{Default: RateLimit{Requests: 1, Per: time.Minute}}
+	forwarded := func(ip string) http.Header { return http.Header{"X-Forwarded-For": {ip}} }
+
+	// By default no proxy is trusted, so a made-up X-Forwarded-For does not
+	// buy a fresh bucket.
+	r := rateLimitedRouter(NewMemoryRateLimitStore(), cfg, routes)
+	assert.NoError(t, r.SetTrustedProxies(defaultConfig().HTTP.TrustedProxies))
+	assert.Equal(t, http.StatusOK, sendLimited(r, http.MethodPost, "/auth/login", forwarded("198.51.100.1")).Code)
+	assert.Equal(t, http.StatusTooManyRequests, sendLimited(r, http.MethodPost, "/auth/login", forwarded("198.51.100.2")).Code)
+
+	// Behind a trusted proxy the forwarded address is the client.
+	r = rateLimitedRouter(NewMemoryRateLimitStore(), cfg, routes)
+	assert.NoError(t, r.SetTrustedProxies([]string{"203.0.113.0/24"}))
+	assert.Equal(t, http.StatusOK, sendLimited(r, http.MethodPost, "/auth/login", forwarded("198.51.100.1")).Code)
+	assert.Equal(t, http.StatusOK, sendLimited(r, http.MethodPost, "/auth/login", forwarded("198.51.100.2")).Code)
+	assert.Equal(t, http.StatusTooManyRequests, sendLimited(r, http.MethodPost, "/auth/login", forwarded("198.51.100.2")).Code)
+}
+
+func TestRateLimiterTellsClientsApart(t *testing.T) {
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r := rateLimitedRouter(NewMemoryRateLimitStore(), RateLimitConfig{
+		Default: RateLimit{Requests: 1, Per: time.Minute},
+		APIKeys: map[string]string{"reporting": "secret-key"},
+	}, []Route{
+		{Method: http.MethodGet, Path: "/health", Handler: ok, Public: true},
+		{Method: http.MethodGet, Path: "/students", Handler: ok},
+	})
+	tm := NewTokenManager("test-secret", time.Minute, time.Hour)
+	alice, _ := tm.IssueAccessToken(User{ID: 1, Role: RoleAdmin}, testSession)
+	bob, _ := tm.IssueAccessToken(User{ID: 2, Role: RoleAdmin}, testSession)
+	bearer := func(token string) http.Header { return http.Header{"Authorization": {"Bearer " + token}} }
+
+	assert.Equal(t, http.StatusOK, sendLimited(r, http.MethodGet, "/health", nil).Code)
+	assert.Equal(t, http.StatusTooManyRequests, sendLimited(r, http.MethodGet, "/health", nil).Code)
+	// An unknown key is no way around the limit of the IP address.
+	assert.Equal(t, http.StatusTooManyRequests, sendLimited(r, http.MethodGet, "/health", http.Header{apiKeyHeader: {"made-up"}}).Code)
+	assert.Equal(t, http.StatusOK, sendLimited(r, http.MethodGet, "/health", http.Header{apiKeyHeader: {"secret-key"}}).Code)
+
+	// Users behind the same address have a bucket each.
+	assert.Equal(t, http.StatusOK, sendLimited(r, http.MethodGet, "/students", bearer(alice)).Code)
+	assert.Equal(t, http.StatusOK, sendLimited(r, http.MethodGet, "/students", bearer(bob)).Code)
+	assert.Equal(t, http.StatusTooManyRequests, sendLimited(r, http.MethodGet, "/students", bearer(alice)).Code)
+}
+
+func TestRateLimiterFailsOpen(t *testing.T) {
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r := rateLimitedRouter(fakeRateLimitStore{}, RateLimitConfig{Default: RateLimit{Requests: 1, Per: time.Minute}}, []Route{
+		{Method: http.MethodGet, Path: "/health", Handler: ok, Public: true},
+	})
+
+	for range 3 {
+		w := sendLimited(r, http.MethodGet, "/health", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("RateLimit-Limit"))
+	}
+}
+
+func TestPostgresRateLimitStoreTakesUnderLock(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+	store := NewPostgresRateLimitStore(&Db{db: mockDB})
+	now := time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC)
+	limit := RateLimit{Requests: 60, Per: time.Minute, Burst: 10}
+
+	mock.ExpectExec(`DELETE FROM rate_limit_buckets WHERE full_at <= \$1`).
+		WithArgs(now).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT tokens, updated_at FROM rate_limit_buckets WHERE key = \$1 FOR UPDATE`).
+		WithArgs("default ip:203.0.113.7").
+		WillReturnRows(sqlmock.NewRows([]string{"tokens", "updated_at"}).AddRow(0.5, now.Add(-2*time.Second)))
+	mock.ExpectExec(`INSERT INTO rate_limit_buckets \(key, tokens, updated_at, full_at\) VALUES \(\$1, \$2, \$3, \$4\)\s+ON CONFLICT \(key\) DO UPDATE`).
+		WithArgs("default ip:203.0.113.7", 1.5, now, now.Add(8500*time.Millisecond)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	res, err := store.Take(context.Background(), "default ip:203.0.113.7", limit, now)
+	assert.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 1, res.Remaining)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
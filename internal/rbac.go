@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Role is the access level assigned to a user.
+type Role string
+
+const (
+	RoleAdmin   Role = "admin"
+	RoleTeacher Role = "teacher"
+	RoleStudent Role = "student"
+)
+
+// Permission names an action a route can require.
+type Permission string
+
+const (
+	PermStudentsRead    Permission = "students:read"
+	PermStudentsReadOwn Permission = "students:read:own"
+	PermStudentsWrite   Permission = "students:write"
+	PermStudentsDelete  Permission = "students:delete"
+	PermUsersRead       Permission = "users:read"
+	PermUsersWrite      Permission = "users:write"
+	PermUsersDelete     Permission = "users:delete"
+
+	PermCoursesRead         Permission = "courses:read"
+	PermCoursesWrite        Permission = "courses:write"
+	PermCoursesDelete       Permission = "courses:delete"
+	PermEnrollmentsWrite    Permission = "enrollments:write"
+	PermEnrollmentsWriteOwn Permission = "enrollments:write:own"
+	PermGradesRead          Permission = "grades:read"
+	PermGradesReadOwn       Permission = "grades:read:own"
+	PermGradesWrite         Permission = "grades:write"
+	PermAttendanceRead      Permission = "attendance:read"
+	PermAttendanceReadOwn   Permission = "attendance:read:own"
+	PermAttendanceWrite     Permission = "attendance:write"
+
+	PermAuditRead Permission = "audit:read"
+)
+
+// rolePermissions is the single source of truth for what each role may do.
+var rolePermissions = map[Role]map[Permission]bool{
+	RoleAdmin: {
+		PermStudentsRead:   true,
+		PermStudentsWrite:  true,
+		PermStudentsDelete: true,
+		PermUsersRead:      true,
+		PermUsersWrite:     true,
+		PermUsersDelete:    true,
+
+		PermCoursesRead:      true,
+		PermCoursesWrite:     true,
+		PermCoursesDelete:    true,
+		PermEnrollmentsWrite: true,
+		PermGradesRead:       true,
+		PermGradesWrite:      true,
+		PermAttendanceRead:   true,
+		PermAttendanceWrite:  true,
+
+		PermAuditRead: true,
+	},
+	RoleTeacher: {
+		PermStudentsRead:  true,
+		PermStudentsWrite: true,
+		PermUsersRead:     true,
+
+		PermCoursesRead:      true,
+		PermCoursesWrite:     true,
+		PermEnrollmentsWrite: true,
+		PermGradesRead:       true,
+		PermGradesWrite:      true,
+		PermAttendanceRead:   true,
+		PermAttendanceWrite:  true,
+	},
+	RoleStudent: {
+		PermStudentsReadOwn: true,
+
+		PermCoursesRead:         true,
+		PermEnrollmentsWriteOwn: true,
+		PermGradesReadOwn:       true,
+		PermAttendanceReadOwn:   true,
+	},
+}
+
+// Valid reports whether r is one of the known roles.
+func (r Role) Valid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// Can reports whether the role has been granted the permission.
+func (r Role) Can(p Permission) bool {
+	return rolePermissions[r][p]
+}
+
+// Authorize enforces the route's permission for the authenticated caller. A
+// caller lacking Permission is still let through when the route declares an
+// OwnPermission, the caller holds it, and the :id path parameter is the
+// caller's own student record.
+func Authorize(route Route) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := CurrentClaims(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, ErrorResponse{Error: "Missing bearer token"})
+			return
+		}
+
+		role := Role(claims.Role)
+		if route.Permission == "" || role.Can(route.Permission) {
+			c.Next()
+			return
+		}
+		if route.OwnPermission != "" && role.Can(route.OwnPermission) &&
+			claims.StudentID > 0 && c.Param("id") == strconv.Itoa(claims.StudentID) {
+			c.Next()
+			return
+		}
+
+		c.AbortWithStatusJSON(http.StatusForbidden, ErrorResponse{Error: "Insufficient permissions"})
+	}
+}
@@ -0,0 +1,66 @@
+package internal
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRolePermissions(t *testing.T) {
+	assert.True(t, RoleAdmin.Can(PermUsersDelete))
+	assert.True(t, RoleTeacher.Can(PermStudentsRead))
+	assert.True(t, RoleTeacher.Can(PermUsersRead))
+	assert.False(t, RoleTeacher.Can(PermUsersDelete))
+	assert.False(t, RoleStudent.Can(PermStudentsRead))
+	assert.True(t, RoleStudent.Can(PermStudentsReadOwn))
+	assert.False(t, Role("janitor").Valid())
+}
+
+func TestRegisterRoutesEnforcesPermissions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tm := NewTokenManager("test-secret", time.Minute, time.Hour)
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+
+	r := gin.New()
+	RegisterRoutes(r.Group("/api/v1"), RouteMiddleware{Auth: AuthMiddleware(tm, staticSessions{"s": true})}, []Route{
+		{Method: http.MethodGet, Path: "/health", Handler: ok, Public: true},
+		{Method: http.MethodGet, Path: "/students/:id", Handler: ok, Permission: PermStudentsRead, OwnPermission: PermStudentsReadOwn},
+		{Method: http.MethodDelete, Path: "/users/:id", Handler: ok, Permission: PermUsersDelete},
+	})
+
+	studentID := 4
+	admin, _ := tm.IssueAccessToken(User{ID: 1, Role: RoleAdmin}, "s")
+	teacher, _ := tm.IssueAccessToken(User{ID: 2, Role: RoleTeacher}, "s")
+	student, _ := tm.IssueAccessToken(User{ID: 3, Role: RoleStudent, StudentID: &studentID}, "s")
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		want   int
+	}{
+		{"public route without token", http.MethodGet, "/api/v1/health", "", http.StatusOK},
+		{"protected route without token", http.MethodGet, "/api/v1/students/4", "", http.StatusUnauthorized},
+		{"teacher reads any student", http.MethodGet, "/api/v1/students/9", teacher, http.StatusOK},
+		{"student reads own record", http.MethodGet, "/api/v1/students/4", student, http.StatusOK},
+		{"student reads another record", http.MethodGet, "/api/v1/students/9", student, http.StatusForbidden},
+		{"teacher cannot delete users", http.MethodDelete, "/api/v1/users/5", teacher, http.StatusForbidden},
+		{"admin deletes users", http.MethodDelete, "/api/v1/users/5", admin, http.StatusOK},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(tc.method, tc.path, nil)
+			if tc.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			r.ServeHTTP(w, req)
+			assert.Equal(t, tc.want, w.Code)
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned when the requested record does not exist.
+	ErrNotFound = errors.New("record not found")
+	// ErrDuplicate is returned when a unique value is already taken.
+	ErrDuplicate = errors.New("record already exists")
+	// ErrRefreshTokenInvalid is returned for unknown, expired or revoked
+	// refresh tokens.
+	ErrRefreshTokenInvalid = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused is returned when an already rotated refresh token
+	// is presented again; the whole session has been revoked.
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+	// ErrCourseFull is returned when an enrollment would exceed capacity.
+	ErrCourseFull = errors.New("course is full")
+	// ErrCapacityBelowEnrollment is returned when a course update would leave
+	// fewer seats than students already enrolled.
+	ErrCapacityBelowEnrollment = errors.New("capacity is below the current enrollment")
+	// ErrAlreadyEnrolled is returned when the student is already enrolled.
+	ErrAlreadyEnrolled = errors.New("student is already enrolled")
+	// ErrNotEnrolled is returned when grading a student outside a course
+	// they are enrolled in.
+	ErrNotEnrolled = errors.New("student is not enrolled in the course")
+	// ErrVersionMismatch is returned by a conditional write when the record
+	// has been changed since the version it was given.
+	ErrVersionMismatch = errors.New("record version has changed")
+)
+
+// Page is one page of a keyset paginated listing.
+type Page[T any] struct {
+	Items []T
+	// Total counts every row matching the filter, across all pages.
+	Total int
+	// HasMore reports whether rows follow the last item.
+	HasMore bool
+}
+
+// StudentRepository persists students.
+type StudentRepository interface {
+	List(ctx context.Context, filter StudentFilter, opts ListOptions) (Page[Student], error)
+	// Get returns the student unless it has been deleted.
+	Get(ctx context.Context, id int) (Student, error)
+	// GetIncludingDeleted is Get that also finds deleted students.
+	GetIncludingDeleted(ctx context.Context, id int) (Student, error)
+	Create(ctx context.Context, s Student) (Student, error)
+	// Update overwrites the student with s.ID and returns the stored row.
+	// Every write to a student increments its version. A non-zero s.Version
+	// makes the write conditional: it fails with ErrVersionMismatch unless
+	// the stored version still equals it.
+	Update(ctx context.Context, s Student) (Student, error)
+	// Patch changes only the fields set in p and returns the stored row;
+	// p.Version makes it conditional like Update.
+	Patch(ctx context.Context, id int, p StudentPatch) (Student, error)
+	// Delete marks the student as deleted. The row is kept, hidden, until
+	// Purge removes it, and can be brought back with Restore. A non-zero
+	// version makes it conditional like Update.
+	Delete(ctx context.Context, id, version int) error
+	// Restore clears the deletion mark and returns the student. Restoring a
+	// student that is not deleted changes nothing.
+	Restore(ctx context.Context, id int) (Student, error)
+	// Purge permanently removes the students deleted before cutoff and
+	// returns how many there were.
+	Purge(ctx context.Context, cutoff time.Time) (int, error)
+	// Export calls fn for every student matching the filter in sort order,
+	// stopping at the first error fn returns.
+	Export(ctx context.Context, filter StudentFilter, sort []SortField, fn func(Student) error) error
+	// BeginImport starts a bulk import on the transaction of the
+	// repositories InTx hands out; the rows are stored when it commits.
+	BeginImport(ctx context.Context) (StudentImport, error)
+}
+
+// StudentImport stages rows of a bulk import inside one transaction, so the
+// upload is stored completely or not at all.
+type StudentImport interface {
+	// Add stages a validated student; line identifies the row in conflicts.
+	Add(line int, s Student) error
+	// Finish reports the lines whose email already belongs to a student.
+	// When commit is set and there are no conflicts the staged rows are
+	// stored and returned in line order; otherwise nothing is stored.
+	Finish(ctx context.Context, commit bool) (stored []Student, conflicts []int, err error)
+}
+
+// StudentPatch lists the student fields to change; nil fields are left as
+// they are.
+type StudentPatch struct {
+	Name  *string
+	Age   *int
+	Email *string
+	// Version, when non-zero, is the version the student must still have.
+	Version int
+}
+
+// Empty reports whether the patch changes nothing.
+func (p StudentPatch) Empty() bool {
+	return p.Name == nil && p.Age == nil && p.Email == nil
+}
+
+// UserRepository persists users and their password hashes.
+type UserRepository interface {
+	List(ctx context.Context, filter UserFilter, opts ListOptions) (Page[User], error)
+	// Get returns the user unless it has been deleted.
+	Get(ctx context.Context, id int) (User, error)
+	// GetIncludingDeleted is Get that also finds deleted users.
+	GetIncludingDeleted(ctx context.Context, id int) (User, error)
+	// GetCredentials looks a user up by email, ignoring case, and returns
+	// the stored bcrypt hash, which is empty for accounts without a
+	// password. Deleted users are not found.
+	GetCredentials(ctx context.Context, email string) (User, string, error)
+	// Create stores u; an empty passwordHash leaves the account without a
+	// password.
+	Create(ctx context.Context, u User, passwordHash string) (User, error)
+	// Delete marks the user as deleted, which also ends their sessions.
+	Delete(ctx context.Context, id int) error
+	// Purge permanently removes the users deleted before cutoff and returns
+	// how many there were.
+	Purge(ctx context.Context, cutoff time.Time) (int, error)
+	// Export calls fn for every user matching the filter in sort order,
+	// stopping at the first error fn returns.
+	Export(ctx context.Context, filter UserFilter, sort []SortField, fn func(User) error) error
+}
+
+// CourseRepository persists courses and enrollments.
+type CourseRepository interface {
+	List(ctx context.Context, filter CourseFilter, opts ListOptions) (Page[Course], error)
+	Get(ctx context.Context, id int) (Course, error)
+	Create(ctx context.Context, c Course) (Course, error)
+	// Update overwrites the course with c.ID and returns the stored row. It
+	// fails with ErrCapacityBelowEnrollment, returning the unchanged course,
+	// when c.Capacity is less than the number of students enrolled.
+	Update(ctx context.Context, c Course) (Course, error)
+	Delete(ctx context.Context, id int) error
+	// Enroll adds the student to the course atomically: it fails with
+	// ErrCourseFull when no seat is left, ErrAlreadyEnrolled on a repeat and
+	// ErrNotFound when the course does not exist.
+	Enroll(ctx context.Context, studentID, courseID int) (Enrollment, error)
+	// ListStudents pages through the students enrolled in the course.
+	ListStudents(ctx context.Context, courseID int, opts ListOptions) (Page[Student], error)
+}
+
+// GradeRepository persists assessment grades.
+type GradeRepository interface {
+	// Record stores g, replacing an earlier grade for the same student, course
+	// and assessment. It fails with ErrNotEnrolled unless the student is
+	// enrolled in the course.
+	Record(ctx context.Context, g Grade) (Grade, error)
+	// ListByStudent returns the student's graded courses ordered by term and
+	// course code, each with its grades ordered by assessment.
+	ListByStudent(ctx context.Context, studentID int) ([]CourseGrades, error)
+}
+
+// AttendanceRepository persists daily attendance and reports on it.
+type AttendanceRepository interface {
+	// Record stores every entry for the day at once, replacing statuses
+	// already recorded for that day.
+	Record(ctx context.Context, day time.Time, entries []AttendanceEntry) error
+	// Report summarises attendance between from and to inclusive for each
+	// student with records, ordered by student ID.
+	Report(ctx context.Context, from, to time.Time) ([]AttendanceSummary, error)
+	// Summary is Report for a single student; a student without records gets
+	// an empty summary.
+	Summary(ctx context.Context, studentID int, from, to time.Time) (AttendanceSummary, error)
+}
+
+// SessionRepository persists refresh tokens. Tokens rotated from the same
+// login share a family ID, which doubles as the session ID.
+type SessionRepository interface {
+	// Create stores the first refresh token of a new session.
+	Create(ctx context.Context, userID int, familyID, tokenHash string, expiresAt time.Time) error
+	// Rotate atomically retires the token with tokenHash, stores newHash in
+	// its family and returns the owning user and family ID. Presenting an
+	// already rotated token revokes the family and returns
+	// ErrRefreshTokenReused.
+	Rotate(ctx context.Context, tokenHash, newHash string, expiresAt time.Time) (User, string, error)
+	// Revoke invalidates every token of the family.
+	Revoke(ctx context.Context, familyID string) error
+	// Active reports whether the family still holds a usable token and its
+	// user has not been deleted.
+	Active(ctx context.Context, familyID string) (bool, error)
+}
+
+// AuditRepository persists the audit trail. Events are only ever appended.
+type AuditRepository interface {
+	// Record appends the event. Record on the Repositories handed to an InTx
+	// callback to store the event together with the change it describes.
+	Record(ctx context.Context, e AuditEvent) error
+	List(ctx context.Context, filter AuditFilter, opts ListOptions) (Page[AuditEvent], error)
+}
+
+// IdempotencyRecord is a request sent with an Idempotency-Key and, once it
+// has finished, the response to replay to retries.
+type IdempotencyRecord struct {
+	UserID      int
+	Key         string
+	RequestHash string
+	// StatusCode is zero while the first request is still running.
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	ExpiresAt  time.Time
+}
+
+// IdempotencyRepository persists idempotency keys and the responses they
+// produced.
+type IdempotencyRepository interface {
+	// Reserve claims rec.Key for the user. A key that is already claimed
+	// and has not expired by now is left alone: Reserve returns its record
+	// and ErrDuplicate.
+	Reserve(ctx context.Context, rec IdempotencyRecord, now time.Time) (IdempotencyRecord, error)
+	// Complete stores the response of a reserved key.
+	Complete(ctx context.Context, rec IdempotencyRecord) error
+	// Release forgets a reserved key so the request can be tried again.
+	Release(ctx context.Context, userID int, key string) error
+	// Purge removes the keys that expired before now and returns how many
+	// there were.
+	Purge(ctx context.Context, now time.Time) (int, error)
+}
+
+// Repositories bundles the storage backends the Handler depends on.
+type Repositories struct {
+	Students    StudentRepository
+	Users       UserRepository
+	Sessions    SessionRepository
+	Courses     CourseRepository
+	Grades      GradeRepository
+	Attendance  AttendanceRepository
+	Audit       AuditRepository
+	Idempotency IdempotencyRepository
+
+	// InTx calls fn with repositories whose Students, Users and Audit
+	// statements share one transaction, committed when fn returns nil and
+	// rolled back otherwise. Calling InTx on those repositories joins the
+	// transaction already open.
+	InTx func(ctx context.Context, fn func(tx Repositories) error) error
+}
@@ -0,0 +1,927 @@
+package internal
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NewMemoryRepositories returns repositories that keep everything in process
+// memory. They are meant for tests and local experiments; nothing survives a
+// restart.
+func NewMemoryRepositories() Repositories {
+	students := &memoryStudentRepository{rows: map[int]Student{}}
+	users := &memoryUserRepository{rows: map[int]memoryUser{}}
+	courses := &memoryCourseRepository{students: students, rows: map[int]Course{}, enrollments: map[int]map[int]time.Time{}}
+	repos := Repositories{
+		Students:    students,
+		Users:       users,
+		Sessions:    &memorySessionRepository{users: users, tokens: map[string]*memoryRefreshToken{}},
+		Courses:     courses,
+		Grades:      &memoryGradeRepository{courses: courses, rows: map[int]Grade{}},
+		Attendance:  &memoryAttendanceRepository{students: students, days: map[int]map[time.Time]AttendanceStatus{}},
+		Audit:       &memoryAuditRepository{},
+		Idempotency: &memoryIdempotencyRepository{rows: map[memoryIdempotencyKey]IdempotencyRecord{}},
+	}
+	// There is nothing to roll back to: writes made before fn fails stay.
+	repos.InTx = func(_ context.Context, fn func(Repositories) error) error {
+		return fn(repos)
+	}
+	return repos
+}
+
+// paginate sorts the already filtered items and cuts the page that follows
+// the cursor in opts.
+func paginate[T any](items []T, keys func(T, []SortField) []any, opts ListOptions) Page[T] {
+	sort.SliceStable(items, func(i, j int) bool {
+		return compareKeys(opts.Sort, keys(items[i], opts.Sort), keys(items[j], opts.Sort)) < 0
+	})
+
+	page := Page[T]{Total: len(items), Items: make([]T, 0, opts.Limit)}
+	for _, item := range items {
+		if len(opts.After) > 0 && compareKeys(opts.Sort, keys(item, opts.Sort), opts.After) <= 0 {
+			continue
+		}
+		if len(page.Items) == opts.Limit {
+			page.HasMore = true
+			break
+		}
+		page.Items = append(page.Items, item)
+	}
+	return page
+}
+
+// export sorts the already filtered items and hands them to fn one by one.
+func export[T any](items []T, keys func(T, []SortField) []any, sortBy []SortField, fn func(T) error) error {
+	sort.SliceStable(items, func(i, j int) bool {
+		return compareKeys(sortBy, keys(items[i], sortBy), keys(items[j], sortBy)) < 0
+	})
+	for _, item := range items {
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// duplicateEmail is the error the unique email indexes would raise.
+func duplicateEmail(constraint string) error {
+	return &ConstraintError{Kind: ConstraintUnique, Constraint: constraint, Field: "email", Err: ErrDuplicate}
+}
+
+type memoryStudentRepository struct {
+	mu     sync.RWMutex
+	nextID int
+	rows   map[int]Student
+}
+
+// emailTaken mirrors the partial unique index on lower(email) of students
+// that are not deleted, ignoring the row being written.
+func (r *memoryStudentRepository) emailTaken(email string, id int) bool {
+	for _, s := range r.rows {
+		if s.ID != id && s.DeletedAt == nil && strings.EqualFold(s.Email, email) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *memoryStudentRepository) List(_ context.Context, filter StudentFilter, opts ListOptions) (Page[Student], error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var matched []Student
+	for _, s := range r.rows {
+		if filter.matches(s) {
+			matched = append(matched, s)
+		}
+	}
+	return paginate(matched, Student.sortValues, opts), nil
+}
+
+func (r *memoryStudentRepository) Export(_ context.Context, filter StudentFilter, sort []SortField, fn func(Student) error) error {
+	r.mu.RLock()
+	var matched []Student
+	for _, s := range r.rows {
+		if filter.matches(s) {
+			matched = append(matched, s)
+		}
+	}
+	r.mu.RUnlock()
+	return export(matched, Student.sortValues, sort, fn)
+}
+
+func (r *memoryStudentRepository) Get(_ context.Context, id int) (Student, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	s, ok := r.rows[id]
+	if !ok || s.DeletedAt != nil {
+		return Student{}, ErrNotFound
+	}
+	return s, nil
+}
+
+func (r *memoryStudentRepository) GetIncludingDeleted(_ context.Context, id int) (Student, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	s, ok := r.rows[id]
+	if !ok {
+		return Student{}, ErrNotFound
+	}
+	return s, nil
+}
+
+// exists reports whether the row is stored, deleted or not, which is what
+// foreign keys to students see.
+func (r *memoryStudentRepository) exists(id int) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, ok := r.rows[id]
+	return ok
+}
+
+// isLive reports whether the student exists and is not deleted.
+func (r *memoryStudentRepository) isLive(id int) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	s, ok := r.rows[id]
+	return ok && s.DeletedAt == nil
+}
+
+func (r *memoryStudentRepository) Create(_ context.Context, s Student) (Student, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.emailTaken(s.Email, 0) {
+		return Student{}, duplicateEmail("students_email_key")
+	}
+	r.nextID++
+	s.ID, s.Version = r.nextID, 1
+	r.rows[s.ID] = s
+	return s, nil
+}
+
+// live returns the student with id unless it is deleted, checking version
+// like the conditional UPDATE statements do. The caller holds the lock.
+func (r *memoryStudentRepository) live(id, version int) (Student, error) {
+	s, ok := r.rows[id]
+	if !ok || s.DeletedAt != nil {
+		return Student{}, ErrNotFound
+	}
+	if version != 0 && s.Version != version {
+		return Student{}, ErrVersionMismatch
+	}
+	return s, nil
+}
+
+func (r *memoryStudentRepository) Update(_ context.Context, s Student) (Student, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, err := r.live(s.ID, s.Version)
+	if err != nil {
+		return Student{}, err
+	}
+	if r.emailTaken(s.Email, s.ID) {
+		return Student{}, duplicateEmail("students_email_key")
+	}
+	s.Version, s.DeletedAt = existing.Version+1, nil
+	r.rows[s.ID] = s
+	return s, nil
+}
+
+func (r *memoryStudentRepository) Patch(_ context.Context, id int, p StudentPatch) (Student, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, err := r.live(id, p.Version)
+	if err != nil {
+		return Student{}, err
+	}
+	if p.Empty() {
+		return s, nil
+	}
+	if p.Name != nil {
+		s.Name = *p.Name
+	}
+	if p.Age != nil {
+		s.Age = *p.Age
+	}
+	if p.Email != nil {
+		if r.emailTaken(*p.Email, id) {
+			return Student{}, duplicateEmail("students_email_key")
+		}
+		s.Email = *p.Email
+	}
+	s.Version++
+	r.rows[id] = s
+	return s, nil
+}
+
+func (r *memoryStudentRepository) Delete(_ context.Context, id, version int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, err := r.live(id, version)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	s.DeletedAt = &now
+	s.Version++
+	r.rows[id] = s
+	return nil
+}
+
+func (r *memoryStudentRepository) Restore(_ context.Context, id int) (Student, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, ok := r.rows[id]
+	if !ok {
+		return Student{}, ErrNotFound
+	}
+	if s.DeletedAt == nil {
+		return s, nil
+	}
+	if r.emailTaken(s.Email, id) {
+		return Student{}, duplicateEmail("students_email_key")
+	}
+	s.DeletedAt = nil
+	s.Version++
+	r.rows[id] = s
+	return s, nil
+}
+
+func (r *memoryStudentRepository) Purge(_ context.Context, cutoff time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := 0
+	for id, s := range r.rows {
+		if s.DeletedAt != nil && s.DeletedAt.Before(cutoff) {
+			delete(r.rows, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r *memoryStudentRepository) BeginImport(context.Context) (StudentImport, error) {
+	return &memoryStudentImport{repo: r}, nil
+}
+
+// memoryStudentImport holds the staged rows until Finish.
+type memoryStudentImport struct {
+	repo  *memoryStudentRepository
+	lines []int
+	rows  []Student
+}
+
+func (i *memoryStudentImport) Add(line int, s Student) error {
+	i.lines = append(i.lines, line)
+	i.rows = append(i.rows, s)
+	return nil
+}
+
+func (i *memoryStudentImport) Finish(_ context.Context, commit bool) ([]Student, []int, error) {
+	i.repo.mu.Lock()
+	defer i.repo.mu.Unlock()
+
+	var conflicts []int
+	for n, s := range i.rows {
+		if i.repo.emailTaken(s.Email, 0) {
+			conflicts = append(conflicts, i.lines[n])
+		}
+	}
+	if !commit || len(conflicts) > 0 {
+		return nil, conflicts, nil
+	}
+	stored := make([]Student, 0, len(i.rows))
+	for _, s := range i.rows {
+		i.repo.nextID++
+		s.ID, s.Version = i.repo.nextID, 1
+		i.repo.rows[s.ID] = s
+		stored = append(stored, s)
+	}
+	return stored, nil, nil
+}
+
+type memoryUser struct {
+	User
+	passwordHash string
+}
+
+type memoryUserRepository struct {
+	mu     sync.RWMutex
+	nextID int
+	rows   map[int]memoryUser
+}
+
+func (r *memoryUserRepository) List(_ context.Context, filter UserFilter, opts ListOptions) (Page[User], error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var matched []User
+	for _, u := range r.rows {
+		if filter.matches(u.User) {
+			matched = append(matched, u.User)
+		}
+	}
+	return paginate(matched, User.sortValues, opts), nil
+}
+
+func (r *memoryUserRepository) Export(_ context.Context, filter UserFilter, sort []SortField, fn func(User) error) error {
+	r.mu.RLock()
+	var matched []User
+	for _, u := range r.rows {
+		if filter.matches(u.User) {
+			matched = append(matched, u.User)
+		}
+	}
+	r.mu.RUnlock()
+	return export(matched, User.sortValues, sort, fn)
+}
+
+func (r *memoryUserRepository) Get(_ context.Context, id int) (User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	u, ok := r.rows[id]
+	if !ok || u.DeletedAt != nil {
+		return User{}, ErrNotFound
+	}
+	return u.User, nil
+}
+
+func (r *memoryUserRepository) GetIncludingDeleted(_ context.Context, id int) (User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	u, ok := r.rows[id]
+	if !ok {
+		return User{}, ErrNotFound
+	}
+	return u.User, nil
+}
+
+func (r *memoryUserRepository) GetCredentials(_ context.Context, email string) (User, string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, u := range r.rows {
+		if strings.EqualFold(u.Email, email) && u.DeletedAt == nil {
+			return u.User, u.passwordHash, nil
+		}
+	}
+	return User{}, "", ErrNotFound
+}
+
+func (r *memoryUserRepository) Create(_ context.Context, u User, passwordHash string) (User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.rows {
+		if existing.DeletedAt == nil && strings.EqualFold(existing.Email, u.Email) {
+			return User{}, duplicateEmail("users_email_lower_key")
+		}
+	}
+	if u.Role == "" {
+		u.Role = RoleStudent
+	}
+	r.nextID++
+	u.ID = r.nextID
+	r.rows[u.ID] = memoryUser{User: u, passwordHash: passwordHash}
+	return u, nil
+}
+
+func (r *memoryUserRepository) Delete(_ context.Context, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.rows[id]
+	if !ok || u.DeletedAt != nil {
+		return ErrNotFound
+	}
+	now := time.Now()
+	u.DeletedAt = &now
+	r.rows[id] = u
+	return nil
+}
+
+func (r *memoryUserRepository) Purge(_ context.Context, cutoff time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := 0
+	for id, u := range r.rows {
+		if u.DeletedAt != nil && u.DeletedAt.Before(cutoff) {
+			delete(r.rows, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+type memoryCourseRepository struct {
+	mu       sync.RWMutex
+	nextID   int
+	rows     map[int]Course
+	students *memoryStudentRepository
+	// enrollments maps course ID to the enrolled student IDs and when they
+	// enrolled.
+	enrollments map[int]map[int]time.Time
+}
+
+// withEnrolled fills in the enrollment count. Deleted students do not hold
+// a seat, and purged ones are gone as ON DELETE CASCADE would have it.
+func (r *memoryCourseRepository) withEnrolled(c Course) Course {
+	c.Enrolled = 0
+	for studentID := range r.enrollments[c.ID] {
+		if r.students.isLive(studentID) {
+			c.Enrolled++
+		}
+	}
+	return c
+}
+
+// codeTaken mirrors the unique index on (lower(code), term).
+func (r *memoryCourseRepository) codeTaken(c Course) bool {
+	for _, existing := range r.rows {
+		if existing.ID != c.ID && existing.Term == c.Term && strings.EqualFold(existing.Code, c.Code) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *memoryCourseRepository) List(_ context.Context, filter CourseFilter, opts ListOptions) (Page[Course], error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var matched []Course
+	for _, c := range r.rows {
+		if filter.matches(c) {
+			matched = append(matched, r.withEnrolled(c))
+		}
+	}
+	return paginate(matched, Course.sortValues, opts), nil
+}
+
+func (r *memoryCourseRepository) Get(_ context.Context, id int) (Course, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	c, ok := r.rows[id]
+	if !ok {
+		return Course{}, ErrNotFound
+	}
+	return r.withEnrolled(c), nil
+}
+
+func (r *memoryCourseRepository) Create(_ context.Context, c Course) (Course, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c.ID = 0
+	if r.codeTaken(c) {
+		return Course{}, &ConstraintError{Kind: ConstraintUnique, Constraint: "courses_code_term_key", Field: "code", Err: ErrDuplicate}
+	}
+	r.nextID++
+	c.ID, c.Enrolled = r.nextID, 0
+	r.rows[c.ID] = c
+	return c, nil
+}
+
+func (r *memoryCourseRepository) Update(_ context.Context, c Course) (Course, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.rows[c.ID]; !ok {
+		return Course{}, ErrNotFound
+	}
+	if r.codeTaken(c) {
+		return Course{}, &ConstraintError{Kind: ConstraintUnique, Constraint: "courses_code_term_key", Field: "code", Err: ErrDuplicate}
+	}
+	if current := r.withEnrolled(r.rows[c.ID]); c.Capacity < current.Enrolled {
+		return current, ErrCapacityBelowEnrollment
+	}
+	r.rows[c.ID] = c
+	return r.withEnrolled(c), nil
+}
+
+func (r *memoryCourseRepository) Delete(_ context.Context, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.rows[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.rows, id)
+	delete(r.enrollments, id)
+	return nil
+}
+
+func (r *memoryCourseRepository) Enroll(ctx context.Context, studentID, courseID int) (Enrollment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e := Enrollment{StudentID: studentID, CourseID: courseID}
+	c, ok := r.rows[courseID]
+	if !ok {
+		return e, ErrNotFound
+	}
+	if !r.students.exists(studentID) {
+		return e, &ConstraintError{Kind: ConstraintForeignKey, Constraint: "enrollments_student_id_fkey", Err: ErrNotFound}
+	}
+	if _, ok := r.enrollments[courseID][studentID]; ok {
+		return e, ErrAlreadyEnrolled
+	}
+	if r.withEnrolled(c).Enrolled >= c.Capacity {
+		return e, ErrCourseFull
+	}
+
+	if r.enrollments[courseID] == nil {
+		r.enrollments[courseID] = map[int]time.Time{}
+	}
+	e.EnrolledAt = time.Now()
+	r.enrollments[courseID][studentID] = e.EnrolledAt
+	return e, nil
+}
+
+func (r *memoryCourseRepository) ListStudents(ctx context.Context, courseID int, opts ListOptions) (Page[Student], error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var enrolled []Student
+	for studentID := range r.enrollments[courseID] {
+		if s, err := r.students.Get(ctx, studentID); err == nil {
+			enrolled = append(enrolled, s)
+		}
+	}
+	return paginate(enrolled, Student.sortValues, opts), nil
+}
+
+type memoryGradeRepository struct {
+	mu      sync.RWMutex
+	nextID  int
+	rows    map[int]Grade
+	courses *memoryCourseRepository
+}
+
+// enrolled mirrors the foreign key from grades to enrollments, including
+// its ON DELETE CASCADE.
+func (r *memoryGradeRepository) enrolled(studentID, courseID int) bool {
+	r.courses.mu.RLock()
+	defer r.courses.mu.RUnlock()
+
+	if _, ok := r.courses.enrollments[courseID][studentID]; !ok {
+		return false
+	}
+	return r.courses.students.exists(studentID)
+}
+
+func (r *memoryGradeRepository) Record(_ context.Context, g Grade) (Grade, error) {
+	if !r.enrolled(g.StudentID, g.CourseID) {
+		return g, ErrNotEnrolled
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	g.GradedAt = time.Now()
+	for id, existing := range r.rows {
+		if existing.StudentID == g.StudentID && existing.CourseID == g.CourseID && existing.Assessment == g.Assessment {
+			g.ID = id
+			r.rows[id] = g
+			return g, nil
+		}
+	}
+	r.nextID++
+	g.ID = r.nextID
+	r.rows[g.ID] = g
+	return g, nil
+}
+
+func (r *memoryGradeRepository) ListByStudent(ctx context.Context, studentID int) ([]CourseGrades, error) {
+	r.mu.RLock()
+	byCourse := map[int][]Grade{}
+	for _, g := range r.rows {
+		if g.StudentID == studentID {
+			byCourse[g.CourseID] = append(byCourse[g.CourseID], g)
+		}
+	}
+	r.mu.RUnlock()
+
+	var graded []CourseGrades
+	for courseID, grades := range byCourse {
+		if !r.enrolled(studentID, courseID) {
+			continue
+		}
+		c, err := r.courses.Get(ctx, courseID)
+		if err != nil {
+			continue
+		}
+		sort.Slice(grades, func(i, j int) bool { return grades[i].Assessment < grades[j].Assessment })
+		graded = append(graded, CourseGrades{Course: c, Grades: grades})
+	}
+	sort.Slice(graded, func(i, j int) bool {
+		a, b := graded[i].Course, graded[j].Course
+		if a.Term != b.Term {
+			return a.Term < b.Term
+		}
+		if a.Code != b.Code {
+			return a.Code < b.Code
+		}
+		return a.ID < b.ID
+	})
+	return graded, nil
+}
+
+type memoryAttendanceRepository struct {
+	mu       sync.RWMutex
+	students *memoryStudentRepository
+	// days maps student ID to their status by day.
+	days map[int]map[time.Time]AttendanceStatus
+}
+
+func (r *memoryAttendanceRepository) Record(ctx context.Context, day time.Time, entries []AttendanceEntry) error {
+	day = truncateDay(day)
+	for _, e := range entries {
+		if !r.students.exists(e.StudentID) {
+			return &ConstraintError{Kind: ConstraintForeignKey, Constraint: "attendance_student_id_fkey", Field: "student_id", Err: ErrNotFound}
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range entries {
+		if r.days[e.StudentID] == nil {
+			r.days[e.StudentID] = map[time.Time]AttendanceStatus{}
+		}
+		r.days[e.StudentID][day] = e.Status
+	}
+	return nil
+}
+
+// summarise mirrors the aggregation in attendanceSummaryQuery.
+func (r *memoryAttendanceRepository) summarise(studentID int, from, to time.Time) AttendanceSummary {
+	s := AttendanceSummary{StudentID: studentID}
+	from, to = truncateDay(from), truncateDay(to)
+	for day, status := range r.days[studentID] {
+		if day.Before(from) || day.After(to) {
+			continue
+		}
+		s.Days++
+		switch status {
+		case AttendancePresent:
+			s.Present++
+		case AttendanceLate:
+			s.Late++
+		case AttendanceAbsent:
+			s.Absent++
+		case AttendanceExcused:
+			s.Excused++
+		}
+	}
+	if counted := s.Days - s.Excused; counted > 0 {
+		rate := math.Round(float64(s.Present+s.Late)/float64(counted)*10000) / 10000
+		s.Rate = &rate
+	}
+	return s
+}
+
+func (r *memoryAttendanceRepository) Report(ctx context.Context, from, to time.Time) ([]AttendanceSummary, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	report := []AttendanceSummary{}
+	for studentID := range r.days {
+		// Deleted students are left out; purging them cascades to their
+		// attendance.
+		if !r.students.isLive(studentID) {
+			continue
+		}
+		if s := r.summarise(studentID, from, to); s.Days > 0 {
+			report = append(report, s)
+		}
+	}
+	sort.Slice(report, func(i, j int) bool { return report[i].StudentID < report[j].StudentID })
+	return report, nil
+}
+
+func (r *memoryAttendanceRepository) Summary(_ context.Context, studentID int, from, to time.Time) (AttendanceSummary, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.summarise(studentID, from, to), nil
+}
+
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+type memoryRefreshToken struct {
+	userID    int
+	familyID  string
+	expiresAt time.Time
+	revoked   bool
+	rotated   bool
+}
+
+type memorySessionRepository struct {
+	mu     sync.Mutex
+	users  *memoryUserRepository
+	tokens map[string]*memoryRefreshToken
+}
+
+func (r *memorySessionRepository) Create(_ context.Context, userID int, familyID, tokenHash string, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.tokens[tokenHash] = &memoryRefreshToken{userID: userID, familyID: familyID, expiresAt: expiresAt}
+	return nil
+}
+
+func (r *memorySessionRepository) Rotate(ctx context.Context, tokenHash, newHash string, expiresAt time.Time) (User, string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.tokens[tokenHash]
+	if !ok {
+		return User{}, "", ErrRefreshTokenInvalid
+	}
+	u, err := r.users.Get(ctx, t.userID)
+	if err != nil {
+		return User{}, "", ErrRefreshTokenInvalid
+	}
+	if t.rotated {
+		r.revokeLocked(t.familyID)
+		return u, t.familyID, ErrRefreshTokenReused
+	}
+	if t.revoked || time.Now().After(t.expiresAt) {
+		return User{}, "", ErrRefreshTokenInvalid
+	}
+
+	t.revoked, t.rotated = true, true
+	r.tokens[newHash] = &memoryRefreshToken{userID: t.userID, familyID: t.familyID, expiresAt: expiresAt}
+	return u, t.familyID, nil
+}
+
+func (r *memorySessionRepository) Revoke(_ context.Context, familyID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.revokeLocked(familyID)
+	return nil
+}
+
+func (r *memorySessionRepository) revokeLocked(familyID string) {
+	for _, t := range r.tokens {
+		if t.familyID == familyID {
+			t.revoked = true
+		}
+	}
+}
+
+func (r *memorySessionRepository) Active(ctx context.Context, familyID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for _, t := range r.tokens {
+		if t.familyID == familyID && !t.revoked && now.Before(t.expiresAt) {
+			_, err := r.users.Get(ctx, t.userID)
+			return err == nil, nil
+		}
+	}
+	return false, nil
+}
+
+type memoryAuditRepository struct {
+	mu     sync.RWMutex
+	nextID int64
+	rows   []AuditEvent
+}
+
+func (r *memoryAuditRepository) Record(_ context.Context, e AuditEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	e.ID = r.nextID
+	e.OccurredAt = time.Now().UTC()
+	r.rows = append(r.rows, e)
+	return nil
+}
+
+func (r *memoryAuditRepository) List(_ context.Context, filter AuditFilter, opts ListOptions) (Page[AuditEvent], error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var matched []AuditEvent
+	for _, e := range r.rows {
+		if filter.matches(e) {
+			matched = append(matched, e)
+		}
+	}
+	return paginate(matched, AuditEvent.sortValues, opts), nil
+}
+
+type memoryIdempotencyKey struct {
+	userID int
+	key    string
+}
+
+type memoryIdempotencyRepository struct {
+	mu   sync.Mutex
+	rows map[memoryIdempotencyKey]IdempotencyRecord
+}
+
+func (r *memoryIdempotencyRepository) Reserve(_ context.Context, rec IdempotencyRecord, now time.Time) (IdempotencyRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k := memoryIdempotencyKey{rec.UserID, rec.Key}
+	if stored, ok := r.rows[k]; ok && stored.ExpiresAt.After(now) {
+		return stored, ErrDuplicate
+	}
+	rec.StatusCode, rec.Header, rec.Body = 0, nil, nil
+	r.rows[k] = rec
+	return rec, nil
+}
+
+func (r *memoryIdempotencyRepository) Complete(_ context.Context, rec IdempotencyRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k := memoryIdempotencyKey{rec.UserID, rec.Key}
+	stored, ok := r.rows[k]
+	if !ok {
+		return ErrNotFound
+	}
+	stored.StatusCode, stored.Header, stored.Body = rec.StatusCode, rec.Header, rec.Body
+	r.rows[k] = stored
+	return nil
+}
+
+func (r *memoryIdempotencyRepository) Release(_ context.Context, userID int, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.rows, memoryIdempotencyKey{userID, key})
+	return nil
+}
+
+func (r *memoryIdempotencyRepository) Purge(_ context.Context, now time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := 0
+	for k, rec := range r.rows {
+		if !rec.ExpiresAt.After(now) {
+			delete(r.rows, k)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// rateLimitSweepInterval is how often the rate limit stores drop buckets that
+// have refilled.
+const rateLimitSweepInterval = time.Minute
+
+type memoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]tokenBucket
+	sweptAt time.Time
+}
+
+// NewMemoryRateLimitStore returns a rate limit store local to the process, so
+// each replica enforces its limits on its own.
+func NewMemoryRateLimitStore() RateLimitStore {
+	return &memoryRateLimitStore{buckets: map[string]tokenBucket{}}
+}
+
+func (s *memoryRateLimitStore) Take(_ context.Context, key string, limit RateLimit, now time.Time) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.sweptAt) >= rateLimitSweepInterval {
+		for k, b := range s.buckets {
+			if !b.FullAt.After(now) {
+				delete(s.buckets, k)
+			}
+		}
+		s.sweptAt = now
+	}
+	b, res := limit.take(s.buckets[key], now)
+	s.buckets[key] = b
+	return res, nil
+}
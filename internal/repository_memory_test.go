@@ -0,0 +1,150 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// helper to create a handler backed by in-memory repositories
+func newMemoryHandler(t *testing.T) (*Handler, Repositories) {
+	t.Helper()
+	repos := NewMemoryRepositories()
+	return NewHandler(repos, logrus.New(), NewTokenManager("test-secret", time.Minute, time.Hour)), repos
+}
+
+// testSession is the session of the tokens test routers accept.
+const testSession = "test-session"
+
+// testAdmin is who most router tests are signed in as.
+var testAdmin = User{ID: 1, Name: "Admin", Email: "admin@example.com", Role: RoleAdmin}
+
+// testRouter serves h's route table through RegisterRoutes, as main does
+// under /api/v1, behind the request logger and the real auth middleware.
+// Requests that carry no Authorization header are signed in as user; mw
+// adds idempotency or rate limiting.
+func testRouter(t *testing.T, h *Handler, user User, mw RouteMiddleware) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RequestLogger(h.logger))
+	token, err := h.tokens.IssueAccessToken(user, testSession)
+	if err != nil {
+		t.Fatalf("failed to issue test token: %v", err)
+	}
+	r.Use(func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.Request.Header.Set("Authorization", "Bearer "+token)
+		}
+		c.Next()
+	})
+	mountTestRoutes(r, h.tokens, mw, h.Routes())
+	return r
+}
+
+// mountTestRoutes registers routes on the root of r, with auth accepting
+// tokens of testSession.
+func mountTestRoutes(r *gin.Engine, tokens *TokenManager, mw RouteMiddleware, routes []Route) {
+	mw.Auth = AuthMiddleware(tokens, staticSessions{testSession: true})
+	RegisterRoutes(r.Group("/"), mw, routes)
+}
+
+func TestMemoryStudentsPaginateLikePostgres(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h, repos := newMemoryHandler(t)
+	ctx := context.Background()
+	for _, s := range []Student{
+		{Name: "Bob", Age: 22, Email: "bob@example.com"},
+		{Name: "Alice", Age: 20, Email: "alice@example.com"},
+		{Name: "Carol", Age: 19, Email: "carol@example.com"},
+		{Name: "Dave", Age: 17, Email: "dave@example.com"},
+		{Name: "Eve", Age: 30, Email: "eve@other.org"},
+	} {
+		_, err := repos.Students.Create(ctx, s)
+		assert.NoError(t, err)
+	}
+	r := gin.New()
+	r.GET("/students", h.GetStudents)
+
+	get := func(q url.Values) StudentListResponse {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/students?"+q.Encode(), nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp StudentListResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp
+	}
+
+	q := url.Values{"limit": {"2"}, "sort": {"name"}, "min_age": {"18"}, "email_domain": {"example.com"}}
+	first := get(q)
+	assert.Equal(t, 3, first.Total)
+	assert.Equal(t, []string{"Alice", "Bob"}, []string{first.Data[0].Name, first.Data[1].Name})
+	assert.NotEmpty(t, first.NextCursor)
+
+	q.Set("cursor", first.NextCursor)
+	second := get(q)
+	assert.Len(t, second.Data, 1)
+	assert.Equal(t, "Carol", second.Data[0].Name)
+	assert.Empty(t, second.NextCursor)
+}
+
+func TestMemoryUserEmailIsUnique(t *testing.T) {
+	repos := NewMemoryRepositories()
+	ctx := context.Background()
+
+	_, err := repos.Users.Create(ctx, User{Name: "Alice", Email: "alice@example.com"}, "")
+	assert.NoError(t, err)
+	_, err = repos.Users.Create(ctx, User{Name: "Alice again", Email: "alice@example.com"}, "")
+	assert.ErrorIs(t, err, ErrDuplicate)
+}
+
+func TestMemorySessionsRotateAndDetectReuse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h, repos := newMemoryHandler(t)
+	ctx := context.Background()
+	hash, _ := HashPassword("correct-horse")
+	_, err := repos.Users.Create(ctx, User{Name: "Alice", Email: "alice@example.com", Role: RoleTeacher}, hash)
+	assert.NoError(t, err)
+
+	r := gin.New()
+	r.POST("/auth/login", h.Login)
+	r.POST("/auth/refresh", h.Refresh)
+	post := func(path, body string) (int, TokenResponse) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		r.ServeHTTP(w, req)
+		var resp TokenResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	code, login := post("/auth/login", `{"email":"alice@example.com","password":"correct-horse"}`)
+	assert.Equal(t, http.StatusOK, code)
+	claims, err := h.tokens.ParseAccessToken(login.AccessToken)
+	assert.NoError(t, err)
+
+	code, rotated := post("/auth/refresh", `{"refresh_token":"`+login.RefreshToken+`"}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.NotEqual(t, login.RefreshToken, rotated.RefreshToken)
+
+	active, _ := h.SessionActive(ctx, claims.SessionID)
+	assert.True(t, active)
+
+	// Replaying the first token revokes the session, including the new token.
+	code, _ = post("/auth/refresh", `{"refresh_token":"`+login.RefreshToken+`"}`)
+	assert.Equal(t, http.StatusUnauthorized, code)
+	active, _ = h.SessionActive(ctx, claims.SessionID)
+	assert.False(t, active)
+	code, _ = post("/auth/refresh", `{"refresh_token":"`+rotated.RefreshToken+`"}`)
+	assert.Equal(t, http.StatusUnauthorized, code)
+}
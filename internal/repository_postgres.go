@@ -0,0 +1,1171 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// NewPostgresRepositories returns repositories backed by the PostgreSQL
+// connection pool.
+func NewPostgresRepositories(d *Db) Repositories {
+	return newPostgresRepositories(d.db, d.db)
+}
+
+// newPostgresRepositories builds the repositories on q, which is either the
+// pool itself or a transaction InTx began on it.
+func newPostgresRepositories(pool *sql.DB, q querier) Repositories {
+	repos := Repositories{
+		Students:    &pgStudentRepository{db: q, pool: pool},
+		Users:       &pgUserRepository{db: q, pool: pool},
+		Sessions:    &pgSessionRepository{db: pool},
+		Courses:     &pgCourseRepository{db: pool},
+		Grades:      &pgGradeRepository{db: pool},
+		Attendance:  &pgAttendanceRepository{db: pool},
+		Audit:       &pgAuditRepository{db: q},
+		Idempotency: &pgIdempotencyRepository{db: pool},
+	}
+	if _, inTx := q.(*sql.Tx); inTx {
+		repos.InTx = func(_ context.Context, fn func(Repositories) error) error {
+			return fn(repos)
+		}
+		return repos
+	}
+	repos.InTx = func(ctx context.Context, fn func(Repositories) error) error {
+		tx, err := pool.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		if err := fn(newPostgresRepositories(pool, tx)); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+	return repos
+}
+
+// pgStudentRepository runs its statements on db, which is a transaction
+// inside InTx. Exports need a transaction of their own and always begin it
+// on pool.
+type pgStudentRepository struct {
+	db   querier
+	pool *sql.DB
+}
+
+// studentColumns selects a student as scanStudent reads it.
+const studentColumns = "id, name, age, email, deleted_at, version"
+
+func scanStudent(row interface{ Scan(...any) error }) (Student, error) {
+	var s Student
+	err := row.Scan(&s.ID, &s.Name, &s.Age, &s.Email, &s.DeletedAt, &s.Version)
+	return s, err
+}
+
+// withVersion makes an UPDATE of a student conditional on its version,
+// unless version is zero.
+func withVersion(query string, args []any, version int) (string, []any) {
+	if version == 0 {
+		return query, args
+	}
+	args = append(args, version)
+	return fmt.Sprintf("%s AND version = $%d", query, len(args)), args
+}
+
+// missing explains why a write to a live student matched no row: the
+// student is gone, or it has moved past the version the write required.
+func (r *pgStudentRepository) missing(ctx context.Context, id, version int) error {
+	if version == 0 {
+		return ErrNotFound
+	}
+	if _, err := r.Get(ctx, id); err != nil {
+		return err
+	}
+	return ErrVersionMismatch
+}
+
+func (r *pgStudentRepository) List(ctx context.Context, filter StudentFilter, opts ListOptions) (Page[Student], error) {
+	var (
+		page  Page[Student]
+		where whereBuilder
+	)
+	filter.apply(&where)
+
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM students"+where.String(), where.args...).Scan(&page.Total); err != nil {
+		return page, fmt.Errorf("error counting students: %w", err)
+	}
+
+	where.addKeyset(opts)
+	// Fetch one extra row to learn whether another page follows.
+	query := fmt.Sprintf("SELECT %s FROM students%s %s LIMIT %d", studentColumns, where.String(), opts.OrderBy(), opts.Limit+1)
+	rows, err := r.db.QueryContext(ctx, query, where.args...)
+	if err != nil {
+		return page, fmt.Errorf("error querying students: %w", err)
+	}
+	defer rows.Close()
+
+	page.Items = make([]Student, 0, opts.Limit)
+	for rows.Next() {
+		s, err := scanStudent(rows)
+		if err != nil {
+			return page, fmt.Errorf("error scanning student row: %w", err)
+		}
+		page.Items = append(page.Items, s)
+	}
+	if err := rows.Err(); err != nil {
+		return page, fmt.Errorf("error iterating student rows: %w", err)
+	}
+
+	if len(page.Items) > opts.Limit {
+		page.Items = page.Items[:opts.Limit]
+		page.HasMore = true
+	}
+	return page, nil
+}
+
+func (r *pgStudentRepository) Export(ctx context.Context, filter StudentFilter, sort []SortField, fn func(Student) error) error {
+	var where whereBuilder
+	filter.apply(&where)
+	query := fmt.Sprintf("SELECT %s FROM students%s %s", studentColumns, where.String(), ListOptions{Sort: sort}.OrderBy())
+	return streamCursor(ctx, r.pool, query, where.args, func(rows *sql.Rows) error {
+		s, err := scanStudent(rows)
+		if err != nil {
+			return fmt.Errorf("error scanning student row: %w", err)
+		}
+		return fn(s)
+	})
+}
+
+func (r *pgStudentRepository) Get(ctx context.Context, id int) (Student, error) {
+	s, err := scanStudent(r.db.QueryRowContext(ctx, "SELECT "+studentColumns+" FROM students WHERE id = $1 AND deleted_at IS NULL", id))
+	if err == sql.ErrNoRows {
+		return s, ErrNotFound
+	}
+	return s, err
+}
+
+func (r *pgStudentRepository) GetIncludingDeleted(ctx context.Context, id int) (Student, error) {
+	s, err := scanStudent(r.db.QueryRowContext(ctx, "SELECT "+studentColumns+" FROM students WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return s, ErrNotFound
+	}
+	return s, err
+}
+
+func (r *pgStudentRepository) Create(ctx context.Context, s Student) (Student, error) {
+	err := r.db.QueryRowContext(ctx,
+		"INSERT INTO students (name, age, email) VALUES ($1, $2, $3) RETURNING id, version",
+		s.Name, s.Age, s.Email,
+	).Scan(&s.ID, &s.Version)
+	return s, translatePgError(err)
+}
+
+func (r *pgStudentRepository) Update(ctx context.Context, s Student) (Student, error) {
+	query, args := withVersion(
+		"UPDATE students SET name = $1, age = $2, email = $3, version = version + 1 WHERE id = $4 AND deleted_at IS NULL",
+		[]any{s.Name, s.Age, s.Email, s.ID}, s.Version,
+	)
+	updated, err := scanStudent(r.db.QueryRowContext(ctx, query+" RETURNING "+studentColumns, args...))
+	if err == sql.ErrNoRows {
+		return updated, r.missing(ctx, s.ID, s.Version)
+	}
+	return updated, translatePgError(err)
+}
+
+func (r *pgStudentRepository) Patch(ctx context.Context, id int, p StudentPatch) (Student, error) {
+	if p.Empty() {
+		s, err := r.Get(ctx, id)
+		if err == nil && p.Version != 0 && s.Version != p.Version {
+			err = ErrVersionMismatch
+		}
+		return s, err
+	}
+
+	var (
+		sets []string
+		args []any
+	)
+	set := func(column string, value any) {
+		args = append(args, value)
+		sets = append(sets, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+	if p.Name != nil {
+		set("name", *p.Name)
+	}
+	if p.Age != nil {
+		set("age", *p.Age)
+	}
+	if p.Email != nil {
+		set("email", *p.Email)
+	}
+	sets = append(sets, "version = version + 1")
+	args = append(args, id)
+
+	query, args := withVersion(
+		fmt.Sprintf("UPDATE students SET %s WHERE id = $%d AND deleted_at IS NULL", strings.Join(sets, ", "), len(args)),
+		args, p.Version,
+	)
+	updated, err := scanStudent(r.db.QueryRowContext(ctx, query+" RETURNING "+studentColumns, args...))
+	if err == sql.ErrNoRows {
+		return updated, r.missing(ctx, id, p.Version)
+	}
+	return updated, translatePgError(err)
+}
+
+func (r *pgStudentRepository) Delete(ctx context.Context, id, version int) error {
+	query, args := withVersion(
+		"UPDATE students SET deleted_at = now(), version = version + 1 WHERE id = $1 AND deleted_at IS NULL",
+		[]any{id}, version,
+	)
+	res, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	err = expectAffected(res)
+	if errors.Is(err, ErrNotFound) {
+		return r.missing(ctx, id, version)
+	}
+	return err
+}
+
+func (r *pgStudentRepository) Restore(ctx context.Context, id int) (Student, error) {
+	// Restoring fails on the partial unique index if another student took
+	// the email in the meantime.
+	s, err := scanStudent(r.db.QueryRowContext(ctx,
+		`UPDATE students SET deleted_at = NULL, version = CASE WHEN deleted_at IS NULL THEN version ELSE version + 1 END
+		WHERE id = $1 RETURNING `+studentColumns, id,
+	))
+	if err == sql.ErrNoRows {
+		return s, ErrNotFound
+	}
+	return s, translatePgError(err)
+}
+
+func (r *pgStudentRepository) Purge(ctx context.Context, cutoff time.Time) (int, error) {
+	return purgeDeleted(ctx, r.db, "students", cutoff)
+}
+
+func (r *pgStudentRepository) BeginImport(ctx context.Context) (StudentImport, error) {
+	tx, ok := r.db.(*sql.Tx)
+	if !ok {
+		return nil, errors.New("student imports must run inside InTx")
+	}
+	// Rows are copied into a scratch table first so conflicts with existing
+	// students can be reported per line before anything is stored.
+	if _, err := tx.ExecContext(ctx,
+		"CREATE TEMP TABLE student_import (line INTEGER, name TEXT, age INTEGER, email TEXT) ON COMMIT DROP",
+	); err != nil {
+		return nil, fmt.Errorf("error creating import table: %w", err)
+	}
+	// The statement is closed by Finish, or with the transaction.
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("student_import", "line", "name", "age", "email"))
+	if err != nil {
+		return nil, fmt.Errorf("error starting copy: %w", err)
+	}
+	return &pgStudentImport{tx: tx, copy: stmt}, nil
+}
+
+type pgStudentImport struct {
+	tx   *sql.Tx
+	copy *sql.Stmt
+}
+
+func (i *pgStudentImport) Add(line int, s Student) error {
+	_, err := i.copy.Exec(line, s.Name, s.Age, s.Email)
+	return err
+}
+
+func (i *pgStudentImport) Finish(ctx context.Context, commit bool) ([]Student, []int, error) {
+	// Exec without arguments flushes the COPY.
+	if _, err := i.copy.ExecContext(ctx); err != nil {
+		return nil, nil, fmt.Errorf("error copying import rows: %w", err)
+	}
+	if err := i.copy.Close(); err != nil {
+		return nil, nil, fmt.Errorf("error copying import rows: %w", err)
+	}
+
+	conflicts, err := i.conflicts(ctx)
+	if err != nil || !commit || len(conflicts) > 0 {
+		return nil, conflicts, err
+	}
+
+	rows, err := i.tx.QueryContext(ctx,
+		"INSERT INTO students (name, age, email) SELECT name, age, email FROM student_import ORDER BY line RETURNING "+studentColumns,
+	)
+	if err != nil {
+		return nil, nil, translatePgError(err)
+	}
+	defer rows.Close()
+	var stored []Student
+	for rows.Next() {
+		s, err := scanStudent(rows)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error scanning imported student: %w", err)
+		}
+		stored = append(stored, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, translatePgError(err)
+	}
+	return stored, nil, nil
+}
+
+// conflicts lists the lines whose email belongs to a student already.
+func (i *pgStudentImport) conflicts(ctx context.Context) ([]int, error) {
+	rows, err := i.tx.QueryContext(ctx,
+		"SELECT i.line FROM student_import i JOIN students s ON lower(s.email) = lower(i.email) AND s.deleted_at IS NULL ORDER BY i.line",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error checking import conflicts: %w", err)
+	}
+	defer rows.Close()
+	var conflicts []int
+	for rows.Next() {
+		var line int
+		if err := rows.Scan(&line); err != nil {
+			return nil, fmt.Errorf("error scanning import conflict: %w", err)
+		}
+		conflicts = append(conflicts, line)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating import conflicts: %w", err)
+	}
+	return conflicts, nil
+}
+
+// pgUserRepository splits its statements between db and pool like
+// pgStudentRepository.
+type pgUserRepository struct {
+	db   querier
+	pool *sql.DB
+}
+
+// userColumns selects a user as scanUser reads it.
+const userColumns = "id, name, email, role, student_id, deleted_at"
+
+func scanUser(row interface{ Scan(...any) error }) (User, error) {
+	var u User
+	err := row.Scan(&u.ID, &u.Name, &u.Email, &u.Role, &u.StudentID, &u.DeletedAt)
+	return u, err
+}
+
+func (r *pgUserRepository) List(ctx context.Context, filter UserFilter, opts ListOptions) (Page[User], error) {
+	var (
+		page  Page[User]
+		where whereBuilder
+	)
+	filter.apply(&where)
+
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM users"+where.String(), where.args...).Scan(&page.Total); err != nil {
+		return page, fmt.Errorf("error counting users: %w", err)
+	}
+
+	where.addKeyset(opts)
+	query := fmt.Sprintf("SELECT %s FROM users%s %s LIMIT %d", userColumns, where.String(), opts.OrderBy(), opts.Limit+1)
+	rows, err := r.db.QueryContext(ctx, query, where.args...)
+	if err != nil {
+		return page, fmt.Errorf("error querying users: %w", err)
+	}
+	defer rows.Close()
+
+	page.Items = make([]User, 0, opts.Limit)
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return page, fmt.Errorf("error scanning user row: %w", err)
+		}
+		page.Items = append(page.Items, u)
+	}
+	if err := rows.Err(); err != nil {
+		return page, fmt.Errorf("error iterating user rows: %w", err)
+	}
+
+	if len(page.Items) > opts.Limit {
+		page.Items = page.Items[:opts.Limit]
+		page.HasMore = true
+	}
+	return page, nil
+}
+
+func (r *pgUserRepository) Export(ctx context.Context, filter UserFilter, sort []SortField, fn func(User) error) error {
+	var where whereBuilder
+	filter.apply(&where)
+	query := fmt.Sprintf("SELECT %s FROM users%s %s", userColumns, where.String(), ListOptions{Sort: sort}.OrderBy())
+	return streamCursor(ctx, r.pool, query, where.args, func(rows *sql.Rows) error {
+		u, err := scanUser(rows)
+		if err != nil {
+			return fmt.Errorf("error scanning user row: %w", err)
+		}
+		return fn(u)
+	})
+}
+
+func (r *pgUserRepository) Get(ctx context.Context, id int) (User, error) {
+	u, err := scanUser(r.db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE id = $1 AND deleted_at IS NULL", id))
+	if err == sql.ErrNoRows {
+		return u, ErrNotFound
+	}
+	return u, err
+}
+
+func (r *pgUserRepository) GetIncludingDeleted(ctx context.Context, id int) (User, error) {
+	u, err := scanUser(r.db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return u, ErrNotFound
+	}
+	return u, err
+}
+
+func (r *pgUserRepository) GetCredentials(ctx context.Context, email string) (User, string, error) {
+	var (
+		u    User
+		hash sql.NullString
+	)
+	err := r.db.QueryRowContext(ctx,
+		"SELECT id, name, email, role, student_id, password_hash FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL", email,
+	).Scan(&u.ID, &u.Name, &u.Email, &u.Role, &u.StudentID, &hash)
+	if err == sql.ErrNoRows {
+		return u, "", ErrNotFound
+	}
+	return u, hash.String, err
+}
+
+func (r *pgUserRepository) Create(ctx context.Context, u User, passwordHash string) (User, error) {
+	hash := sql.NullString{String: passwordHash, Valid: passwordHash != ""}
+	err := r.db.QueryRowContext(ctx,
+		"INSERT INTO users (name, email, role, student_id, password_hash) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+		u.Name, u.Email, u.Role, u.StudentID, hash,
+	).Scan(&u.ID)
+	return u, translatePgError(err)
+}
+
+func (r *pgUserRepository) Delete(ctx context.Context, id int) error {
+	// Sessions of deleted users stop being active, see pgSessionRepository.
+	res, err := r.db.ExecContext(ctx, "UPDATE users SET deleted_at = now() WHERE id = $1 AND deleted_at IS NULL", id)
+	if err != nil {
+		return err
+	}
+	return expectAffected(res)
+}
+
+func (r *pgUserRepository) Purge(ctx context.Context, cutoff time.Time) (int, error) {
+	return purgeDeleted(ctx, r.db, "users", cutoff)
+}
+
+type pgCourseRepository struct {
+	db *sql.DB
+}
+
+// courseColumns selects a course with its current enrollment count, which
+// leaves out deleted students.
+const courseColumns = "id, code, title, credits, capacity, term, " +
+	"(SELECT COUNT(*) FROM enrollments e JOIN students s ON s.id = e.student_id WHERE e.course_id = courses.id AND s.deleted_at IS NULL)"
+
+func scanCourse(row interface{ Scan(...any) error }) (Course, error) {
+	var c Course
+	err := row.Scan(&c.ID, &c.Code, &c.Title, &c.Credits, &c.Capacity, &c.Term, &c.Enrolled)
+	return c, err
+}
+
+func (r *pgCourseRepository) List(ctx context.Context, filter CourseFilter, opts ListOptions) (Page[Course], error) {
+	var (
+		page  Page[Course]
+		where whereBuilder
+	)
+	filter.apply(&where)
+
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM courses"+where.String(), where.args...).Scan(&page.Total); err != nil {
+		return page, fmt.Errorf("error counting courses: %w", err)
+	}
+
+	where.addKeyset(opts)
+	query := fmt.Sprintf("SELECT %s FROM courses%s %s LIMIT %d", courseColumns, where.String(), opts.OrderBy(), opts.Limit+1)
+	rows, err := r.db.QueryContext(ctx, query, where.args...)
+	if err != nil {
+		return page, fmt.Errorf("error querying courses: %w", err)
+	}
+	defer rows.Close()
+
+	page.Items = make([]Course, 0, opts.Limit)
+	for rows.Next() {
+		c, err := scanCourse(rows)
+		if err != nil {
+			return page, fmt.Errorf("error scanning course row: %w", err)
+		}
+		page.Items = append(page.Items, c)
+	}
+	if err := rows.Err(); err != nil {
+		return page, fmt.Errorf("error iterating course rows: %w", err)
+	}
+
+	if len(page.Items) > opts.Limit {
+		page.Items = page.Items[:opts.Limit]
+		page.HasMore = true
+	}
+	return page, nil
+}
+
+func (r *pgCourseRepository) Get(ctx context.Context, id int) (Course, error) {
+	c, err := scanCourse(r.db.QueryRowContext(ctx, "SELECT "+courseColumns+" FROM courses WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return c, ErrNotFound
+	}
+	return c, err
+}
+
+func (r *pgCourseRepository) Create(ctx context.Context, c Course) (Course, error) {
+	err := r.db.QueryRowContext(ctx,
+		"INSERT INTO courses (code, title, credits, capacity, term) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+		c.Code, c.Title, c.Credits, c.Capacity, c.Term,
+	).Scan(&c.ID)
+	c.Enrolled = 0
+	return c, translatePgError(err)
+}
+
+func (r *pgCourseRepository) Update(ctx context.Context, c Course) (Course, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Course{}, err
+	}
+	defer tx.Rollback()
+
+	// The same row lock Enroll takes, so no student can enroll between
+	// counting the seats and shrinking the course.
+	current, err := scanCourse(tx.QueryRowContext(ctx, "SELECT "+courseColumns+" FROM courses WHERE id = $1 FOR UPDATE", c.ID))
+	if err == sql.ErrNoRows {
+		return Course{}, ErrNotFound
+	} else if err != nil {
+		return Course{}, err
+	}
+	if c.Capacity < current.Enrolled {
+		return current, ErrCapacityBelowEnrollment
+	}
+
+	updated, err := scanCourse(tx.QueryRowContext(ctx,
+		"UPDATE courses SET code = $1, title = $2, credits = $3, capacity = $4, term = $5 WHERE id = $6 RETURNING "+courseColumns,
+		c.Code, c.Title, c.Credits, c.Capacity, c.Term, c.ID,
+	))
+	if err != nil {
+		return updated, translatePgError(err)
+	}
+	return updated, tx.Commit()
+}
+
+func (r *pgCourseRepository) Delete(ctx context.Context, id int) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM courses WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	return expectAffected(res)
+}
+
+func (r *pgCourseRepository) Enroll(ctx context.Context, studentID, courseID int) (Enrollment, error) {
+	e := Enrollment{StudentID: studentID, CourseID: courseID}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return e, err
+	}
+	defer tx.Rollback()
+
+	// Locking the course row serialises enrollments into the same course, so
+	// two requests cannot both take the last seat.
+	var capacity int
+	err = tx.QueryRowContext(ctx, "SELECT capacity FROM courses WHERE id = $1 FOR UPDATE", courseID).Scan(&capacity)
+	if err == sql.ErrNoRows {
+		return e, ErrNotFound
+	} else if err != nil {
+		return e, err
+	}
+
+	// Deleted students do not hold a seat.
+	var (
+		enrolled int
+		already  bool
+	)
+	if err := tx.QueryRowContext(ctx,
+		"SELECT COUNT(*), COALESCE(bool_or(e.student_id = $2), false) FROM enrollments e JOIN students s ON s.id = e.student_id WHERE e.course_id = $1 AND s.deleted_at IS NULL",
+		courseID, studentID,
+	).Scan(&enrolled, &already); err != nil {
+		return e, err
+	}
+	if already {
+		return e, ErrAlreadyEnrolled
+	}
+	if enrolled >= capacity {
+		return e, ErrCourseFull
+	}
+
+	err = tx.QueryRowContext(ctx,
+		"INSERT INTO enrollments (student_id, course_id) VALUES ($1, $2) RETURNING enrolled_at",
+		studentID, courseID,
+	).Scan(&e.EnrolledAt)
+	if err = translatePgError(err); errors.Is(err, ErrDuplicate) {
+		return e, ErrAlreadyEnrolled
+	} else if err != nil {
+		return e, err
+	}
+	return e, tx.Commit()
+}
+
+func (r *pgCourseRepository) ListStudents(ctx context.Context, courseID int, opts ListOptions) (Page[Student], error) {
+	var (
+		page  Page[Student]
+		where whereBuilder
+	)
+	where.add("e.course_id = ?", courseID)
+	where.add("students.deleted_at IS NULL")
+
+	const from = " FROM students JOIN enrollments e ON e.student_id = students.id"
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*)"+from+where.String(), where.args...).Scan(&page.Total); err != nil {
+		return page, fmt.Errorf("error counting enrolled students: %w", err)
+	}
+
+	where.addKeyset(opts)
+	query := fmt.Sprintf("SELECT id, name, age, email, version%s%s %s LIMIT %d", from, where.String(), opts.OrderBy(), opts.Limit+1)
+	rows, err := r.db.QueryContext(ctx, query, where.args...)
+	if err != nil {
+		return page, fmt.Errorf("error querying enrolled students: %w", err)
+	}
+	defer rows.Close()
+
+	page.Items = make([]Student, 0, opts.Limit)
+	for rows.Next() {
+		var s Student
+		if err := rows.Scan(&s.ID, &s.Name, &s.Age, &s.Email, &s.Version); err != nil {
+			return page, fmt.Errorf("error scanning student row: %w", err)
+		}
+		page.Items = append(page.Items, s)
+	}
+	if err := rows.Err(); err != nil {
+		return page, fmt.Errorf("error iterating student rows: %w", err)
+	}
+
+	if len(page.Items) > opts.Limit {
+		page.Items = page.Items[:opts.Limit]
+		page.HasMore = true
+	}
+	return page, nil
+}
+
+type pgGradeRepository struct {
+	db *sql.DB
+}
+
+func (r *pgGradeRepository) Record(ctx context.Context, g Grade) (Grade, error) {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO grades (student_id, course_id, assessment, score, weight)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (student_id, course_id, assessment)
+		DO UPDATE SET score = EXCLUDED.score, weight = EXCLUDED.weight, graded_at = now()
+		RETURNING id, graded_at`,
+		g.StudentID, g.CourseID, g.Assessment, g.Score, g.Weight,
+	).Scan(&g.ID, &g.GradedAt)
+
+	// The only foreign key points at the enrollment being graded.
+	var ce *ConstraintError
+	if err = translatePgError(err); errors.As(err, &ce) && ce.Kind == ConstraintForeignKey {
+		return g, ErrNotEnrolled
+	}
+	return g, err
+}
+
+func (r *pgGradeRepository) ListByStudent(ctx context.Context, studentID int) ([]CourseGrades, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT c.id, c.code, c.title, c.credits, c.term, g.id, g.assessment, g.score, g.weight, g.graded_at
+		FROM grades g
+		JOIN courses c ON c.id = g.course_id
+		WHERE g.student_id = $1
+		ORDER BY c.term, c.code, c.id, g.assessment`,
+		studentID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error querying grades: %w", err)
+	}
+	defer rows.Close()
+
+	var graded []CourseGrades
+	for rows.Next() {
+		var (
+			c Course
+			g = Grade{StudentID: studentID}
+		)
+		if err := rows.Scan(&c.ID, &c.Code, &c.Title, &c.Credits, &c.Term, &g.ID, &g.Assessment, &g.Score, &g.Weight, &g.GradedAt); err != nil {
+			return nil, fmt.Errorf("error scanning grade row: %w", err)
+		}
+		g.CourseID = c.ID
+		// Rows arrive grouped by course, so a new course starts a new group.
+		if n := len(graded); n == 0 || graded[n-1].Course.ID != c.ID {
+			graded = append(graded, CourseGrades{Course: c})
+		}
+		last := &graded[len(graded)-1]
+		last.Grades = append(last.Grades, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating grade rows: %w", err)
+	}
+	return graded, nil
+}
+
+type pgAttendanceRepository struct {
+	db *sql.DB
+}
+
+func (r *pgAttendanceRepository) Record(ctx context.Context, day time.Time, entries []AttendanceEntry) error {
+	ids := make([]int64, len(entries))
+	statuses := make([]string, len(entries))
+	for i, e := range entries {
+		ids[i], statuses[i] = int64(e.StudentID), string(e.Status)
+	}
+	// A single statement keeps the batch all or nothing.
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO attendance (student_id, day, status)
+		SELECT e.student_id, $1::date, e.status
+		FROM unnest($2::int[], $3::text[]) AS e (student_id, status)
+		ON CONFLICT (student_id, day)
+		DO UPDATE SET status = EXCLUDED.status, recorded_at = now()`,
+		day, pq.Array(ids), pq.Array(statuses),
+	)
+	return translatePgError(err)
+}
+
+// attendanceSummaryQuery aggregates attendance per student between $1 and $2,
+// leaving out deleted students. Excused days count towards neither side of
+// the rate.
+const attendanceSummaryQuery = `
+	SELECT student_id,
+		COUNT(*),
+		COUNT(*) FILTER (WHERE status = 'present'),
+		COUNT(*) FILTER (WHERE status = 'late'),
+		COUNT(*) FILTER (WHERE status = 'absent'),
+		COUNT(*) FILTER (WHERE status = 'excused'),
+		ROUND(COUNT(*) FILTER (WHERE status IN ('present', 'late'))::numeric
+			/ NULLIF(COUNT(*) FILTER (WHERE status <> 'excused'), 0), 4)
+	FROM attendance
+	JOIN students s ON s.id = attendance.student_id
+	WHERE day BETWEEN $1::date AND $2::date AND s.deleted_at IS NULL`
+
+func scanAttendanceSummary(row interface{ Scan(...any) error }) (AttendanceSummary, error) {
+	var (
+		s    AttendanceSummary
+		rate sql.NullFloat64
+	)
+	err := row.Scan(&s.StudentID, &s.Days, &s.Present, &s.Late, &s.Absent, &s.Excused, &rate)
+	if rate.Valid {
+		s.Rate = &rate.Float64
+	}
+	return s, err
+}
+
+func (r *pgAttendanceRepository) Report(ctx context.Context, from, to time.Time) ([]AttendanceSummary, error) {
+	rows, err := r.db.QueryContext(ctx, attendanceSummaryQuery+" GROUP BY student_id ORDER BY student_id", from, to)
+	if err != nil {
+		return nil, fmt.Errorf("error querying attendance: %w", err)
+	}
+	defer rows.Close()
+
+	report := []AttendanceSummary{}
+	for rows.Next() {
+		s, err := scanAttendanceSummary(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning attendance row: %w", err)
+		}
+		report = append(report, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating attendance rows: %w", err)
+	}
+	return report, nil
+}
+
+func (r *pgAttendanceRepository) Summary(ctx context.Context, studentID int, from, to time.Time) (AttendanceSummary, error) {
+	s, err := scanAttendanceSummary(r.db.QueryRowContext(ctx,
+		attendanceSummaryQuery+" AND student_id = $3 GROUP BY student_id", from, to, studentID,
+	))
+	if err == sql.ErrNoRows {
+		return AttendanceSummary{StudentID: studentID}, nil
+	}
+	return s, err
+}
+
+type pgSessionRepository struct {
+	db *sql.DB
+}
+
+func (r *pgSessionRepository) Create(ctx context.Context, userID int, familyID, tokenHash string, expiresAt time.Time) error {
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at) VALUES ($1, $2, $3, $4)",
+		userID, familyID, tokenHash, expiresAt,
+	)
+	return err
+}
+
+func (r *pgSessionRepository) Rotate(ctx context.Context, tokenHash, newHash string, expiresAt time.Time) (User, string, error) {
+	var (
+		u          User
+		tokenID    int64
+		familyID   string
+		tokenExp   time.Time
+		revokedAt  sql.NullTime
+		replacedBy sql.NullInt64
+	)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return u, "", err
+	}
+	defer tx.Rollback()
+
+	// Lock the row so two concurrent refreshes with the same token cannot
+	// both rotate it.
+	err = tx.QueryRowContext(ctx,
+		`SELECT rt.id, rt.family_id, rt.expires_at, rt.revoked_at, rt.replaced_by, u.id, u.name, u.email, u.role, u.student_id
+		FROM refresh_tokens rt JOIN users u ON u.id = rt.user_id
+		WHERE rt.token_hash = $1 AND u.deleted_at IS NULL FOR UPDATE OF rt`,
+		tokenHash,
+	).Scan(&tokenID, &familyID, &tokenExp, &revokedAt, &replacedBy, &u.ID, &u.Name, &u.Email, &u.Role, &u.StudentID)
+	if err == sql.ErrNoRows {
+		return u, "", ErrRefreshTokenInvalid
+	} else if err != nil {
+		return u, "", err
+	}
+
+	if replacedBy.Valid {
+		// The token was already exchanged once; whoever holds it now may have
+		// stolen it, so kill every token in the family.
+		if err := revokeFamily(ctx, tx, familyID); err != nil {
+			return u, "", err
+		}
+		if err := tx.Commit(); err != nil {
+			return u, "", err
+		}
+		return u, familyID, ErrRefreshTokenReused
+	}
+	if revokedAt.Valid || time.Now().After(tokenExp) {
+		return u, "", ErrRefreshTokenInvalid
+	}
+
+	var newID int64
+	if err := tx.QueryRowContext(ctx,
+		"INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at) VALUES ($1, $2, $3, $4) RETURNING id",
+		u.ID, familyID, newHash, expiresAt,
+	).Scan(&newID); err != nil {
+		return u, "", err
+	}
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE refresh_tokens SET revoked_at = now(), replaced_by = $1 WHERE id = $2",
+		newID, tokenID,
+	); err != nil {
+		return u, "", err
+	}
+	return u, familyID, tx.Commit()
+}
+
+func (r *pgSessionRepository) Revoke(ctx context.Context, familyID string) error {
+	return revokeFamily(ctx, r.db, familyID)
+}
+
+func (r *pgSessionRepository) Active(ctx context.Context, familyID string) (bool, error) {
+	var active bool
+	err := r.db.QueryRowContext(ctx,
+		`SELECT EXISTS (
+			SELECT 1 FROM refresh_tokens rt JOIN users u ON u.id = rt.user_id
+			WHERE rt.family_id = $1 AND rt.revoked_at IS NULL AND rt.expires_at > now() AND u.deleted_at IS NULL
+		)`,
+		familyID,
+	).Scan(&active)
+	return active, err
+}
+
+type pgAuditRepository struct {
+	db querier
+}
+
+// auditColumns selects an audit event as scanAuditEvent reads it.
+const auditColumns = "id, occurred_at, actor_id, actor_email, action, entity_type, entity_id, changes, request_id, client_ip"
+
+func scanAuditEvent(row interface{ Scan(...any) error }) (AuditEvent, error) {
+	var (
+		e          AuditEvent
+		actorEmail sql.NullString
+		requestID  sql.NullString
+		changes    []byte
+	)
+	err := row.Scan(&e.ID, &e.OccurredAt, &e.ActorID, &actorEmail, &e.Action, &e.EntityType, &e.EntityID, &changes, &requestID, &e.ClientIP)
+	if err != nil {
+		return e, err
+	}
+	e.ActorEmail = actorEmail.String
+	e.RequestID = requestID.String
+	return e, json.Unmarshal(changes, &e.Changes)
+}
+
+func (r *pgAuditRepository) Record(ctx context.Context, e AuditEvent) error {
+	changes, err := json.Marshal(e.Changes)
+	if err != nil {
+		return fmt.Errorf("error encoding audit changes: %w", err)
+	}
+	_, err = r.db.ExecContext(ctx,
+		`INSERT INTO audit_events (actor_id, actor_email, action, entity_type, entity_id, changes, request_id, client_ip)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		e.ActorID, sql.NullString{String: e.ActorEmail, Valid: e.ActorEmail != ""}, e.Action, e.EntityType, e.EntityID,
+		changes, sql.NullString{String: e.RequestID, Valid: e.RequestID != ""}, e.ClientIP,
+	)
+	return err
+}
+
+func (r *pgAuditRepository) List(ctx context.Context, filter AuditFilter, opts ListOptions) (Page[AuditEvent], error) {
+	var (
+		page  Page[AuditEvent]
+		where whereBuilder
+	)
+	filter.apply(&where)
+
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM audit_events"+where.String(), where.args...).Scan(&page.Total); err != nil {
+		return page, fmt.Errorf("error counting audit events: %w", err)
+	}
+
+	where.addKeyset(opts)
+	query := fmt.Sprintf("SELECT %s FROM audit_events%s %s LIMIT %d", auditColumns, where.String(), opts.OrderBy(), opts.Limit+1)
+	rows, err := r.db.QueryContext(ctx, query, where.args...)
+	if err != nil {
+		return page, fmt.Errorf("error querying audit events: %w", err)
+	}
+	defer rows.Close()
+
+	page.Items = make([]AuditEvent, 0, opts.Limit)
+	for rows.Next() {
+		e, err := scanAuditEvent(rows)
+		if err != nil {
+			return page, fmt.Errorf("error scanning audit event row: %w", err)
+		}
+		page.Items = append(page.Items, e)
+	}
+	if err := rows.Err(); err != nil {
+		return page, fmt.Errorf("error iterating audit event rows: %w", err)
+	}
+
+	if len(page.Items) > opts.Limit {
+		page.Items = page.Items[:opts.Limit]
+		page.HasMore = true
+	}
+	return page, nil
+}
+
+type pgIdempotencyRepository struct {
+	db *sql.DB
+}
+
+func (r *pgIdempotencyRepository) Reserve(ctx context.Context, rec IdempotencyRecord, now time.Time) (IdempotencyRecord, error) {
+	// An expired key is claimed afresh, as if the purge had already
+	// removed it.
+	res, err := r.db.ExecContext(ctx,
+		`INSERT INTO idempotency_keys (user_id, key, request_hash, expires_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, key) DO UPDATE
+		SET request_hash = EXCLUDED.request_hash, status_code = NULL, headers = NULL, body = NULL, expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= $5`,
+		rec.UserID, rec.Key, rec.RequestHash, rec.ExpiresAt, now,
+	)
+	if err != nil {
+		return rec, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return rec, err
+	}
+
+	stored := IdempotencyRecord{UserID: rec.UserID, Key: rec.Key}
+	var (
+		status  sql.NullInt64
+		headers []byte
+	)
+	err = r.db.QueryRowContext(ctx,
+		"SELECT request_hash, status_code, headers, body, expires_at FROM idempotency_keys WHERE user_id = $1 AND key = $2",
+		rec.UserID, rec.Key,
+	).Scan(&stored.RequestHash, &status, &headers, &stored.Body, &stored.ExpiresAt)
+	if err != nil {
+		return stored, fmt.Errorf("error reading idempotency key: %w", err)
+	}
+	stored.StatusCode = int(status.Int64)
+	if headers != nil {
+		if err := json.Unmarshal(headers, &stored.Header); err != nil {
+			return stored, fmt.Errorf("error decoding stored headers: %w", err)
+		}
+	}
+	return stored, ErrDuplicate
+}
+
+func (r *pgIdempotencyRepository) Complete(ctx context.Context, rec IdempotencyRecord) error {
+	headers, err := json.Marshal(rec.Header)
+	if err != nil {
+		return err
+	}
+	res, err := r.db.ExecContext(ctx,
+		"UPDATE idempotency_keys SET status_code = $1, headers = $2, body = $3 WHERE user_id = $4 AND key = $5",
+		rec.StatusCode, headers, rec.Body, rec.UserID, rec.Key,
+	)
+	if err != nil {
+		return err
+	}
+	return expectAffected(res)
+}
+
+func (r *pgIdempotencyRepository) Release(ctx context.Context, userID int, key string) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2", userID, key)
+	return err
+}
+
+func (r *pgIdempotencyRepository) Purge(ctx context.Context, now time.Time) (int, error) {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE expires_at <= $1", now)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+type pgRateLimitStore struct {
+	db      *sql.DB
+	mu      sync.Mutex
+	sweptAt time.Time
+}
+
+// NewPostgresRateLimitStore returns a rate limit store that keeps the buckets
+// in the database, so every replica draws from the same budget.
+func NewPostgresRateLimitStore(d *Db) RateLimitStore {
+	return &pgRateLimitStore{db: d.db}
+}
+
+func (s *pgRateLimitStore) Take(ctx context.Context, key string, limit RateLimit, now time.Time) (RateLimitResult, error) {
+	if err := s.sweep(ctx, now); err != nil {
+		return RateLimitResult{}, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	defer tx.Rollback()
+
+	// The row lock serialises replicas taking from the same bucket. Two that
+	// meet a new client at the same moment both start from a full bucket,
+	// which lets at most one extra request through.
+	var b tokenBucket
+	err = tx.QueryRowContext(ctx,
+		"SELECT tokens, updated_at FROM rate_limit_buckets WHERE key = $1 FOR UPDATE", key,
+	).Scan(&b.Tokens, &b.UpdatedAt)
+	if err != nil && err != sql.ErrNoRows {
+		return RateLimitResult{}, fmt.Errorf("error reading rate limit bucket: %w", err)
+	}
+	b, res := limit.take(b, now)
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO rate_limit_buckets (key, tokens, updated_at, full_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (key) DO UPDATE SET tokens = EXCLUDED.tokens, updated_at = EXCLUDED.updated_at, full_at = EXCLUDED.full_at`,
+		key, b.Tokens, b.UpdatedAt, b.FullAt,
+	); err != nil {
+		return RateLimitResult{}, fmt.Errorf("error storing rate limit bucket: %w", err)
+	}
+	return res, tx.Commit()
+}
+
+// sweep drops the buckets that have refilled, at most once per interval.
+func (s *pgRateLimitStore) sweep(ctx context.Context, now time.Time) error {
+	s.mu.Lock()
+	due := now.Sub(s.sweptAt) >= rateLimitSweepInterval
+	if due {
+		s.sweptAt = now
+	}
+	s.mu.Unlock()
+	if !due {
+		return nil
+	}
+	_, err := s.db.ExecContext(ctx, "DELETE FROM rate_limit_buckets WHERE full_at <= $1", now)
+	return err
+}
+
+// exportBatchSize is how many rows each FETCH pulls from an export cursor.
+const exportBatchSize = 500
+
+// streamCursor runs query through a server-side cursor and calls scan for
+// each row, holding at most one batch in memory however large the result.
+func streamCursor(ctx context.Context, db *sql.DB, query string, args []any, scan func(*sql.Rows) error) error {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DECLARE export_cursor NO SCROLL CURSOR FOR "+query, args...); err != nil {
+		return fmt.Errorf("error declaring export cursor: %w", err)
+	}
+	fetch := fmt.Sprintf("FETCH %d FROM export_cursor", exportBatchSize)
+	for {
+		rows, err := tx.QueryContext(ctx, fetch)
+		if err != nil {
+			return fmt.Errorf("error fetching export rows: %w", err)
+		}
+		n := 0
+		for rows.Next() {
+			n++
+			if err := scan(rows); err != nil {
+				rows.Close()
+				return err
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error iterating export rows: %w", err)
+		}
+		if n < exportBatchSize {
+			return tx.Commit()
+		}
+	}
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// querier is execer with queries, again satisfied by *sql.DB and *sql.Tx.
+type querier interface {
+	execer
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func revokeFamily(ctx context.Context, db execer, familyID string) error {
+	_, err := db.ExecContext(ctx,
+		"UPDATE refresh_tokens SET revoked_at = now() WHERE family_id = $1 AND revoked_at IS NULL",
+		familyID,
+	)
+	return err
+}
+
+// purgeDeleted permanently removes the rows of table deleted before cutoff.
+func purgeDeleted(ctx context.Context, db execer, table string, cutoff time.Time) (int, error) {
+	res, err := db.ExecContext(ctx, "DELETE FROM "+table+" WHERE deleted_at < $1", cutoff)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// expectAffected maps a statement that touched no rows to ErrNotFound.
+func expectAffected(res sql.Result) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
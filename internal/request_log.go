@@ -0,0 +1,111 @@
+package internal
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// requestIDHeader carries the ID that ties together the log lines, audit
+	// events and response of one request.
+	requestIDHeader     = "X-Request-ID"
+	requestIDContextKey = "request.id"
+	logEntryContextKey  = "request.log"
+	maxRequestIDLength  = 128
+)
+
+// RequestLogger gives every request an ID and a log entry scoped to it, and
+// logs the request once it has been handled. The caller's X-Request-ID is
+// kept when it is safe to log; otherwise a new one is generated. Either way
+// it is echoed in the response. Traced requests also log their trace and
+// span IDs.
+func RequestLogger(logger *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		id := c.GetHeader(requestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		c.Set(requestIDContextKey, id)
+		c.Header(requestIDHeader, id)
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		fields := logrus.Fields{
+			"request_id": id,
+			"method":     c.Request.Method,
+			"route":      route,
+		}
+		// Tracing runs first, so the lines can be looked up from the trace
+		if span := trace.SpanContextFromContext(c.Request.Context()); span.IsValid() {
+			fields["trace_id"] = span.TraceID().String()
+			fields["span_id"] = span.SpanID().String()
+		}
+		c.Set(logEntryContextKey, logger.WithFields(fields))
+
+		c.Next()
+
+		entry := requestLog(c, logger).WithFields(logrus.Fields{
+			"status":  c.Writer.Status(),
+			"latency": time.Since(start).Seconds(),
+		})
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			entry.Error("Request failed")
+		} else {
+			entry.Info("Request handled")
+		}
+	}
+}
+
+// requestLog returns the log entry of the request, with the caller's user ID
+// once they are authenticated. Outside RequestLogger it falls back to a plain
+// entry of logger.
+func requestLog(c *gin.Context, logger *logrus.Logger) *logrus.Entry {
+	v, _ := c.Get(logEntryContextKey)
+	entry, ok := v.(*logrus.Entry)
+	if !ok {
+		entry = logrus.NewEntry(logger)
+	}
+	if claims, ok := CurrentClaims(c); ok {
+		entry = entry.WithField("user_id", claims.UserID)
+	}
+	return entry
+}
+
+// log is the request's log entry for handlers.
+func (h *Handler) log(c *gin.Context) *logrus.Entry {
+	return requestLog(c, h.logger)
+}
+
+// RequestID returns the ID RequestLogger gave the request, if any.
+func RequestID(c *gin.Context) string {
+	return c.GetString(requestIDContextKey)
+}
+
+// validRequestID accepts IDs of printable ASCII without spaces, so a caller
+// cannot forge log lines or headers through them.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestLoggerAssignsRequestIDs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger, _ := test.NewNullLogger()
+	r := gin.New()
+	r.Use(RequestLogger(logger))
+	var seen string
+	r.GET("/ping", func(c *gin.Context) {
+		seen = RequestID(c)
+		c.Status(http.StatusOK)
+	})
+
+	send := func(id string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/ping", nil)
+		if id != "" {
+			req.Header.Set(requestIDHeader, id)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("")
+	assert.Len(t, seen, 32)
+	assert.Equal(t, seen, w.Header().Get(requestIDHeader))
+
+	w = send("client-7f3a")
+	assert.Equal(t, "client-7f3a", seen)
+	assert.Equal(t, "client-7f3a", w.Header().Get(requestIDHeader))
+
+	// IDs that could forge log lines or are too long are replaced.
+	for _, bad := range []string{"two words", "tab\tbed", strings.Repeat("x", maxRequestIDLength+1)} {
+		w = send(bad)
+		assert.NotEqual(t, bad, seen)
+		assert.Len(t, seen, 32)
+	}
+}
+
+func TestHandlersLogThroughTheRequestEntry(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger, hook := test.NewNullLogger()
+	h := NewHandler(NewMemoryRepositories(), logger, NewTokenManager("test-secret", time.Minute, time.Hour))
+	r := gin.New()
+	r.Use(RequestLogger(logger))
+	r.Use(func(c *gin.Context) {
+		c.Set(claimsContextKey, &Claims{UserID: 7, Role: RoleAdmin})
+		c.Next()
+	})
+	r.GET("/students/:id", h.GetStudentByID)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/students/9", nil)
+	req.Header.Set(requestIDHeader, "req-42")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	entries := hook.AllEntries()
+	assert.Len(t, entries, 2)
+	for _, e := range entries {
+		assert.Equal(t, "req-42", e.Data["request_id"])
+		assert.Equal(t, "/students/:id", e.Data["route"])
+		assert.Equal(t, http.MethodGet, e.Data["method"])
+		assert.Equal(t, 7, e.Data["user_id"])
+	}
+	assert.Equal(t, logrus.WarnLevel, entries[0].Level)
+	assert.Contains(t, entries[0].Message, "Student not found")
+
+	done := entries[1]
+	assert.Equal(t, "Request handled", done.Message)
+	assert.Equal(t, http.StatusNotFound, done.Data["status"])
+	assert.IsType(t, float64(0), done.Data["latency"])
+}
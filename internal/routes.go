@@ -0,0 +1,116 @@
+package internal
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Route declares an endpoint of the API together with its access rules.
+type Route struct {
+	Method  string
+	Path    string
+	Handler gin.HandlerFunc
+
+	// Public routes skip authentication entirely.
+	Public bool
+	// Permission is required from authenticated callers. Leave empty to
+	// admit any authenticated caller.
+	Permission Permission
+	// OwnPermission, when set, admits callers holding it for their own
+	// student record (matched against the :id path parameter).
+	OwnPermission Permission
+	// RateLimit gives the route a limit and bucket of its own instead of
+	// the limiter's default.
+	RateLimit *RateLimit
+}
+
+// RouteMiddleware is what RegisterRoutes places in front of route handlers.
+type RouteMiddleware struct {
+	// Auth authenticates every non-public route.
+	Auth gin.HandlerFunc
+	// Idempotency, when set, guards the non-public POST routes. Public ones
+	// are left out: their responses carry credentials, which are not kept.
+	Idempotency gin.HandlerFunc
+	// RateLimiter, when set, limits every route. It runs after
+	// authentication so callers are limited as themselves, and before
+	// idempotency so a refused request is not replayed to retries.
+	RateLimiter *RateLimiter
+}
+
+// RegisterRoutes mounts routes on the group, placing auth in front of every
+// non-public route followed by its permission check.
+func RegisterRoutes(g *gin.RouterGroup, mw RouteMiddleware, routes []Route) {
+	for _, rt := range routes {
+		handlers := []gin.HandlerFunc{}
+		if !rt.Public {
+			handlers = append(handlers, mw.Auth)
+		}
+		if mw.RateLimiter != nil {
+			handlers = append(handlers, mw.RateLimiter.Limit(rt))
+		}
+		if !rt.Public {
+			handlers = append(handlers, Authorize(rt))
+			if rt.Method == http.MethodPost && mw.Idempotency != nil {
+				handlers = append(handlers, mw.Idempotency)
+			}
+		}
+		handlers = append(handlers, rt.Handler)
+		g.Handle(rt.Method, rt.Path, handlers...)
+	}
+}
+
+// Routes is the route table of the API, which main mounts under /api/v1.
+func (h *Handler) Routes() []Route {
+	return []Route{
+		//healthcheck endpoint
+		{Method: http.MethodGet, Path: "/health", Handler: h.Healthcheck, Public: true},
+		{Method: http.MethodGet, Path: "/health/live", Handler: h.Liveness, Public: true},
+		{Method: http.MethodGet, Path: "/health/ready", Handler: h.Readiness, Public: true},
+
+		// auth endpoints
+		{Method: http.MethodPost, Path: "/auth/signup", Handler: h.Signup, Public: true, RateLimit: &RateLimit{Requests: 5, Per: time.Hour}},
+		{Method: http.MethodPost, Path: "/auth/login", Handler: h.Login, Public: true, RateLimit: &RateLimit{Requests: 10, Per: time.Minute}},
+		{Method: http.MethodPost, Path: "/auth/refresh", Handler: h.Refresh, Public: true},
+		{Method: http.MethodPost, Path: "/auth/logout", Handler: h.Logout},
+
+		// student endpoints
+		{Method: http.MethodGet, Path: "/students", Handler: h.GetStudents, Permission: PermStudentsRead},
+		{Method: http.MethodPost, Path: "/students", Handler: h.CreateStudent, Permission: PermStudentsWrite},
+		{Method: http.MethodPost, Path: "/students/import", Handler: h.ImportStudents, Permission: PermStudentsWrite, RateLimit: &RateLimit{Requests: 10, Per: time.Hour, Burst: 2}},
+		{Method: http.MethodGet, Path: "/students/export", Handler: h.ExportStudents, Permission: PermStudentsRead, RateLimit: &RateLimit{Requests: 30, Per: time.Hour, Burst: 5}},
+		{Method: http.MethodGet, Path: "/students/:id", Handler: h.GetStudentByID, Permission: PermStudentsRead, OwnPermission: PermStudentsReadOwn},
+		{Method: http.MethodPut, Path: "/students/:id", Handler: h.UpdateStudent, Permission: PermStudentsWrite},
+		{Method: http.MethodPatch, Path: "/students/:id", Handler: h.PatchStudent, Permission: PermStudentsWrite},
+		{Method: http.MethodDelete, Path: "/students/:id", Handler: h.DeleteStudent, Permission: PermStudentsDelete},
+		{Method: http.MethodPost, Path: "/students/:id/restore", Handler: h.RestoreStudent, Permission: PermStudentsDelete},
+
+		{Method: http.MethodPost, Path: "/students/:id/grades", Handler: h.RecordGrade, Permission: PermGradesWrite},
+		{Method: http.MethodGet, Path: "/students/:id/transcript", Handler: h.GetTranscript, Permission: PermGradesRead, OwnPermission: PermGradesReadOwn},
+		{Method: http.MethodGet, Path: "/students/:id/attendance", Handler: h.GetStudentAttendance, Permission: PermAttendanceRead, OwnPermission: PermAttendanceReadOwn},
+		{Method: http.MethodPost, Path: "/students/:id/enrollments", Handler: h.EnrollStudent, Permission: PermEnrollmentsWrite, OwnPermission: PermEnrollmentsWriteOwn},
+
+		// attendance endpoints
+		{Method: http.MethodPost, Path: "/attendance", Handler: h.RecordAttendance, Permission: PermAttendanceWrite},
+		{Method: http.MethodGet, Path: "/attendance/report", Handler: h.GetAttendanceReport, Permission: PermAttendanceRead},
+
+		// course endpoints
+		{Method: http.MethodGet, Path: "/courses", Handler: h.GetCourses, Permission: PermCoursesRead},
+		{Method: http.MethodPost, Path: "/courses", Handler: h.CreateCourse, Permission: PermCoursesWrite},
+		{Method: http.MethodGet, Path: "/courses/:id", Handler: h.GetCourseByID, Permission: PermCoursesRead},
+		{Method: http.MethodPut, Path: "/courses/:id", Handler: h.UpdateCourse, Permission: PermCoursesWrite},
+		{Method: http.MethodDelete, Path: "/courses/:id", Handler: h.DeleteCourse, Permission: PermCoursesDelete},
+		{Method: http.MethodGet, Path: "/courses/:id/students", Handler: h.GetCourseStudents, Permission: PermStudentsRead},
+
+		// user endpoints
+		{Method: http.MethodGet, Path: "/users", Handler: h.GetUsers, Permission: PermUsersRead},
+		{Method: http.MethodGet, Path: "/users/export", Handler: h.ExportUsers, Permission: PermUsersRead, RateLimit: &RateLimit{Requests: 30, Per: time.Hour, Burst: 5}},
+		{Method: http.MethodGet, Path: "/users/by-id", Handler: h.GetUserById, Permission: PermUsersRead},
+		{Method: http.MethodPost, Path: "/users", Handler: h.CreateUser, Permission: PermUsersWrite},
+		{Method: http.MethodDelete, Path: "/users/:id", Handler: h.DeleteUserById, Permission: PermUsersDelete},
+
+		// audit endpoints
+		{Method: http.MethodGet, Path: "/audit", Handler: h.GetAuditEvents, Permission: PermAuditRead},
+	}
+}
@@ -0,0 +1,133 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeletedStudentIsHiddenUntilRestored(t *testing.T) {
+	h, repos := newMemoryHandler(t)
+	ctx := context.Background()
+	kept, _ := repos.Students.Create(ctx, Student{Name: "Alice", Age: 20, Email: "alice@example.com"})
+	gone, _ := repos.Students.Create(ctx, Student{Name: "Bob", Age: 22, Email: "bob@example.com"})
+	r := testRouter(t, h, testAdmin, RouteMiddleware{})
+
+	assert.Equal(t, http.StatusNoContent, doJSON(r, http.MethodDelete, "/students/2", "", nil))
+	assert.Equal(t, http.StatusNotFound, doJSON(r, http.MethodDelete, "/students/2", "", nil))
+	assert.Equal(t, http.StatusNotFound, doJSON(r, http.MethodGet, "/students/2", "", nil))
+
+	var list StudentListResponse
+	assert.Equal(t, http.StatusOK, doJSON(r, http.MethodGet, "/students", "", &list))
+	assert.Equal(t, []Student{kept}, list.Data)
+
+	assert.Equal(t, http.StatusOK, doJSON(r, http.MethodGet, "/students?include_deleted=true", "", &list))
+	assert.Equal(t, 2, list.Total)
+	assert.Equal(t, gone.ID, list.Data[1].ID)
+	assert.NotNil(t, list.Data[1].DeletedAt)
+
+	var s Student
+	assert.Equal(t, http.StatusOK, doJSON(r, http.MethodGet, "/students/2?include_deleted=true", "", &s))
+	assert.NotNil(t, s.DeletedAt)
+
+	var restored Student
+	assert.Equal(t, http.StatusOK, doJSON(r, http.MethodPost, "/students/2/restore", "", &restored))
+	// Deleting and restoring are both writes, so the version moved on twice.
+	gone.Version += 2
+	assert.Equal(t, gone, restored)
+	assert.Equal(t, http.StatusOK, doJSON(r, http.MethodGet, "/students/2", "", nil))
+	assert.Equal(t, http.StatusNotFound, doJSON(r, http.MethodPost, "/students/9/restore", "", nil))
+}
+
+func TestIncludeDeletedIsForAdminsOnly(t *testing.T) {
+	h, _ := newMemoryHandler(t)
+	r := testRouter(t, h, User{ID: 2, Email: "teacher@example.com", Role: RoleTeacher}, RouteMiddleware{})
+
+	assert.Equal(t, http.StatusForbidden, doJSON(r, http.MethodGet, "/students?include_deleted=true", "", nil))
+	assert.Equal(t, http.StatusForbidden, doJSON(r, http.MethodGet, "/students/1?include_deleted=true", "", nil))
+	assert.Equal(t, http.StatusBadRequest, doJSON(r, http.MethodGet, "/students?include_deleted=maybe", "", nil))
+	assert.Equal(t, http.StatusOK, doJSON(r, http.MethodGet, "/students?include_deleted=false", "", nil))
+}
+
+func TestRestoreConflictsWithReusedEmail(t *testing.T) {
+	h, _ := newMemoryHandler(t)
+	r := testRouter(t, h, testAdmin, RouteMiddleware{})
+	body := `{"name":"John Doe","age":20,"email":"john@example.com"}`
+
+	assert.Equal(t, http.StatusCreated, doJSON(r, http.MethodPost, "/students", body, nil))
+	assert.Equal(t, http.StatusNoContent, doJSON(r, http.MethodDelete, "/students/1", "", nil))
+	// The email is free again once its owner is deleted.
+	assert.Equal(t, http.StatusCreated, doJSON(r, http.MethodPost, "/students", body, nil))
+
+	var resp ErrorResponse
+	assert.Equal(t, http.StatusConflict, doJSON(r, http.MethodPost, "/students/1/restore", "", &resp))
+	assert.Equal(t, CodeAlreadyExists, resp.Code)
+}
+
+func TestDeletedUserLosesSessions(t *testing.T) {
+	repos := NewMemoryRepositories()
+	ctx := context.Background()
+	u, _ := repos.Users.Create(ctx, User{Name: "Alice", Email: "alice@example.com"}, "hash")
+	assert.NoError(t, repos.Sessions.Create(ctx, u.ID, "family", "token", time.Now().Add(time.Hour)))
+
+	assert.NoError(t, repos.Users.Delete(ctx, u.ID))
+	active, err := repos.Sessions.Active(ctx, "family")
+	assert.NoError(t, err)
+	assert.False(t, active)
+	_, _, err = repos.Users.GetCredentials(ctx, "alice@example.com")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestPurgerRemovesRecordsPastRetention(t *testing.T) {
+	repos := NewMemoryRepositories()
+	ctx := context.Background()
+	s, _ := repos.Students.Create(ctx, Student{Name: "Bob", Age: 22, Email: "bob@example.com"})
+	u, _ := repos.Users.Create(ctx, User{Name: "Bob", Email: "bob@example.com"}, "")
+	assert.NoError(t, repos.Students.Delete(ctx, s.ID, 0))
+	assert.NoError(t, repos.Users.Delete(ctx, u.ID))
+
+	p := NewPurger(repos, PurgeConfig{Retention: 24 * time.Hour, Interval: time.Hour}, logrus.New())
+
+	assert.NoError(t, p.Purge(ctx, time.Now()))
+	_, err := repos.Students.GetIncludingDeleted(ctx, s.ID)
+	assert.NoError(t, err, "still within retention")
+
+	assert.NoError(t, p.Purge(ctx, time.Now().Add(25*time.Hour)))
+	_, err = repos.Students.GetIncludingDeleted(ctx, s.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = repos.Users.GetIncludingDeleted(ctx, u.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestPostgresStudentSoftDelete(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+	repo := NewPostgresRepositories(&Db{db: mockDB}).Students
+	ctx := context.Background()
+	cutoff := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectExec(`UPDATE students SET deleted_at = now\(\), version = version \+ 1 WHERE id = \$1 AND deleted_at IS NULL`).
+		WithArgs(3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`UPDATE students SET deleted_at = NULL, version = CASE WHEN deleted_at IS NULL THEN version ELSE version \+ 1 END\s+WHERE id = \$1 RETURNING id, name, age, email, deleted_at, version`).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "age", "email", "deleted_at", "version"}).AddRow(3, "Bob", 22, "bob@example.com", nil, 3))
+	mock.ExpectExec(`DELETE FROM students WHERE deleted_at < \$1`).
+		WithArgs(cutoff).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+
+	assert.NoError(t, repo.Delete(ctx, 3, 0))
+	s, err := repo.Restore(ctx, 3)
+	assert.NoError(t, err)
+	assert.Nil(t, s.DeletedAt)
+	n, err := repo.Purge(ctx, cutoff)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,363 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxImportBytes caps the size of an upload.
+	maxImportBytes = 10 << 20
+	// maxImportRows caps the rows of one import, which are read into memory
+	// before they are stored in one transaction.
+	maxImportRows = 10000
+	// maxImportLine caps a single JSON Lines record.
+	maxImportLine = 64 << 10
+)
+
+// CodeMalformedImport marks an upload that cannot be read as a whole, as
+// opposed to individual rows that fail validation.
+const CodeMalformedImport = "malformed_import"
+
+var (
+	errUnsupportedImport = errors.New("unsupported import format")
+	errTooManyRows       = fmt.Errorf("an import may contain at most %d rows", maxImportRows)
+)
+
+// importReader yields the rows of an upload one at a time.
+type importReader interface {
+	// Next returns the next row and its line number, or io.EOF when the
+	// upload is exhausted. A row that cannot be decoded is reported through
+	// rowErrs; any other error ends the import.
+	Next() (line int, req StudentCreateRequest, rowErrs []FieldError, err error)
+}
+
+// importColumns are the CSV columns an upload must provide.
+var importColumns = []string{"name", "age", "email"}
+
+type csvImportReader struct {
+	r *csv.Reader
+	// index maps each import column to its position in the header.
+	index map[string]int
+}
+
+func newCSVImportReader(src io.Reader) (*csvImportReader, error) {
+	r := csv.NewReader(src)
+	r.ReuseRecord = true
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil, errors.New("the upload is empty; expected a header row")
+	} else if err != nil {
+		return nil, err
+	}
+	index := map[string]int{}
+	for i, col := range header {
+		col = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(col, "\ufeff")))
+		if !slices.Contains(importColumns, col) {
+			return nil, fmt.Errorf("unknown column %q; expected %s", col, strings.Join(importColumns, ", "))
+		}
+		if _, dup := index[col]; dup {
+			return nil, fmt.Errorf("column %q appears twice", col)
+		}
+		index[col] = i
+	}
+	for _, col := range importColumns {
+		if _, ok := index[col]; !ok {
+			return nil, fmt.Errorf("missing column %q", col)
+		}
+	}
+	return &csvImportReader{r: r, index: index}, nil
+}
+
+func (c *csvImportReader) Next() (int, StudentCreateRequest, []FieldError, error) {
+	var req StudentCreateRequest
+	record, err := c.r.Read()
+	if err != nil && !errors.Is(err, csv.ErrFieldCount) {
+		return 0, req, nil, err
+	}
+	line, _ := c.r.FieldPos(0)
+	if err != nil {
+		return line, req, []FieldError{{
+			Field:   "row",
+			Rule:    "columns",
+			Message: fmt.Sprintf("row has %d columns, the header has %d", len(record), len(c.index)),
+		}}, nil
+	}
+
+	req.Name = record[c.index["name"]]
+	req.Email = record[c.index["email"]]
+	if raw := strings.TrimSpace(record[c.index["age"]]); raw != "" {
+		age, err := strconv.Atoi(raw)
+		if err != nil {
+			return line, req, []FieldError{{Field: "age", Rule: "type", Message: "age must be an integer"}}, nil
+		}
+		req.Age = age
+	}
+	return line, req, nil, nil
+}
+
+type jsonlImportReader struct {
+	s    *bufio.Scanner
+	line int
+}
+
+func newJSONLImportReader(src io.Reader) *jsonlImportReader {
+	s := bufio.NewScanner(src)
+	s.Buffer(make([]byte, 0, 4096), maxImportLine)
+	return &jsonlImportReader{s: s}
+}
+
+func (j *jsonlImportReader) Next() (int, StudentCreateRequest, []FieldError, error) {
+	var req StudentCreateRequest
+	for j.s.Scan() {
+		j.line++
+		raw := bytes.TrimSpace(j.s.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		if err := decodeStrict(raw, &req); err != nil {
+			_, resp := bindError(err)
+			if len(resp.Details) == 0 {
+				resp.Details = []FieldError{{Field: "row", Rule: "json", Message: "line is not a valid JSON object"}}
+			}
+			return j.line, req, resp.Details, nil
+		}
+		return j.line, req, nil, nil
+	}
+	if err := j.s.Err(); errors.Is(err, bufio.ErrTooLong) {
+		return 0, req, nil, fmt.Errorf("line %d is longer than %d bytes", j.line+1, maxImportLine)
+	} else if err != nil {
+		return 0, req, nil, err
+	}
+	return 0, req, nil, io.EOF
+}
+
+// openImport picks the reader for the request body: a CSV or JSON Lines
+// body, or the "file" field of a multipart form.
+func openImport(r *http.Request) (importReader, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		return importReaderFor(mediaType, "", r.Body)
+	}
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil, errors.New(`the form has no "file" field`)
+		} else if err != nil {
+			return nil, err
+		}
+		if part.FormName() != "file" {
+			continue
+		}
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		return importReaderFor(partType, part.FileName(), part)
+	}
+}
+
+// importReaderFor chooses the format by media type, falling back to the file
+// extension for generic uploads.
+func importReaderFor(mediaType, filename string, src io.Reader) (importReader, error) {
+	switch mediaType {
+	case "text/csv":
+		return newCSVImportReader(src)
+	case "application/x-ndjson", "application/jsonl", "application/x-jsonlines", "application/jsonlines":
+		return newJSONLImportReader(src), nil
+	}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return newCSVImportReader(src)
+	case ".jsonl", ".ndjson":
+		return newJSONLImportReader(src), nil
+	}
+	return nil, errUnsupportedImport
+}
+
+// importRow is a row of an upload that passed validation.
+type importRow struct {
+	line    int
+	student Student
+}
+
+// readImport reads and validates the whole upload before anything is staged,
+// so no transaction is held open while the client is still sending it. Rows
+// that fail validation are counted and reported in result; the others are
+// returned.
+func readImport(src importReader, result *ImportResult) ([]importRow, error) {
+	var rows []importRow
+	seen := map[string]int{}
+	for {
+		line, req, rowErrs, err := src.Next()
+		if err == io.EOF {
+			return rows, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if result.Rows++; result.Rows > maxImportRows {
+			return nil, errTooManyRows
+		}
+
+		if rowErrs == nil {
+			if err := validateStruct(&req); err != nil {
+				_, resp := bindError(err)
+				rowErrs = resp.Details
+			}
+		}
+		if rowErrs == nil {
+			email := strings.ToLower(req.Email)
+			if first, dup := seen[email]; dup {
+				rowErrs = []FieldError{{Field: "email", Rule: "unique", Message: fmt.Sprintf("email is already used on line %d", first)}}
+			} else {
+				seen[email] = line
+			}
+		}
+		if rowErrs != nil {
+			result.Errors = append(result.Errors, ImportRowError{Line: line, Errors: rowErrs})
+			continue
+		}
+		rows = append(rows, importRow{line: line, student: Student{Name: req.Name, Age: req.Age, Email: req.Email}})
+	}
+}
+
+// ImportStudents godoc
+// @Summary      Bulk import students
+// @Description  Creates students from a CSV (header row with name, age and email) or JSON Lines upload, sent as the request body or as the "file" field of a multipart form. Every row is validated and the upload is stored in one transaction: if any row fails, nothing is imported and the response lists the errors by line. Every imported student is recorded in the audit trail. With dry_run=true the upload is checked, including against existing emails, but never stored.
+// @Tags         Students
+// @Accept       text/csv
+// @Accept       application/x-ndjson
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        dry_run          query     bool          false  "Validate without storing"
+// @Param        file             formData  file          false  "CSV or JSON Lines file when uploading a form"
+// @Param        Idempotency-Key  header    string        false  "Unique key that makes retrying this request safe"
+// @Success      200              {object}  ImportResult  "Dry run finished without errors"
+// @Success      201              {object}  ImportResult
+// @Failure      400              {object}  ErrorResponse
+// @Failure      401              {object}  ErrorResponse
+// @Failure      403              {object}  ErrorResponse
+// @Failure      413              {object}  ErrorResponse
+// @Failure      415              {object}  ErrorResponse
+// @Failure      422              {object}  ImportResult
+// @Failure      500              {object}  ErrorResponse
+// @Security     BearerAuth
+// @Router       /students/import [post]
+func (h *Handler) ImportStudents(c *gin.Context) {
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "dry_run must be true or false"})
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBytes)
+
+	src, err := openImport(c.Request)
+	if err != nil {
+		h.respondImportError(c, err)
+		return
+	}
+
+	result := ImportResult{DryRun: dryRun, Errors: []ImportRowError{}}
+	rows, err := readImport(src, &result)
+	if err != nil {
+		h.respondImportError(c, err)
+		return
+	}
+
+	// Existing emails are checked even when rows failed so the report is
+	// complete in one pass.
+	commit := !dryRun && len(result.Errors) == 0
+	var (
+		ctx       = c.Request.Context()
+		stored    []Student
+		conflicts []int
+	)
+	err = h.inTx(ctx, func(tx Repositories) error {
+		imp, err := tx.Students.BeginImport(ctx)
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			if err := imp.Add(row.line, row.student); err != nil {
+				return err
+			}
+		}
+		if stored, conflicts, err = imp.Finish(ctx, commit); err != nil {
+			return err
+		}
+		for _, s := range stored {
+			change := auditedChange{action: AuditCreate, entityType: AuditEntityStudent, entityID: s.ID, after: s}
+			if err := recordChange(c, tx, change); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if h.respondConstraintError(c, err) {
+		return
+	} else if err != nil {
+		h.log(c).Error("Failed to import students:", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to import students"})
+		return
+	}
+	for _, line := range conflicts {
+		result.Errors = append(result.Errors, ImportRowError{
+			Line:   line,
+			Errors: []FieldError{{Field: "email", Rule: "unique", Message: "email is already in use"}},
+		})
+	}
+	result.Imported = len(stored)
+
+	switch {
+	case len(result.Errors) > 0:
+		// Conflicts found in the database were appended after the rows that
+		// failed validation.
+		sort.SliceStable(result.Errors, func(i, j int) bool { return result.Errors[i].Line < result.Errors[j].Line })
+		h.log(c).Warn("Rejected student import with ", len(result.Errors), " invalid rows")
+		c.JSON(http.StatusUnprocessableEntity, result)
+	case dryRun:
+		h.log(c).Info("Dry run of student import passed for ", result.Rows, " rows")
+		c.JSON(http.StatusOK, result)
+	default:
+		h.log(c).Info("Imported ", result.Imported, " students")
+		c.JSON(http.StatusCreated, result)
+	}
+}
+
+// respondImportError answers for an upload that could not be read at all.
+func (h *Handler) respondImportError(c *gin.Context, err error) {
+	h.log(c).Warn("Invalid student import:", err)
+	var (
+		tooLarge *http.MaxBytesError
+		parseErr *csv.ParseError
+	)
+	switch {
+	case errors.As(err, &tooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, ErrorResponse{Error: fmt.Sprintf("an import may be at most %d bytes", tooLarge.Limit)})
+	case errors.Is(err, errTooManyRows):
+		c.JSON(http.StatusRequestEntityTooLarge, ErrorResponse{Error: err.Error()})
+	case errors.Is(err, errUnsupportedImport):
+		c.JSON(http.StatusUnsupportedMediaType, ErrorResponse{Error: "Upload must be text/csv or application/x-ndjson, or a form file ending in .csv or .jsonl"})
+	case errors.As(err, &parseErr):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("line %d: %v", parseErr.Line, parseErr.Err), Code: CodeMalformedImport})
+	default:
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error(), Code: CodeMalformedImport})
+	}
+}
@@ -0,0 +1,184 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func importStudents(h *Handler, query, contentType string, body []byte) (int, ImportResult, ErrorResponse) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/students/import", h.ImportStudents)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/students/import"+query, bytes.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	r.ServeHTTP(w, req)
+
+	var (
+		result  ImportResult
+		errResp ErrorResponse
+	)
+	_ = json.Unmarshal(w.Body.Bytes(), &result)
+	_ = json.Unmarshal(w.Body.Bytes(), &errResp)
+	return w.Code, result, errResp
+}
+
+func countStudents(t *testing.T, repos Repositories) int {
+	t.Helper()
+	page, err := repos.Students.List(context.Background(), StudentFilter{}, ListOptions{Limit: 100, Sort: []SortField{{Column: "id"}}})
+	assert.NoError(t, err)
+	return page.Total
+}
+
+func TestImportStudentsCSV(t *testing.T) {
+	h, repos := newMemoryHandler(t)
+	csvBody := []byte("Email,Name,Age\njohn@example.com,John Doe,20\njane@example.com,\"Doe, Jane\",22\n")
+
+	code, result, _ := importStudents(h, "?dry_run=true", "text/csv", csvBody)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, ImportResult{DryRun: true, Rows: 2, Errors: []ImportRowError{}}, result)
+	assert.Equal(t, 0, countStudents(t, repos))
+
+	code, result, _ = importStudents(h, "", "text/csv; charset=utf-8", csvBody)
+	assert.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, 2, result.Imported)
+	s, err := repos.Students.Get(context.Background(), 2)
+	assert.NoError(t, err)
+	assert.Equal(t, Student{ID: 2, Name: "Doe, Jane", Age: 22, Email: "jane@example.com", Version: 1}, s)
+}
+
+func TestImportStudentsReportsEveryBadRow(t *testing.T) {
+	h, repos := newMemoryHandler(t)
+	_, _ = repos.Students.Create(context.Background(), Student{Name: "Taken", Age: 30, Email: "taken@example.com"})
+
+	body := []byte(strings.Join([]string{
+		"name,age,email",
+		"Ok Row,20,ok@example.com",
+		"Bad Age,twenty,age@example.com",
+		",19,noname@example.com",
+		"Dup,21,OK@example.com",
+		"Existing,25,taken@example.com",
+		"Short,25",
+	}, "\n"))
+	code, result, _ := importStudents(h, "", "text/csv", body)
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	assert.Equal(t, 0, result.Imported)
+	assert.Equal(t, 6, result.Rows)
+	assert.Equal(t, []ImportRowError{
+		{Line: 3, Errors: []FieldError{{Field: "age", Rule: "type", Message: "age must be an integer"}}},
+		{Line: 4, Errors: []FieldError{{Field: "name", Rule: "required", Message: "name is required"}}},
+		{Line: 5, Errors: []FieldError{{Field: "email", Rule: "unique", Message: "email is already used on line 2"}}},
+		{Line: 6, Errors: []FieldError{{Field: "email", Rule: "unique", Message: "email is already in use"}}},
+		{Line: 7, Errors: []FieldError{{Field: "row", Rule: "columns", Message: "row has 2 columns, the header has 3"}}},
+	}, result.Errors)
+	assert.Equal(t, 1, countStudents(t, repos))
+}
+
+func TestImportStudentsJSONLinesAndMultipart(t *testing.T) {
+	h, repos := newMemoryHandler(t)
+
+	jsonl := []byte(`{"name": "John", "age": 20, "email": "john@example.com"}
+
+{"name": "Jane", "age": 22, "email": "jane@example.com", "id": 9}
+`)
+	code, result, _ := importStudents(h, "", "application/x-ndjson", jsonl)
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	assert.Equal(t, []ImportRowError{{Line: 3, Errors: []FieldError{{Field: "id", Rule: "unknown", Message: "id is not a writable field"}}}}, result.Errors)
+
+	var form bytes.Buffer
+	mw := multipart.NewWriter(&form)
+	_ = mw.WriteField("note", "ignored")
+	fw, _ := mw.CreateFormFile("file", "students.jsonl")
+	_, _ = fw.Write([]byte(`{"name": "John", "age": 20, "email": "john@example.com"}` + "\n"))
+	_ = mw.Close()
+
+	code, result, _ = importStudents(h, "", mw.FormDataContentType(), form.Bytes())
+	assert.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, 1, result.Imported)
+	assert.Equal(t, 1, countStudents(t, repos))
+}
+
+func TestImportStudentsRejectsUnreadableUploads(t *testing.T) {
+	h, _ := newMemoryHandler(t)
+
+	code, _, _ := importStudents(h, "", "application/json", []byte(`[]`))
+	assert.Equal(t, http.StatusUnsupportedMediaType, code)
+
+	code, _, errResp := importStudents(h, "", "text/csv", []byte("name,age,email,phone\n"))
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, CodeMalformedImport, errResp.Code)
+	assert.Contains(t, errResp.Error, `unknown column "phone"`)
+
+	code, _, errResp = importStudents(h, "", "text/csv", []byte("name,age,email\n\"open,20,a@example.com\n"))
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, errResp.Error, "line 2")
+
+	code, _, _ = importStudents(h, "?dry_run=maybe", "text/csv", []byte("name,age,email\n"))
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestImportStudentsReadsUploadBeforeBeginning(t *testing.T) {
+	// No transaction is expected: uploads that fail to read are rejected
+	// before the database is touched.
+	h, mock := newTestHandler(t)
+
+	body := "name,age,email\n" + strings.Repeat("John,20,john@example.com\n", 3) + "\"open,20,a@example.com\n"
+	code, _, _ := importStudents(h, "", "text/csv", []byte(body))
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	body = "name,age,email\n" + strings.Repeat("John,20,john@example.com\n", maxImportRows+1)
+	code, _, _ = importStudents(h, "", "text/csv", []byte(body))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresImportCopiesThroughScratchTable(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+	repos := NewPostgresRepositories(&Db{db: mockDB})
+
+	mock.ExpectBegin()
+	mock.ExpectExec("CREATE TEMP TABLE student_import").WillReturnResult(sqlmock.NewResult(0, 0))
+	copyIn := mock.ExpectPrepare("COPY")
+	copyIn.ExpectExec().WithArgs(2, "John", 20, "john@example.com").WillReturnResult(sqlmock.NewResult(0, 1))
+	copyIn.ExpectExec().WithArgs().WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM student_import i JOIN students s").WillReturnRows(sqlmock.NewRows([]string{"line"}))
+	mock.ExpectQuery("INSERT INTO students \\(name, age, email\\) SELECT .* RETURNING").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "age", "email", "deleted_at", "version"}).
+			AddRow(7, "John", 20, "john@example.com", nil, 1))
+	mock.ExpectCommit()
+
+	var stored []Student
+	err = repos.InTx(context.Background(), func(tx Repositories) error {
+		ctx := context.Background()
+		imp, err := tx.Students.BeginImport(ctx)
+		if err != nil {
+			return err
+		}
+		if err := imp.Add(2, Student{Name: "John", Age: 20, Email: "john@example.com"}); err != nil {
+			return err
+		}
+		var conflicts []int
+		stored, conflicts, err = imp.Finish(ctx, true)
+		assert.Empty(t, conflicts)
+		return err
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []Student{{ID: 7, Name: "John", Age: 20, Email: "john@example.com", Version: 1}}, stored)
+
+	_, err = repos.Students.BeginImport(context.Background())
+	assert.Error(t, err, "imports outside InTx have no transaction to run on")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation scope of the spans started here.
+const tracerName = "github.com/pratik6266/go-full/internal"
+
+// TracePropagator reads and writes the W3C traceparent, tracestate and
+// baggage headers.
+var TracePropagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// NewTracerProvider returns a provider exporting spans as cfg says. Shut it
+// down to flush the spans not exported yet.
+func NewTracerProvider(ctx context.Context, cfg TracingConfig, service, version, env string) (*sdktrace.TracerProvider, error) {
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	}
+	switch cfg.Exporter {
+	case "otlp":
+		exp, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+		if err != nil {
+			return nil, fmt.Errorf("creating OTLP exporter: %w", err)
+		}
+		opts = append(opts, sdktrace.WithBatcher(exp))
+	case "stdout":
+		exp, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, fmt.Errorf("creating stdout exporter: %w", err)
+		}
+		opts = append(opts, sdktrace.WithBatcher(exp))
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", service),
+		attribute.String("service.version", version),
+		attribute.String("deployment.environment.name", env),
+	))
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, sdktrace.WithResource(res))
+	return sdktrace.NewTracerProvider(opts...), nil
+}
+
+// Tracing starts a server span for every request, continuing the caller's
+// trace when it sends traceparent. Prometheus scrapes are left out.
+func Tracing(service string, tp trace.TracerProvider) gin.HandlerFunc {
+	return otelgin.Middleware(service,
+		otelgin.WithTracerProvider(tp),
+		otelgin.WithPropagators(TracePropagator),
+		otelgin.WithGinFilter(func(c *gin.Context) bool {
+			return c.FullPath() != "/metrics"
+		}),
+	)
+}
@@ -0,0 +1,138 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newTestTracer() (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	exp := tracetest.NewInMemoryExporter()
+	return sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp)), exp
+}
+
+func spanAttr(s tracetest.SpanStub, key attribute.Key) attribute.Value {
+	for _, kv := range s.Attributes {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestTracingContinuesCallerTraceAndTagsLogs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tp, exp := newTestTracer()
+	logger, hook := test.NewNullLogger()
+	r := gin.New()
+	r.Use(Tracing("student-api", tp))
+	r.Use(RequestLogger(logger))
+	r.GET("/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/metrics", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/ping", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	spans := exp.GetSpans()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "GET /ping", span.Name)
+	assert.Equal(t, trace.SpanKindServer, span.SpanKind)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", span.Parent.SpanID().String())
+	assert.Equal(t, "/ping", spanAttr(span, "http.route").AsString())
+
+	entry := hook.LastEntry()
+	assert.Equal(t, span.SpanContext.TraceID().String(), entry.Data["trace_id"])
+	assert.Equal(t, span.SpanContext.SpanID().String(), entry.Data["span_id"])
+
+	// Scrapes are not traced.
+	exp.Reset()
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Empty(t, exp.GetSpans())
+}
+
+// dsnConnector opens connections of a driver by name, like sql.Open.
+type dsnConnector struct {
+	dsn string
+	drv driver.Driver
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) { return c.drv.Open(c.dsn) }
+func (c dsnConnector) Driver() driver.Driver                        { return c.drv }
+
+func TestTracedQueriesRecordSummaryAndRowsAffected(t *testing.T) {
+	mockDB, mock, err := sqlmock.NewWithDSN("tracing_test")
+	require.NoError(t, err)
+	defer mockDB.Close()
+	tp, exp := newTestTracer()
+	db := sql.OpenDB(newTracedConnector(dsnConnector{dsn: "tracing_test", drv: mockDB.Driver()}, tp))
+	defer db.Close()
+	repo := NewPostgresRepositories(&Db{db: db}).Attendance
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "request")
+	day := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectExec("INSERT INTO attendance").
+		WithArgs(day, pq.Array([]int64{1, 2}), pq.Array([]string{"present", "late"})).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	err = repo.Record(ctx, day, []AttendanceEntry{{StudentID: 1, Status: AttendancePresent}, {StudentID: 2, Status: AttendanceLate}})
+	assert.NoError(t, err)
+
+	mock.ExpectQuery("GROUP BY student_id").WillReturnError(errors.New("connection reset"))
+	_, err = repo.Report(ctx, day, day)
+	assert.Error(t, err)
+	parent.End()
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	spans := exp.GetSpans()
+	require.Len(t, spans, 3)
+	insert, report := spans[0], spans[1]
+	for _, s := range []tracetest.SpanStub{insert, report} {
+		assert.Equal(t, parent.SpanContext().SpanID(), s.Parent.SpanID())
+		assert.Equal(t, trace.SpanKindClient, s.SpanKind)
+		assert.Equal(t, "postgresql", spanAttr(s, dbSystemKey).AsString())
+	}
+
+	assert.Equal(t, "INSERT attendance", insert.Name)
+	assert.Equal(t, "attendance", spanAttr(insert, dbCollectionKey).AsString())
+	assert.Equal(t, int64(2), spanAttr(insert, dbRowsAffectedKey).AsInt64())
+	assert.Contains(t, spanAttr(insert, dbQueryTextKey).AsString(), "INSERT INTO attendance")
+
+	assert.Equal(t, "SELECT attendance", report.Name)
+	assert.Equal(t, codes.Error, report.Status.Code)
+	assert.Equal(t, "connection reset", report.Status.Description)
+}
+
+func TestSummarizeQuery(t *testing.T) {
+	for query, want := range map[string][2]string{
+		"SELECT id, name FROM students WHERE id = $1":                        {"SELECT", "students"},
+		"\n\t\tinsert into audit_events (action) VALUES ($1)":                {"INSERT", "audit_events"},
+		"UPDATE students SET name = $1 WHERE id = $2 RETURNING version":      {"UPDATE", "students"},
+		"DELETE FROM idempotency_keys WHERE expires_at <= $1":                {"DELETE", "idempotency_keys"},
+		"SELECT s.id FROM enrollments e JOIN students s ON s.id = e.student": {"SELECT", "enrollments"},
+		"BEGIN": {"BEGIN", ""},
+	} {
+		operation, collection := summarizeQuery(query)
+		assert.Equal(t, want, [2]string{operation, collection}, query)
+	}
+}
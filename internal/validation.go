@@ -0,0 +1,170 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// Machine-readable error codes carried in ErrorResponse.Code.
+const (
+	CodeMalformedJSON    = "malformed_json"
+	CodeValidationFailed = "validation_failed"
+)
+
+func init() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	// Report fields by their JSON names, which is what clients send.
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	_ = v.RegisterValidation("role", func(fl validator.FieldLevel) bool {
+		return Role(fl.Field().String()).Valid()
+	})
+}
+
+// bindJSON decodes and validates the request body into req. On failure it
+// writes the error response and returns false.
+func (h *Handler) bindJSON(c *gin.Context, req any) bool {
+	err := c.ShouldBindJSON(req)
+	if err == nil {
+		return true
+	}
+	h.log(c).Warn("Invalid request payload:", err)
+	c.JSON(bindError(err))
+	return false
+}
+
+// validateStruct runs the binding rules on an already decoded value.
+func validateStruct(v any) error {
+	return binding.Validator.ValidateStruct(v)
+}
+
+// bindError turns a decoding or validation error into a response. Malformed
+// JSON is a 400; a well-formed body that breaks the rules is a 422 listing
+// every offending field.
+func bindError(err error) (int, ErrorResponse) {
+	var (
+		verrs   validator.ValidationErrors
+		typeErr *json.UnmarshalTypeError
+	)
+	switch {
+	case errors.As(err, &verrs):
+		details := make([]FieldError, len(verrs))
+		for i, fe := range verrs {
+			details[i] = FieldError{Field: fieldPath(fe), Rule: fe.Tag(), Message: fieldMessage(fe)}
+		}
+		return http.StatusUnprocessableEntity, ErrorResponse{Error: "Validation failed", Code: CodeValidationFailed, Details: details}
+	case errors.As(err, &typeErr):
+		return http.StatusUnprocessableEntity, ErrorResponse{
+			Error: "Validation failed",
+			Code:  CodeValidationFailed,
+			Details: []FieldError{{
+				Field:   typeErr.Field,
+				Rule:    "type",
+				Message: fmt.Sprintf("%s must be %s", typeErr.Field, jsonTypeName(typeErr.Type)),
+			}},
+		}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return http.StatusUnprocessableEntity, ErrorResponse{
+			Error:   "Validation failed",
+			Code:    CodeValidationFailed,
+			Details: []FieldError{{Field: field, Rule: "unknown", Message: field + " is not a writable field"}},
+		}
+	}
+	return http.StatusBadRequest, ErrorResponse{Error: "Invalid request payload", Code: CodeMalformedJSON}
+}
+
+// fieldPath names the field relative to the request body, so nested fields
+// read like records[2].status.
+func fieldPath(fe validator.FieldError) string {
+	_, path, ok := strings.Cut(fe.Namespace(), ".")
+	if !ok {
+		return fe.Field()
+	}
+	return path
+}
+
+func fieldMessage(fe validator.FieldError) string {
+	name := fieldPath(fe)
+	isString := fe.Kind() == reflect.String
+	switch fe.Tag() {
+	case "required":
+		return name + " is required"
+	case "email":
+		return name + " must be a valid email address"
+	case "role":
+		return fmt.Sprintf("%s must be one of %s, %s, %s", name, RoleAdmin, RoleTeacher, RoleStudent)
+	case "min":
+		if isString {
+			return fmt.Sprintf("%s must be at least %s characters", name, fe.Param())
+		}
+		return fmt.Sprintf("%s must be at least %s", name, fe.Param())
+	case "max":
+		if isString {
+			return fmt.Sprintf("%s must be at most %s characters", name, fe.Param())
+		}
+		return fmt.Sprintf("%s must be at most %s", name, fe.Param())
+	case "gt":
+		return fmt.Sprintf("%s must be greater than %s", name, fe.Param())
+	case "oneof":
+		return fmt.Sprintf("%s must be one of %s", name, strings.ReplaceAll(fe.Param(), " ", ", "))
+	case "datetime":
+		return fmt.Sprintf("%s must be a date formatted as %s", name, fe.Param())
+	case "unique":
+		return fmt.Sprintf("%s must not repeat a %s", name, uniqueKey(fe))
+	}
+	return fmt.Sprintf("%s failed the %s rule", name, fe.Tag())
+}
+
+// uniqueKey returns the JSON name of the element field a unique=Field rule
+// compares, or "value" for plain lists.
+func uniqueKey(fe validator.FieldError) string {
+	t := fe.Type()
+	if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Struct && fe.Param() != "" {
+		if f, ok := t.FieldByName(fe.Param()); ok {
+			if name, _, _ := strings.Cut(f.Tag.Get("json"), ","); name != "" {
+				return name
+			}
+		}
+	}
+	return "value"
+}
+
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	}
+	return "an object"
+}
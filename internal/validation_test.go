@@ -0,0 +1,76 @@
+package internal
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func postJSON(h gin.HandlerFunc, body string) (int, ErrorResponse) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/", h)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	var resp ErrorResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	return w.Code, resp
+}
+
+func TestCreateStudentValidationDetails(t *testing.T) {
+	h, _ := newMemoryHandler(t)
+
+	code, resp := postJSON(h.CreateStudent, `{"name": "", "age": -3, "email": "not-an-email"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	assert.Equal(t, CodeValidationFailed, resp.Code)
+	assert.Equal(t, []FieldError{
+		{Field: "name", Rule: "required", Message: "name is required"},
+		{Field: "age", Rule: "min", Message: "age must be at least 1"},
+		{Field: "email", Rule: "email", Message: "email must be a valid email address"},
+	}, resp.Details)
+}
+
+func TestCreateStudentRejectsLongName(t *testing.T) {
+	h, _ := newMemoryHandler(t)
+
+	body := `{"name": "` + strings.Repeat("x", 101) + `", "age": 20, "email": "a@example.com"}`
+	code, resp := postJSON(h.CreateStudent, body)
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	assert.Equal(t, "name must be at most 100 characters", resp.Details[0].Message)
+}
+
+func TestBindErrorsForMalformedAndMistypedJSON(t *testing.T) {
+	h, _ := newMemoryHandler(t)
+
+	code, resp := postJSON(h.CreateStudent, `{"name": "John"`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, CodeMalformedJSON, resp.Code)
+	assert.Empty(t, resp.Details)
+
+	code, resp = postJSON(h.CreateStudent, `{"name": "John", "age": "twenty", "email": "john@example.com"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	assert.Equal(t, []FieldError{{Field: "age", Rule: "type", Message: "age must be an integer"}}, resp.Details)
+}
+
+func TestCreateUserValidatesRoleAndPassword(t *testing.T) {
+	h, _ := newMemoryHandler(t)
+
+	code, resp := postJSON(h.CreateUser, `{"name": "Root", "email": "root@example.com", "role": "superuser", "password": "short"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	assert.Equal(t, []FieldError{
+		{Field: "role", Rule: "role", Message: "role must be one of admin, teacher, student"},
+		{Field: "password", Rule: "min", Message: "password must be at least 8 characters"},
+	}, resp.Details)
+
+	code, _ = postJSON(h.CreateUser, `{"name": "Teach", "email": "teach@example.com", "role": "teacher"}`)
+	assert.Equal(t, http.StatusCreated, code)
+}